@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"swoozeki/climock/internal/config"
+	"swoozeki/climock/internal/mock"
+
+	"github.com/spf13/cobra"
+)
+
+// listedFeature is the JSON representation of a feature printed by list
+type listedFeature struct {
+	Feature   string           `json:"feature"`
+	Endpoints []listedEndpoint `json:"endpoints"`
+}
+
+// listedEndpoint is the JSON representation of an endpoint printed by list
+type listedEndpoint struct {
+	ID              string `json:"id"`
+	Method          string `json:"method"`
+	Path            string `json:"path"`
+	Active          bool   `json:"active"`
+	DefaultResponse string `json:"defaultResponse"`
+}
+
+// listCmd returns the list subcommand
+func listCmd() *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List all features and their endpoints",
+		Args:  cobra.NoArgs,
+		// Errors from RunE are about the config, not the command line
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(false)
+			if err != nil {
+				return err
+			}
+
+			// Collect features in a stable order
+			features := []listedFeature{}
+			for _, name := range cfg.FeatureNames() {
+				featureConfig, _ := cfg.GetFeature(name)
+				feature := listedFeature{Feature: name, Endpoints: []listedEndpoint{}}
+				for _, endpoint := range featureConfig.Endpoints {
+					feature.Endpoints = append(feature.Endpoints, listedEndpoint{
+						ID:              endpoint.ID,
+						Method:          endpoint.Method,
+						Path:            endpoint.Path,
+						Active:          endpoint.Active,
+						DefaultResponse: endpoint.DefaultResponse,
+					})
+				}
+				features = append(features, feature)
+			}
+
+			if jsonOutput {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(features)
+			}
+
+			// Print a table with one row per endpoint
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "FEATURE\tID\tMETHOD\tPATH\tACTIVE\tDEFAULT")
+			for _, feature := range features {
+				for _, endpoint := range feature.Endpoints {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\n",
+						feature.Feature,
+						endpoint.ID,
+						endpoint.Method,
+						endpoint.Path,
+						endpoint.Active,
+						endpoint.DefaultResponse)
+				}
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print machine-readable JSON")
+
+	return cmd
+}
+
+// toggleCmd returns the toggle subcommand
+func toggleCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "toggle <feature> <endpoint>",
+		Short:        "Toggle whether an endpoint is mocked",
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feature, id := args[0], args[1]
+
+			cfg, err := loadConfig(false)
+			if err != nil {
+				return err
+			}
+
+			if err := mock.New(cfg).ToggleEndpoint(feature, id); err != nil {
+				return err
+			}
+
+			endpoint, err := cfg.GetEndpoint(feature, id)
+			if err != nil {
+				return err
+			}
+
+			state := "inactive"
+			if endpoint.Active {
+				state = "active"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s/%s is now %s\n", feature, id, state)
+			return nil
+		},
+	}
+}
+
+// responseCmd returns the response subcommand
+func responseCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "response <feature> <endpoint> <response>",
+		Short:        "Set the default response of an endpoint",
+		Args:         cobra.ExactArgs(3),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feature, id, response := args[0], args[1], args[2]
+
+			cfg, err := loadConfig(false)
+			if err != nil {
+				return err
+			}
+
+			if err := mock.New(cfg).SetDefaultResponse(feature, id, response); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s/%s now responds with %s\n", feature, id, response)
+			return nil
+		},
+	}
+}
+
+// addEndpointCmd returns the add-endpoint subcommand
+func addEndpointCmd() *cobra.Command {
+	var (
+		id            string
+		method        string
+		path          string
+		status        int
+		createFeature bool
+	)
+
+	cmd := &cobra.Command{
+		Use:          "add-endpoint <feature>",
+		Short:        "Add a new endpoint to a feature",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feature := args[0]
+
+			// Validate before touching the config, using the same rules as the UI
+			endpoint, err := mock.NewEndpoint(id, method, path, status)
+			if err != nil {
+				return err
+			}
+
+			cfg, err := loadConfig(false)
+			if err != nil {
+				return err
+			}
+			mockManager := mock.New(cfg)
+
+			if _, ok := cfg.GetFeature(feature); !ok {
+				if !createFeature {
+					return fmt.Errorf("feature %s not found (use --create-feature to create it)", feature)
+				}
+				if err := mockManager.CreateFeature(config.FeatureConfig{
+					Feature:   feature,
+					Endpoints: []config.Endpoint{},
+				}); err != nil {
+					return err
+				}
+			}
+
+			if err := mockManager.CreateEndpoint(feature, endpoint); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Created %s %s as %s/%s\n", endpoint.Method, endpoint.Path, feature, endpoint.ID)
+			if conflicts := mockManager.FindConflicts(feature, endpoint); len(conflicts) > 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s %s is also handled by %s\n", endpoint.Method, endpoint.Path, strings.Join(conflicts, ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&id, "id", "", "Endpoint ID (letters, numbers, hyphens, underscores)")
+	cmd.Flags().StringVar(&method, "method", "GET", "HTTP method")
+	cmd.Flags().StringVar(&path, "path", "", "Path (e.g., /api/users/:id)")
+	cmd.Flags().IntVar(&status, "status", 200, "Status code of the default response")
+	cmd.Flags().BoolVar(&createFeature, "create-feature", false, "Create the feature if it doesn't exist")
+
+	return cmd
+}
+
+// versionInfo is the JSON representation of the build printed by version
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+}
+
+// versionCmd returns the version subcommand
+func versionCmd() *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the version, commit and build date",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info := versionInfo{Version: Version, Commit: Commit, BuildDate: BuildDate}
+
+			if jsonOutput {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(info)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "climock %s (commit %s, built %s)\n", info.Version, info.Commit, info.BuildDate)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print machine-readable JSON")
+
+	return cmd
+}
@@ -17,60 +17,139 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var (
 	// Version is the version of the application
 	Version = "1.0.0"
 	
-	// ConfigDir is the directory containing mock configurations
+	// Commit and BuildDate describe the build. Like Version, release builds
+	// set them with -ldflags "-X main.Commit=... -X main.BuildDate=..."
+	Commit    = "unknown"
+	BuildDate = "unknown"
+	
+	// ConfigDirs are the directories containing mock configurations, lowest
+	// precedence first
+	ConfigDirs []string
+	
+	// ConfigDir is the last of ConfigDirs, the one default configs are
+	// created in
 	ConfigDir string
 	
 	// Debug mode flag
 	debugMode bool
 )
 
+const (
+	// configDirEnv names the config directory when --config is not given
+	configDirEnv = "CLIMOCK_CONFIG"
+	// portEnv overrides the server port from config.json
+	portEnv = "CLIMOCK_PORT"
+)
+
 func main() {
+	// Execute
+	if err := newRootCmd().Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// newRootCmd returns the root command with all subcommands attached
+func newRootCmd() *cobra.Command {
 	// Create root command
 	rootCmd := &cobra.Command{
 		Use:     "climock",
 		Short:   "Climock - A mock server system",
 		Version: Version,
 		Run:     runUI,
+		// main prints returned errors itself
+		SilenceErrors: true,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			ConfigDirs = resolveConfigDirs(ConfigDirs, cmd.Flags().Changed("config"), os.Getenv(configDirEnv))
+		},
 	}
 	
 	// Add flags
-	rootCmd.PersistentFlags().StringVarP(&ConfigDir, "config", "c", "mocks", "Directory containing mock configurations")
+	rootCmd.PersistentFlags().StringArrayVarP(&ConfigDirs, "config", "c", []string{"mocks"}, "Directory containing mock configurations; repeat to layer directories, later ones overriding earlier ones")
 	rootCmd.PersistentFlags().BoolVarP(&debugMode, "debug", "d", false, "Enable debug mode")
 	
 	// Add subcommands
 	rootCmd.AddCommand(serverCmd())
+	rootCmd.AddCommand(listCmd())
+	rootCmd.AddCommand(toggleCmd())
+	rootCmd.AddCommand(responseCmd())
+	rootCmd.AddCommand(addEndpointCmd())
+	rootCmd.AddCommand(versionCmd())
 	
-	// Execute
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
+	return rootCmd
 }
 
-// setupServer initializes and returns the common components needed for both UI and server modes
-func setupServer() (*config.Config, *mock.Manager, *proxy.Manager, *server.Server, error) {
+// loadConfig initializes the logger and loads the configuration from
+// ConfigDirs. interactive allows asking for the settings of a new config
+// dir; scripted subcommands get the defaults instead.
+func loadConfig(interactive bool) (*config.Config, error) {
 	// Initialize logger
 	if err := logger.Init(debugMode); err != nil {
-		return nil, nil, nil, nil, fmt.Errorf("error initializing logger: %v", err)
+		return nil, fmt.Errorf("error initializing logger: %v", err)
 	}
 
 	// Ensure config directory exists
-	if err := ensureConfigDir(); err != nil {
+	if err := ensureConfigDir(interactive); err != nil {
 		logger.Error("Failed to ensure config directory: %v", err)
-		return nil, nil, nil, nil, fmt.Errorf("error: %v", err)
+		return nil, fmt.Errorf("error: %v", err)
 	}
 
 	// Create config
-	cfg := config.New(ConfigDir)
+	cfg := config.New(ConfigDirs...)
 	if err := cfg.Load(); err != nil {
 		logger.Error("Failed to load configuration: %v", err)
-		return nil, nil, nil, nil, fmt.Errorf("error loading configuration: %v", err)
+		return nil, fmt.Errorf("error loading configuration: %v", err)
+	}
+	for _, loadErr := range cfg.LoadErrors() {
+		logger.Warn("Skipped feature config %v", loadErr)
+	}
+
+	// Let the environment override the configured port
+	port, err := resolvePort(cfg.Global.ServerConfig.Port, os.Getenv(portEnv))
+	if err != nil {
+		logger.Error("Invalid %s: %v", portEnv, err)
+		return nil, fmt.Errorf("error: invalid %s: %v", portEnv, err)
+	}
+	cfg.Global.ServerConfig.Port = port
+
+	return cfg, nil
+}
+
+// resolveConfigDirs picks the config directories: explicit --config flags
+// win, then the CLIMOCK_CONFIG environment variable, then the flag default
+func resolveConfigDirs(flagValues []string, flagChanged bool, envValue string) []string {
+	if !flagChanged && envValue != "" {
+		return []string{envValue}
+	}
+	return flagValues
+}
+
+// resolvePort returns the server port, preferring the CLIMOCK_PORT
+// environment variable over the port from config.json
+func resolvePort(configPort int, envValue string) (int, error) {
+	if envValue == "" {
+		return configPort, nil
+	}
+
+	port, err := strconv.Atoi(envValue)
+	if err != nil || port <= 0 || port >= 65536 {
+		return 0, fmt.Errorf("%q is not a valid port", envValue)
+	}
+	return port, nil
+}
+
+// setupServer initializes and returns the common components needed for both UI and server modes
+func setupServer() (*config.Config, *mock.Manager, *proxy.Manager, *server.Server, error) {
+	cfg, err := loadConfig(true)
+	if err != nil {
+		return nil, nil, nil, nil, err
 	}
 
 	// Create mock manager
@@ -150,59 +229,70 @@ func runServer(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 	
-	logger.Info("Server started at %s", srv.GetAddress())
-	fmt.Printf("Server started at %s\n", srv.GetAddress())
+	logger.Info("Server started at %s", srv.GetDisplayAddress())
+	fmt.Printf("Server started at %s\n", srv.GetDisplayAddress())
 	fmt.Println("Press Ctrl+C to stop")
 	
 	// Wait for interrupt
 	<-make(chan struct{})
 }
 
-// ensureConfigDir ensures the config directory exists
-func ensureConfigDir() error {
-	// Get absolute path
-	absPath, err := filepath.Abs(ConfigDir)
-	if err != nil {
-		return err
-	}
-	
-	// Update ConfigDir to absolute path
-	ConfigDir = absPath
-	
-	// Check if directory exists
-	info, err := os.Stat(ConfigDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Create directory
-			if err := os.MkdirAll(ConfigDir, 0755); err != nil {
+// ensureConfigDir ensures the config directories exist. Default configs are
+// only created on first use: when the last directory is new and none of the
+// others has a config.json to build on. The user is only asked for their
+// settings when interactive is set and stdin is a terminal.
+func ensureConfigDir(interactive bool) error {
+	created := false
+	for i, dir := range ConfigDirs {
+		// Get absolute path
+		absPath, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		ConfigDirs[i] = absPath
+		
+		// Check if directory exists
+		info, err := os.Stat(absPath)
+		if err != nil {
+			if !os.IsNotExist(err) {
 				return err
 			}
 			
-			// Create default config files
-			if err := createDefaultConfigs(); err != nil {
+			// Create directory
+			if err := os.MkdirAll(absPath, 0755); err != nil {
 				return err
 			}
-			
-			return nil
+			created = i == len(ConfigDirs)-1
+			continue
 		}
 		
-		return err
+		// Check if it's a directory
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", absPath)
+		}
 	}
+	ConfigDir = ConfigDirs[len(ConfigDirs)-1]
 	
-	// Check if it's a directory
-	if !info.IsDir() {
-		return fmt.Errorf("%s is not a directory", ConfigDir)
+	if !created {
+		return nil
+	}
+	for _, dir := range ConfigDirs[:len(ConfigDirs)-1] {
+		if _, err := os.Stat(filepath.Join(dir, "config.json")); err == nil {
+			return nil
+		}
 	}
 	
-	return nil
+	// Create default config files
+	return createDefaultConfigs(interactive && term.IsTerminal(int(os.Stdin.Fd())))
 }
 
-// promptUserForConfig prompts the user for configuration values
+// promptUserForConfig prompts the user for configuration values. Prompts go
+// to stderr, so they never mix with a command's output.
 func promptUserForConfig() (string, int, string) {
 	reader := bufio.NewReader(os.Stdin)
 	
 	// Prompt for proxy target
-	fmt.Print("Enter proxy target URL [https://api.real-server.com]: ")
+	fmt.Fprint(os.Stderr, "Enter proxy target URL [https://api.real-server.com]: ")
 	proxyTarget, _ := reader.ReadString('\n')
 	proxyTarget = strings.TrimSpace(proxyTarget)
 	if proxyTarget == "" {
@@ -211,7 +301,7 @@ func promptUserForConfig() (string, int, string) {
 	
 	// Prompt for server port
 	var port int = 3000
-	fmt.Print("Enter mock server port [3000]: ")
+	fmt.Fprint(os.Stderr, "Enter mock server port [3000]: ")
 	portStr, _ := reader.ReadString('\n')
 	portStr = strings.TrimSpace(portStr)
 	if portStr != "" {
@@ -219,12 +309,12 @@ func promptUserForConfig() (string, int, string) {
 		if err == nil && portVal > 0 && portVal < 65536 {
 			port = portVal
 		} else {
-			fmt.Println("Invalid port number, using default: 3000")
+			fmt.Fprintln(os.Stderr, "Invalid port number, using default: 3000")
 		}
 	}
 	
 	// Prompt for host
-	fmt.Print("Enter host [localhost]: ")
+	fmt.Fprint(os.Stderr, "Enter host [localhost]: ")
 	host, _ := reader.ReadString('\n')
 	host = strings.TrimSpace(host)
 	if host == "" {
@@ -234,17 +324,21 @@ func promptUserForConfig() (string, int, string) {
 	return proxyTarget, port, host
 }
 
-// createDefaultConfigs creates default configuration files
-func createDefaultConfigs() error {
+// createDefaultConfigs creates default configuration files, asking for the
+// proxy target, port and host if prompt is set
+func createDefaultConfigs(prompt bool) error {
 	// Create config.json
 	configPath := filepath.Join(ConfigDir, "config.json")
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		fmt.Printf("\nNo configuration found in %s\n", ConfigDir)
-		fmt.Println("Please provide the following information to create a new configuration:")
-		
-		proxyTarget, port, host := promptUserForConfig()
+		proxyTarget, port, host := "https://api.real-server.com", 3000, "localhost"
+		if prompt {
+			fmt.Fprintf(os.Stderr, "\nNo configuration found in %s\n", ConfigDir)
+			fmt.Fprintln(os.Stderr, "Please provide the following information to create a new configuration:")
+			proxyTarget, port, host = promptUserForConfig()
+		}
 		
 		configContent := fmt.Sprintf(`{
+  "version": %d,
   "proxyConfig": {
     "target": "%s",
     "changeOrigin": true,
@@ -260,13 +354,13 @@ func createDefaultConfigs() error {
     "command": "code",
     "args": ["-g", "{file}:{line}"]
   }
-}`, proxyTarget, port, host)
+}`, config.CurrentVersion, proxyTarget, port, host)
 		
 		if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
 			return err
 		}
 		
-		fmt.Printf("Configuration created successfully in %s\n\n", configPath)
+		fmt.Fprintf(os.Stderr, "Configuration created successfully in %s\n\n", configPath)
 	}
 	
 	// Create example.json
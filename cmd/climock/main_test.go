@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"swoozeki/climock/internal/logger"
+)
+
+func init() {
+	// Initialize test logger to prevent nil pointer dereferences
+	logger.InitTestLogger()
+}
+
+// copyFixture copies the fixture config dir into a temporary directory so
+// commands that save changes don't modify testdata
+func copyFixture(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	files, err := os.ReadDir("testdata/mocks")
+	if err != nil {
+		t.Fatalf("Failed to read fixture dir: %v", err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join("testdata/mocks", file.Name()))
+		if err != nil {
+			t.Fatalf("Failed to read fixture %s: %v", file.Name(), err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file.Name()), data, 0644); err != nil {
+			t.Fatalf("Failed to write fixture %s: %v", file.Name(), err)
+		}
+	}
+
+	return dir
+}
+
+// runCommand runs the root command with args and returns its output
+func runCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+	cmd := newRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+// TestListJSON tests that list --json prints the endpoints of the config dir
+func TestListJSON(t *testing.T) {
+	dir := copyFixture(t)
+
+	output, err := runCommand(t, "--config", dir, "list", "--json")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+
+	var features []listedFeature
+	if err := json.Unmarshal([]byte(output), &features); err != nil {
+		t.Fatalf("Failed to parse list output: %v\n%s", err, output)
+	}
+
+	if len(features) != 1 || features[0].Feature != "users" {
+		t.Fatalf("Expected only the users feature, got %+v", features)
+	}
+	expected := []listedEndpoint{
+		{ID: "get-users", Method: "GET", Path: "/api/users", Active: true, DefaultResponse: "standard"},
+		{ID: "create-user", Method: "POST", Path: "/api/users", Active: false, DefaultResponse: "created"},
+	}
+	if len(features[0].Endpoints) != len(expected) {
+		t.Fatalf("Expected %d endpoints, got %d", len(expected), len(features[0].Endpoints))
+	}
+	for i, endpoint := range features[0].Endpoints {
+		if endpoint != expected[i] {
+			t.Errorf("Expected endpoint %+v, got %+v", expected[i], endpoint)
+		}
+	}
+}
+
+// TestListJSONNewConfigDir tests that list --json on a config dir that
+// doesn't exist yet creates the defaults without prompting, so the only
+// output is JSON
+func TestListJSONNewConfigDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mocks")
+
+	// Prompts used to go straight to stdout, around the command's output
+	stdout, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	defer stdout.Close()
+	realStdout := os.Stdout
+	os.Stdout = stdout
+	output, err := runCommand(t, "--config", dir, "list", "--json")
+	os.Stdout = realStdout
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+
+	if written, _ := os.ReadFile(stdout.Name()); len(written) > 0 {
+		t.Errorf("Expected nothing on stdout besides the command output, got %q", written)
+	}
+	var features []listedFeature
+	if err := json.Unmarshal([]byte(output), &features); err != nil {
+		t.Fatalf("Failed to parse list output: %v\n%s", err, output)
+	}
+	if len(features) != 1 || features[0].Feature != "example" {
+		t.Errorf("Expected the default example feature, got %+v", features)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.json")); err != nil {
+		t.Errorf("Expected a default config.json to be created: %v", err)
+	}
+}
+
+// TestListTable tests the default table output of list
+func TestListTable(t *testing.T) {
+	dir := copyFixture(t)
+
+	output, err := runCommand(t, "--config", dir, "list")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got:\n%s", output)
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "users get-users GET /api/users true standard" {
+		t.Errorf("Unexpected row: %q", lines[1])
+	}
+}
+
+// TestToggle tests that toggle flips and persists an endpoint's active state
+func TestToggle(t *testing.T) {
+	dir := copyFixture(t)
+
+	output, err := runCommand(t, "--config", dir, "toggle", "users", "create-user")
+	if err != nil {
+		t.Fatalf("toggle failed: %v", err)
+	}
+	if strings.TrimSpace(output) != "users/create-user is now active" {
+		t.Errorf("Unexpected output: %q", output)
+	}
+
+	// The change is saved, so a second toggle flips it back
+	output, err = runCommand(t, "--config", dir, "toggle", "users", "create-user")
+	if err != nil {
+		t.Fatalf("toggle failed: %v", err)
+	}
+	if strings.TrimSpace(output) != "users/create-user is now inactive" {
+		t.Errorf("Unexpected output: %q", output)
+	}
+
+	// Unknown endpoints are reported as errors
+	if _, err := runCommand(t, "--config", dir, "toggle", "users", "missing"); err == nil {
+		t.Error("Expected error for unknown endpoint, got nil")
+	}
+}
+
+// TestResponse tests that response sets and persists the default response
+func TestResponse(t *testing.T) {
+	dir := copyFixture(t)
+
+	output, err := runCommand(t, "--config", dir, "response", "users", "get-users", "empty")
+	if err != nil {
+		t.Fatalf("response failed: %v", err)
+	}
+	if strings.TrimSpace(output) != "users/get-users now responds with empty" {
+		t.Errorf("Unexpected output: %q", output)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "users.json"))
+	if err != nil {
+		t.Fatalf("Failed to read feature file: %v", err)
+	}
+	if !strings.Contains(string(data), `"defaultResponse": "empty"`) {
+		t.Error("Expected new default response to be saved")
+	}
+
+	if _, err := runCommand(t, "--config", dir, "response", "users", "get-users", "missing"); err == nil {
+		t.Error("Expected error for unknown response, got nil")
+	}
+}
+
+// TestAddEndpoint tests creating endpoints from the command line
+func TestAddEndpoint(t *testing.T) {
+	dir := copyFixture(t)
+
+	output, err := runCommand(t, "--config", dir, "add-endpoint", "users",
+		"--id", "delete-user", "--method", "delete", "--path", "api/users/:id", "--status", "204")
+	if err != nil {
+		t.Fatalf("add-endpoint failed: %v", err)
+	}
+	if strings.TrimSpace(output) != "Created DELETE /api/users/:id as users/delete-user" {
+		t.Errorf("Unexpected output: %q", output)
+	}
+
+	// Missing features are only created on request
+	if _, err := runCommand(t, "--config", dir, "add-endpoint", "orders", "--id", "get-orders", "--path", "/api/orders"); err == nil {
+		t.Error("Expected error for missing feature, got nil")
+	}
+	if _, err := runCommand(t, "--config", dir, "add-endpoint", "orders", "--id", "get-orders", "--path", "/api/orders", "--create-feature"); err != nil {
+		t.Fatalf("add-endpoint with --create-feature failed: %v", err)
+	}
+
+	output, err = runCommand(t, "--config", dir, "list", "--json")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	var features []listedFeature
+	if err := json.Unmarshal([]byte(output), &features); err != nil {
+		t.Fatalf("Failed to parse list output: %v", err)
+	}
+	if len(features) != 2 || features[0].Feature != "orders" || features[0].Endpoints[0].ID != "get-orders" {
+		t.Errorf("Expected orders feature with get-orders, got %+v", features)
+	}
+	if endpoints := features[1].Endpoints; endpoints[len(endpoints)-1].ID != "delete-user" {
+		t.Errorf("Expected delete-user to be added to users, got %+v", endpoints)
+	}
+}
+
+// TestAddEndpointInvalidMethod tests that invalid methods are rejected
+func TestAddEndpointInvalidMethod(t *testing.T) {
+	dir := copyFixture(t)
+
+	_, err := runCommand(t, "--config", dir, "add-endpoint", "users", "--id", "bad", "--method", "FETCH", "--path", "/api/bad")
+	if err == nil || !strings.Contains(err.Error(), "invalid HTTP method") {
+		t.Errorf("Expected invalid method error, got %v", err)
+	}
+}
+
+// TestResolveConfigDirs tests that --config takes precedence over CLIMOCK_CONFIG,
+// which takes precedence over the default
+func TestResolveConfigDirs(t *testing.T) {
+	tests := []struct {
+		name        string
+		flagValues  []string
+		flagChanged bool
+		envValue    string
+		expected    []string
+	}{
+		{"flag wins over env", []string{"/from/flag"}, true, "/from/env", []string{"/from/flag"}},
+		{"repeated flags are kept in order", []string{"/base", "/local"}, true, "/from/env", []string{"/base", "/local"}},
+		{"env wins over default", []string{"mocks"}, false, "/from/env", []string{"/from/env"}},
+		{"default without env", []string{"mocks"}, false, "", []string{"mocks"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveConfigDirs(tt.flagValues, tt.flagChanged, tt.envValue); strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestLayeredConfigDirs tests that repeated --config flags layer the
+// directories, later ones overriding features from earlier ones
+func TestLayeredConfigDirs(t *testing.T) {
+	base := copyFixture(t)
+	local := filepath.Join(t.TempDir(), "local")
+
+	listDefault := func() string {
+		t.Helper()
+		output, err := runCommand(t, "--config", base, "--config", local, "list", "--json")
+		if err != nil {
+			t.Fatalf("list failed: %v", err)
+		}
+		var features []listedFeature
+		if err := json.Unmarshal([]byte(output), &features); err != nil {
+			t.Fatalf("Failed to parse list output: %v\n%s", err, output)
+		}
+		if len(features) != 1 || len(features[0].Endpoints) == 0 {
+			t.Fatalf("Expected the users feature, got %s", output)
+		}
+		return features[0].Endpoints[0].DefaultResponse
+	}
+
+	// A missing override directory is created empty; the base has the config
+	if got := listDefault(); got != "standard" {
+		t.Errorf("Expected the base response, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(local, "config.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected no default config in the override directory, got %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(base, "users.json"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	override := strings.Replace(string(data), `"defaultResponse": "standard"`, `"defaultResponse": "empty"`, 1)
+	if err := os.WriteFile(filepath.Join(local, "users.json"), []byte(override), 0644); err != nil {
+		t.Fatalf("Failed to write override: %v", err)
+	}
+	if got := listDefault(); got != "empty" {
+		t.Errorf("Expected the override's response, got %q", got)
+	}
+}
+
+// TestResolvePort tests that CLIMOCK_PORT overrides the configured port
+func TestResolvePort(t *testing.T) {
+	if port, err := resolvePort(3000, ""); err != nil || port != 3000 {
+		t.Errorf("Expected config port 3000, got %d (%v)", port, err)
+	}
+	if port, err := resolvePort(3000, "4000"); err != nil || port != 4000 {
+		t.Errorf("Expected env port 4000, got %d (%v)", port, err)
+	}
+	if _, err := resolvePort(3000, "not-a-port"); err == nil {
+		t.Error("Expected error for invalid port, got nil")
+	}
+}
+
+// TestConfigDirFromEnv tests that commands load the directory named by CLIMOCK_CONFIG
+func TestConfigDirFromEnv(t *testing.T) {
+	t.Setenv(configDirEnv, copyFixture(t))
+
+	output, err := runCommand(t, "list", "--json")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if !strings.Contains(output, `"get-users"`) {
+		t.Errorf("Expected fixture endpoints in output, got %q", output)
+	}
+}
+
+// TestVersionJSON tests that version --json prints the build information
+func TestVersionJSON(t *testing.T) {
+	output, err := runCommand(t, "version", "--json")
+	if err != nil {
+		t.Fatalf("version failed: %v", err)
+	}
+
+	var info map[string]string
+	if err := json.Unmarshal([]byte(output), &info); err != nil {
+		t.Fatalf("Failed to parse output %q: %v", output, err)
+	}
+	for _, field := range []string{"version", "commit", "buildDate"} {
+		if info[field] == "" {
+			t.Errorf("Expected %s in the output, got %q", field, output)
+		}
+	}
+	if info["version"] != Version {
+		t.Errorf("Expected version %s, got %s", Version, info["version"])
+	}
+}
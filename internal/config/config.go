@@ -1,11 +1,18 @@
 package config
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"swoozeki/climock/internal/logger"
 )
@@ -15,65 +22,462 @@ type ProxyConfig struct {
 	Target       string            `json:"target"`
 	ChangeOrigin bool              `json:"changeOrigin"`
 	PathRewrite  map[string]string `json:"pathRewrite"`
+	// CacheTTL caches successful proxied GET responses for this many seconds
+	// and serves repeat requests from the cache. 0 disables caching.
+	CacheTTL int `json:"cacheTTL,omitempty"`
+	// PreserveUpstreamCORS passes the target's CORS headers through instead
+	// of replacing them with the server's own
+	PreserveUpstreamCORS bool `json:"preserveUpstreamCORS,omitempty"`
 }
 
 // ServerConfig holds the HTTP server configuration
 type ServerConfig struct {
 	Port int    `json:"port"`
 	Host string `json:"host"`
+	// SocketPath makes the server listen on a Unix domain socket at this
+	// path instead of on Host and Port
+	SocketPath string `json:"socketPath,omitempty"`
+	// AdvertiseHost is the host shown to users as the address to connect to.
+	// When empty it's Host, or localhost if Host binds every interface.
+	AdvertiseHost string `json:"advertiseHost,omitempty"`
 }
 
 // EditorConfig holds the external editor configuration
 type EditorConfig struct {
 	Command string   `json:"command"`
 	Args    []string `json:"args"`
+
+	// Blocking runs the editor in the terminal, suspending the UI until it
+	// exits. When unset, it's detected from the command; use IsBlocking to
+	// read it.
+	Blocking *bool `json:"blocking,omitempty"`
+}
+
+// terminalEditors are editors that need the terminal and so are run blocking
+// unless the config says otherwise
+var terminalEditors = map[string]bool{
+	"vi":    true,
+	"vim":   true,
+	"nvim":  true,
+	"nano":  true,
+	"emacs": true,
+	"micro": true,
+	"hx":    true,
+	"helix": true,
+	"kak":   true,
+	"joe":   true,
+	"ne":    true,
+}
+
+// IsBlocking returns whether the editor should take over the terminal
+func (e EditorConfig) IsBlocking() bool {
+	if e.Blocking != nil {
+		return *e.Blocking
+	}
+	name := strings.TrimSuffix(filepath.Base(e.Command), ".exe")
+	return terminalEditors[name]
 }
 
 // GlobalConfig holds the global application configuration
 type GlobalConfig struct {
+	// Version is the format version of the file, see CurrentVersion
+	Version int `json:"version"`
+
 	ProxyConfig  ProxyConfig  `json:"proxyConfig"`
 	ServerConfig ServerConfig `json:"serverConfig"`
 	Editor       EditorConfig `json:"editor"`
+
+	// IgnoreTrailingSlash treats /api/users and /api/users/ as the same path
+	IgnoreTrailingSlash bool `json:"ignoreTrailingSlash,omitempty"`
+	// CaseInsensitivePaths compares static path segments case-insensitively
+	CaseInsensitivePaths bool `json:"caseInsensitivePaths,omitempty"`
+	// HonorMethodOverride matches requests using the method given in the
+	// X-HTTP-Method-Override header instead of the request method
+	HonorMethodOverride bool `json:"honorMethodOverride,omitempty"`
+
+	// Scenarios maps a scenario name to the default response to select for
+	// each endpoint, keyed by feature and then endpoint ID
+	Scenarios map[string]Scenario `json:"scenarios,omitempty"`
+
+	// ProxyEnabled controls whether unmatched requests are proxied. It
+	// defaults to true when unset; use IsProxyEnabled to read it.
+	ProxyEnabled *bool `json:"proxyEnabled,omitempty"`
+
+	// CORSEnabled controls whether the server adds CORS headers and strips
+	// them from proxied responses. It defaults to true when unset; use
+	// IsCORSEnabled to read it.
+	CORSEnabled *bool `json:"corsEnabled,omitempty"`
+
+	// FallbackResponse is served for unmatched requests that can't be proxied.
+	// When unset, a 404 JSON error is returned instead.
+	FallbackResponse *Response `json:"fallbackResponse,omitempty"`
+
+	// StrictValidation makes Load fail on duplicate endpoints instead of
+	// only logging a warning
+	StrictValidation bool `json:"strictValidation,omitempty"`
+
+	// HealthPath and ReadyPath are the reserved liveness and readiness routes.
+	// They default to /healthz and /readyz when unset; use HealthCheckPath and
+	// ReadinessPath to read them.
+	HealthPath string `json:"healthPath,omitempty"`
+	ReadyPath  string `json:"readyPath,omitempty"`
+
+	// ProxyHistory lists recently used proxy targets, most recent first
+	ProxyHistory []string `json:"proxyHistory,omitempty"`
+
+	// ResponseOverrideHeader names a request header that selects which of the
+	// matched endpoint's responses to serve for that request. Leave it empty
+	// to disable the override.
+	ResponseOverrideHeader string `json:"responseOverrideHeader,omitempty"`
+
+	// CompressResponses gzips every mock response for clients that accept
+	// it, as if each response set compress. Proxied responses are untouched.
+	CompressResponses bool `json:"compressResponses,omitempty"`
+
+	// DefaultHeaders are added to every mock response that doesn't set a
+	// header of the same name itself
+	DefaultHeaders map[string]string `json:"defaultHeaders,omitempty"`
+
+	// DefaultDelay is the delay in milliseconds for mock responses that set
+	// neither delay nor latencyProfile. A delay of -1 opts a response out.
+	DefaultDelay int `json:"defaultDelay,omitempty"`
+
+	// AuditLog is the path of a JSONL file that gets one line per served
+	// request. Leave it empty to disable the audit log.
+	AuditLog string `json:"auditLog,omitempty"`
+
+	// LenientTemplates serves a response's raw body, with a warning, when its
+	// template fails to render instead of answering with a 500
+	LenientTemplates bool `json:"lenientTemplates,omitempty"`
+
+	// ErrorResponse replaces the JSON error sent when a mock response can't
+	// be generated. Its body may use {{.error}} for the failure message, and
+	// its status defaults to 500.
+	ErrorResponse *Response `json:"errorResponse,omitempty"`
+
+	// UI holds terminal UI preferences, saved when the UI quits
+	UI *UIConfig `json:"ui,omitempty"`
+}
+
+// UIConfig holds terminal UI preferences that persist between sessions
+type UIConfig struct {
+	// Panel is the panel focused at startup, "features" or "endpoints"
+	Panel string `json:"panel,omitempty"`
+	// EndpointSort is the endpoints list order: file, path, method or active
+	EndpointSort string `json:"endpointSort,omitempty"`
+	// FeaturesWidth is the features panel's share of the window width, in
+	// percent. 0 uses the default.
+	FeaturesWidth int `json:"featuresWidth,omitempty"`
+	// Theme picks the UI colors: dark (the default), light or high-contrast
+	Theme string `json:"theme,omitempty"`
+}
+
+// IsProxyEnabled returns whether unmatched requests should be proxied
+func (g GlobalConfig) IsProxyEnabled() bool {
+	return g.ProxyEnabled == nil || *g.ProxyEnabled
+}
+
+// IsCORSEnabled returns whether the CORS middleware is installed
+func (g GlobalConfig) IsCORSEnabled() bool {
+	return g.CORSEnabled == nil || *g.CORSEnabled
+}
+
+// KeepsUpstreamCORS returns whether proxied responses keep the target's CORS
+// headers, because the server adds none of its own to them
+func (g GlobalConfig) KeepsUpstreamCORS() bool {
+	return !g.IsCORSEnabled() || g.ProxyConfig.PreserveUpstreamCORS
 }
 
+// DefaultHealthPath and DefaultReadyPath are used when the global config
+// doesn't set healthPath or readyPath
+const (
+	DefaultHealthPath = "/healthz"
+	DefaultReadyPath  = "/readyz"
+)
+
+// HealthCheckPath returns the path of the liveness route
+func (g GlobalConfig) HealthCheckPath() string {
+	if g.HealthPath == "" {
+		return DefaultHealthPath
+	}
+	return g.HealthPath
+}
+
+// ReadinessPath returns the path of the readiness route
+func (g GlobalConfig) ReadinessPath() string {
+	if g.ReadyPath == "" {
+		return DefaultReadyPath
+	}
+	return g.ReadyPath
+}
+
+// Scenario maps feature names to endpoint IDs to response names
+type Scenario map[string]map[string]string
+
 // Config holds the entire application configuration
 type Config struct {
 	Global  GlobalConfig
 	Mocks   map[string]FeatureConfig
+	// BaseDir is the directory changes are saved to. With several config
+	// directories, Load sets it to the last writable one.
 	BaseDir string
+	// Dirs are the config directories, lowest precedence first. A feature in
+	// a later directory replaces the one of the same name from an earlier
+	// one, and settings in its config.json override theirs.
+	Dirs    []string
 	mu      sync.RWMutex
+
+	// With several Dirs, inherited holds the settings of the directories
+	// other than BaseDir and owned the ones BaseDir's config.json sets, both
+	// as decoded JSON. Saving uses them to write only BaseDir's own settings.
+	inherited map[string]interface{}
+	owned     map[string]interface{}
+
+	// loadErrors holds the feature files that failed to load on the last Load
+	loadErrors []LoadError
+}
+
+// LoadError describes a feature file that could not be loaded
+type LoadError struct {
+	File string
+	Err  error
+}
+
+// Error implements the error interface
+func (e LoadError) Error() string {
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
 }
 
 // FeatureConfig holds the configuration for a specific feature
 type FeatureConfig struct {
-	Feature   string     `json:"feature"`
+	Feature string `json:"feature"`
+	// Enabled switches the whole feature off when false. It defaults to
+	// true when unset; use IsEnabled to read it.
+	Enabled   *bool      `json:"enabled,omitempty"`
 	Endpoints []Endpoint `json:"endpoints"`
 }
 
+// IsEnabled returns whether the feature's endpoints can be matched
+func (f FeatureConfig) IsEnabled() bool {
+	return f.Enabled == nil || *f.Enabled
+}
+
 // Endpoint represents a mock API endpoint
 type Endpoint struct {
-	ID              string              `json:"id"`
-	Method          string              `json:"method"`
-	Path            string              `json:"path"`
-	Active          bool                `json:"active"`
-	DefaultResponse string              `json:"defaultResponse"`
-	Responses       map[string]Response `json:"responses"`
+	ID               string              `json:"id"`
+	Method           string              `json:"method"`
+	Path             string              `json:"path"`
+	Active           bool                `json:"active"`
+	ResponseType     string              `json:"responseType,omitempty"`
+	Description      string              `json:"description,omitempty"`
+	// Tags group endpoints by concern across features, for filtering in the
+	// UI. They don't affect matching.
+	Tags             []string            `json:"tags,omitempty"`
+	// RequestSchema is an optional inline JSON Schema that request bodies must
+	// conform to; requests that don't are answered with a 400
+	RequestSchema    interface{}         `json:"requestSchema,omitempty"`
+	DefaultResponse  string              `json:"defaultResponse"`
+	Responses        map[string]Response `json:"responses"`
+	// CORS overrides the server's default CORS headers for this endpoint
+	CORS             *CORSConfig         `json:"cors,omitempty"`
+	// ETag adds an ETag computed from the response body and answers matching
+	// If-None-Match requests with a 304
+	ETag             bool                `json:"etag,omitempty"`
+	// ProxyPathRewrite replaces the global path rewrite rules for this
+	// endpoint's requests when they're proxied
+	ProxyPathRewrite map[string]string   `json:"proxyPathRewrite,omitempty"`
+}
+
+// CORSConfig is an endpoint's CORS policy. Fields left empty keep the
+// server's defaults.
+type CORSConfig struct {
+	AllowOrigin      string `json:"allowOrigin,omitempty"`
+	AllowMethods     string `json:"allowMethods,omitempty"`
+	AllowHeaders     string `json:"allowHeaders,omitempty"`
+	ExposeHeaders    string `json:"exposeHeaders,omitempty"`
+	AllowCredentials bool   `json:"allowCredentials,omitempty"`
+}
+
+// ResponseTypeEcho makes an endpoint reply with a description of the request
+// it received instead of one of its configured responses
+const ResponseTypeEcho = "echo"
+
+// ResponseTypeProxy makes a matched endpoint forward its requests to the
+// proxy target, so an intentionally proxied endpoint can still be listed and
+// described in the config
+const ResponseTypeProxy = "proxy"
+
+// HasTag reports whether the endpoint is tagged with tag
+func (e Endpoint) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// needsResponses reports whether the endpoint serves one of its configured
+// responses, rather than building its reply some other way
+func (e Endpoint) needsResponses() bool {
+	return e.ResponseType != ResponseTypeEcho && e.ResponseType != ResponseTypeProxy
 }
 
 // Response represents a mock API response
 type Response struct {
-	Status  int               `json:"status"`
-	Headers map[string]string `json:"headers"`
-	Body    interface{}       `json:"body"`
-	Delay   int               `json:"delay"`
+	Status      int               `json:"status"`
+	Description string            `json:"description,omitempty"`
+	Headers     map[string]string `json:"headers"`
+	Body        interface{}       `json:"body"`
+	// BodyBase64 is a binary body, such as an image, encoded as standard
+	// base64. It's sent as-is instead of Body.
+	BodyBase64 string `json:"bodyBase64,omitempty"`
+	// Delay is how long to wait before responding, in milliseconds. 0, which
+	// every saved file has for responses without a delay, uses the global
+	// defaultDelay; -1 means no delay even when there's a defaultDelay.
+	Delay int `json:"delay"`
+	// LatencyProfile replaces Delay with a delay sampled from a latency
+	// distribution, given as percentile points
+	LatencyProfile LatencyProfile `json:"latencyProfile,omitempty"`
+	// Compress gzips the body for clients that send Accept-Encoding: gzip
+	Compress bool `json:"compress,omitempty"`
+	// ContentLength, OmitContentLength and ForceChunked control how the end
+	// of the body is signalled, for testing clients against unusual
+	// responses. ContentLength is sent as-is even if it's wrong.
+	ContentLength     *int `json:"contentLength,omitempty"`
+	OmitContentLength bool `json:"omitContentLength,omitempty"`
+	ForceChunked      bool `json:"forceChunked,omitempty"`
+	// Stream sends the body in chunks with a pause between them instead of
+	// all at once
+	Stream *StreamConfig `json:"stream,omitempty"`
+	// SSE sends a list of Server-Sent Events instead of the body
+	SSE *SSEConfig `json:"sse,omitempty"`
+	// Profiles tags the response with response profiles; while one of them is
+	// active, it's served instead of the endpoint's default response
+	Profiles []string `json:"profiles,omitempty"`
+}
+
+// SSEConfig describes a Server-Sent Events response: the events to send, the
+// pause between them in milliseconds, and whether to start over after the
+// last one instead of closing the connection
+type SSEConfig struct {
+	Events   []SSEEvent `json:"events"`
+	Interval int        `json:"interval"`
+	Loop     bool       `json:"loop,omitempty"`
+}
+
+// SSEEvent is a single Server-Sent Event. Data is sent as-is if it's a
+// string and JSON-encoded otherwise; Event and ID are optional.
+type SSEEvent struct {
+	Event string      `json:"event,omitempty"`
+	Data  interface{} `json:"data"`
+	ID    string      `json:"id,omitempty"`
 }
 
-// New creates a new Config instance
-func New(baseDir string) *Config {
-	return &Config{
-		Mocks:   make(map[string]FeatureConfig),
-		BaseDir: baseDir,
+// StreamConfig describes how a response body is streamed. Chunks, if set,
+// are sent instead of the body. Otherwise the body is split into ChunkSize
+// byte pieces, or sent as a single chunk when ChunkSize is 0.
+type StreamConfig struct {
+	Chunks    []string `json:"chunks,omitempty"`
+	ChunkSize int      `json:"chunkSize,omitempty"`
+	// Interval is the pause between chunks, in milliseconds
+	Interval int `json:"interval"`
+}
+
+// LatencyProfile maps percentiles, written "p50", "p99" or "p99.9", to
+// delays in milliseconds. Delays between the points are interpolated
+// linearly. Below the lowest point the delay falls towards 0 at p0, unless
+// p0 is given, and the highest point's delay is the maximum.
+type LatencyProfile map[string]int
+
+// latencyPoint is one percentile of a LatencyProfile
+type latencyPoint struct {
+	percentile float64
+	delay      float64
+}
+
+// points parses the profile into percentile points ordered by percentile,
+// starting at p0
+func (p LatencyProfile) points() ([]latencyPoint, error) {
+	points := make([]latencyPoint, 0, len(p)+1)
+	for key, delay := range p {
+		percentile, err := strconv.ParseFloat(strings.TrimPrefix(key, "p"), 64)
+		if err != nil || !strings.HasPrefix(key, "p") || percentile < 0 || percentile > 100 {
+			return nil, fmt.Errorf("invalid percentile %q, expected p0 to p100", key)
+		}
+		if delay < 0 {
+			return nil, fmt.Errorf("negative delay for %s", key)
+		}
+		points = append(points, latencyPoint{percentile: percentile, delay: float64(delay)})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].percentile < points[j].percentile })
+	
+	if len(points) == 0 || points[0].percentile > 0 {
+		points = append([]latencyPoint{{}}, points...)
+	}
+	for i := 1; i < len(points); i++ {
+		if points[i].delay < points[i-1].delay {
+			return nil, fmt.Errorf("delays must not decrease as percentiles increase")
+		}
+	}
+	return points, nil
+}
+
+// Validate reports whether the profile has valid percentiles and delays
+func (p LatencyProfile) Validate() error {
+	_, err := p.points()
+	return err
+}
+
+// Sample returns the delay at quantile q, in the range [0, 1). Passing a
+// uniformly random q samples the distribution. Invalid profiles give no delay.
+func (p LatencyProfile) Sample(q float64) time.Duration {
+	points, err := p.points()
+	if err != nil {
+		return 0
+	}
+	
+	percentile := q * 100
+	for i := 1; i < len(points); i++ {
+		lo, hi := points[i-1], points[i]
+		if percentile > hi.percentile {
+			continue
+		}
+		delay := lo.delay
+		if span := hi.percentile - lo.percentile; span > 0 {
+			delay += (hi.delay - lo.delay) * (percentile - lo.percentile) / span
+		}
+		return time.Duration(delay * float64(time.Millisecond))
+	}
+	
+	// Past the highest point
+	return time.Duration(points[len(points)-1].delay * float64(time.Millisecond))
+}
+
+// ErrNoBaseDir is returned when saving an in-memory config, one created with
+// an empty base directory, instead of writing to the working directory
+var ErrNoBaseDir = errors.New("no config directory configured; cannot save")
+
+// New creates a new Config instance that loads from baseDirs, lowest
+// precedence first. Without any, the config is in memory only.
+func New(baseDirs ...string) *Config {
+	c := &Config{
+		Mocks: make(map[string]FeatureConfig),
+		Dirs:  baseDirs,
+	}
+	if len(baseDirs) > 0 {
+		c.BaseDir = baseDirs[len(baseDirs)-1]
+	}
+	return c
+}
+
+// layers returns the directories Load reads, lowest precedence first. A
+// single directory is always BaseDir, so setting BaseDir directly still works.
+func (c *Config) layers() []string {
+	if len(c.Dirs) > 1 {
+		return c.Dirs
 	}
+	return []string{c.BaseDir}
 }
 
 // Load loads the configuration from the specified directory
@@ -81,37 +485,228 @@ func (c *Config) Load() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Load global config
-	globalConfigPath := filepath.Join(c.BaseDir, "config.json")
-	if err := c.loadGlobalConfig(globalConfigPath); err != nil {
-		logger.Error("Failed to load global config: %v", err)
-		return fmt.Errorf("failed to load global config: %w", err)
+	layers := c.layers()
+	if len(layers) > 1 {
+		c.BaseDir = lastWritableDir(layers)
 	}
 
-	// Load feature configs
-	files, err := os.ReadDir(c.BaseDir)
-	if err != nil {
-		logger.Error("Failed to read mocks directory: %v", err)
-		return fmt.Errorf("failed to read mocks directory: %w", err)
+	// Load global config, each layer's settings applied over the ones before.
+	// Only one layer needs a config.json.
+	found := false
+	var inherited GlobalConfig
+	c.inherited, c.owned = nil, nil
+	for _, dir := range layers {
+		globalConfigPath := filepath.Join(dir, "config.json")
+		if _, err := os.Stat(globalConfigPath); len(layers) > 1 && os.IsNotExist(err) {
+			continue
+		}
+		if err := c.loadGlobalConfig(globalConfigPath); err != nil {
+			logger.Error("Failed to load global config: %v", err)
+			return fmt.Errorf("failed to load global config: %w", err)
+		}
+		found = true
+		if len(layers) > 1 {
+			if err := c.loadLayerSettings(dir, globalConfigPath, &inherited); err != nil {
+				logger.Error("Failed to load global config: %v", err)
+				return fmt.Errorf("failed to load global config: %w", err)
+			}
+		}
+	}
+	if !found {
+		logger.Error("No config.json found in %s", strings.Join(layers, ", "))
+		return fmt.Errorf("failed to load global config: no config.json in %s", strings.Join(layers, ", "))
+	}
+	if len(layers) > 1 {
+		// Compare against what the other layers mean after migration, so
+		// upgraded defaults don't count as BaseDir's own settings
+		inherited.migrate()
+		var err error
+		if c.inherited, err = jsonObject(inherited); err != nil {
+			return fmt.Errorf("failed to load global config: %w", err)
+		}
 	}
 
+	// Upgrade older files and write the upgrade back, so the next load
+	// doesn't have to guess what they meant again. With several layers the
+	// upgrade is of their merged settings, which belong to no one file, so
+	// it's only made in memory.
+	if c.Global.migrate() && len(layers) == 1 {
+		if err := c.writeGlobalConfig(); err != nil {
+			logger.Warn("Failed to save migrated global config: %v", err)
+		}
+	}
+
+	// Load feature configs, later layers replacing features of the same name
 	c.Mocks = make(map[string]FeatureConfig)
-	for _, file := range files {
-		if file.IsDir() || file.Name() == "config.json" {
-			continue
+	c.loadErrors = nil
+	for _, dir := range layers {
+		if err := c.loadFeatures(dir, len(layers) > 1); err != nil {
+			logger.Error("Failed to read mocks directory: %v", err)
+			return fmt.Errorf("failed to read mocks directory: %w", err)
 		}
+	}
+
+	// Hand-edited files may contain duplicates that make matching ambiguous,
+	// or endpoints that have nothing to respond with
+	problems := c.validateEndpoints()
+	for _, problem := range problems {
+		logger.Warn("Config validation: %s", problem)
+	}
+	if len(problems) > 0 && c.Global.StrictValidation {
+		logger.Error("Config validation failed with %d problem(s)", len(problems))
+		return fmt.Errorf("invalid mock configuration: %s", strings.Join(problems, "; "))
+	}
 
-		featurePath := filepath.Join(c.BaseDir, file.Name())
-		featureConfig, err := c.loadFeatureConfig(featurePath)
+	return nil
+}
+
+// loadFeatures loads the feature configs in dir, including those in
+// subdirectories, into c.Mocks. Load errors name the file relative to dir,
+// prefixed with dir itself when qualify is set.
+func (c *Config) loadFeatures(dir string, qualify bool) error {
+	return filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
 		if err != nil {
-			logger.Error("Failed to load feature config %s: %v", file.Name(), err)
-			return fmt.Errorf("failed to load feature config %s: %w", file.Name(), err)
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			// Leave hidden directories such as .git alone
+			if rel != "." && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Only .json files are features, so notes, READMEs and temporary files
+		// left by an interrupted save aren't reported as broken ones
+		if rel == "config.json" || filepath.Ext(rel) != ".json" {
+			return nil
+		}
+
+		// Skip broken feature files so the rest of the mocks stay usable
+		featureConfig, err := c.loadFeatureConfig(path)
+		if err != nil {
+			file := rel
+			if qualify {
+				file = filepath.ToSlash(path)
+			}
+			logger.Error("Failed to load feature config %s: %v", file, err)
+			c.loadErrors = append(c.loadErrors, LoadError{File: file, Err: err})
+			return nil
+		}
+
+		// Features in subdirectories are keyed by their relative path, so
+		// saving writes them back to the same file
+		if strings.Contains(rel, "/") {
+			featureConfig.Feature = strings.TrimSuffix(rel, filepath.Ext(rel))
 		}
 
 		c.Mocks[featureConfig.Feature] = featureConfig
+		return nil
+	})
+}
+
+// lastWritableDir returns the last of dirs that files can be created in, or
+// the last of them if none can, so saving reports why it fails
+func lastWritableDir(dirs []string) string {
+	for i := len(dirs) - 1; i >= 0; i-- {
+		file, err := os.CreateTemp(dirs[i], ".climock-write-test-*")
+		if err != nil {
+			continue
+		}
+		file.Close()
+		os.Remove(file.Name())
+		return dirs[i]
+	}
+	return dirs[len(dirs)-1]
+}
+
+// validateEndpoints reports endpoints sharing an ID within a feature,
+// endpoints sharing a method and path across all features, endpoints
+// without any responses, and default responses that don't exist. Without
+// strict validation, missing default responses are repaired in memory.
+func (c *Config) validateEndpoints() []string {
+	var problems []string
+
+	// Walk features in a stable order so reports are deterministic
+	features := make([]string, 0, len(c.Mocks))
+	for feature := range c.Mocks {
+		features = append(features, feature)
 	}
+	sort.Strings(features)
 
-	return nil
+	routes := make(map[string]string)
+	for _, feature := range features {
+		ids := make(map[string]bool)
+		for i, endpoint := range c.Mocks[feature].Endpoints {
+			if ids[endpoint.ID] {
+				problems = append(problems, fmt.Sprintf("duplicate endpoint ID %s in feature %s", endpoint.ID, feature))
+			}
+			ids[endpoint.ID] = true
+
+			// Echo and proxy endpoints get their response from elsewhere
+			if len(endpoint.Responses) == 0 && endpoint.needsResponses() {
+				problems = append(problems, fmt.Sprintf("endpoint %s in feature %s has no responses", endpoint.ID, feature))
+			}
+			names := make([]string, 0, len(endpoint.Responses))
+			for name := range endpoint.Responses {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			// A missing default response would make every request 500. Unless
+			// validation is strict, fall back to the first response by name.
+			if _, ok := endpoint.Responses[endpoint.DefaultResponse]; !ok && len(names) > 0 && endpoint.needsResponses() {
+				problem := fmt.Sprintf("endpoint %s in feature %s has defaultResponse %q, which isn't one of its responses", endpoint.ID, feature, endpoint.DefaultResponse)
+				if !c.Global.StrictValidation {
+					c.Mocks[feature].Endpoints[i].DefaultResponse = names[0]
+					problem += fmt.Sprintf("; using %q", names[0])
+				}
+				problems = append(problems, problem)
+			}
+
+			for _, name := range names {
+				if profile := endpoint.Responses[name].LatencyProfile; profile != nil {
+					if err := profile.Validate(); err != nil {
+						problems = append(problems, fmt.Sprintf("response %s of endpoint %s in feature %s has an invalid latencyProfile: %v", name, endpoint.ID, feature, err))
+					}
+				}
+				if encoded := endpoint.Responses[name].BodyBase64; encoded != "" {
+					if _, err := base64.StdEncoding.DecodeString(encoded); err != nil {
+						problems = append(problems, fmt.Sprintf("response %s of endpoint %s in feature %s has an invalid bodyBase64: %v", name, endpoint.ID, feature, err))
+					}
+				}
+			}
+
+			// Disabled features never match, so their routes can't clash
+			if !c.Mocks[feature].IsEnabled() {
+				continue
+			}
+
+			route := endpoint.Method + " " + endpoint.Path
+			owner := feature + "/" + endpoint.ID
+			if first, ok := routes[route]; ok {
+				problems = append(problems, fmt.Sprintf("duplicate route %s in %s and %s", route, first, owner))
+				continue
+			}
+			routes[route] = owner
+		}
+	}
+
+	return problems
+}
+
+// LoadErrors returns the feature files that failed to load on the last Load
+func (c *Config) LoadErrors() []LoadError {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return append([]LoadError(nil), c.loadErrors...)
 }
 
 // loadGlobalConfig loads the global configuration from the specified file
@@ -121,7 +716,7 @@ func (c *Config) loadGlobalConfig(path string) error {
 		return err
 	}
 
-	if err := json.Unmarshal(data, &c.Global); err != nil {
+	if err := json.Unmarshal(StripComments(data), &c.Global); err != nil {
 		return err
 	}
 
@@ -137,7 +732,7 @@ func (c *Config) loadFeatureConfig(path string) (FeatureConfig, error) {
 		return config, err
 	}
 
-	if err := json.Unmarshal(data, &config); err != nil {
+	if err := json.Unmarshal(StripComments(data), &config); err != nil {
 		return config, err
 	}
 
@@ -146,35 +741,60 @@ func (c *Config) loadFeatureConfig(path string) (FeatureConfig, error) {
 
 // SaveFeatureConfig saves a feature configuration to its file
 func (c *Config) SaveFeatureConfig(feature string) error {
-	c.mu.RLock()
-	featureConfig, ok := c.Mocks[feature]
-	c.mu.RUnlock()
-
-	if !ok {
-		return fmt.Errorf("feature %s not found", feature)
+	if c.BaseDir == "" {
+		return ErrNoBaseDir
 	}
 
+	// Hold the lock from reading the feature to writing the file, so
+	// concurrent saves can't interleave or write an older copy over a newer one
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	path := filepath.Join(c.BaseDir, feature+".json")
+	featureConfig, ok := c.Mocks[feature]
+	if !ok {
+		return fmt.Errorf("feature %s not found", feature)
+	}
+
+	path := c.FeaturePath(feature)
 	
-	// Ensure the directory exists
+	// Ensure the directory exists, including any subdirectory the feature lives in
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		logger.Error("Failed to create directory: %v", err)
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 	
+	// Output is deterministic: endpoints keep their slice order and
+	// encoding/json writes map keys (responses, headers) in sorted order
 	data, err := json.MarshalIndent(featureConfig, "", "  ")
 	if err != nil {
 		return err
 	}
 	
+	if err := writeFileAtomic(path, data); err != nil {
+		logger.Error("Failed to write feature config: %v", err)
+		return err
+	}
+	
+	logger.Info("Saved feature config: %s", path)
+	
+	return nil
+}
+
+// FeaturePath returns the file a feature is stored in. Feature names may
+// contain slashes, which map to subdirectories of the base directory.
+func (c *Config) FeaturePath(feature string) string {
+	return filepath.Join(c.BaseDir, filepath.FromSlash(feature)+".json")
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it over path, so readers never see a partially written file
+func writeFileAtomic(path string, data []byte) error {
 	// Create a temporary file in the same directory
 	tempFile := path + ".tmp"
 	if err := os.WriteFile(tempFile, data, 0644); err != nil {
-		logger.Error("Failed to write temporary file: %v", err)
+		// Try to remove a partially written temporary file
+		os.Remove(tempFile)
 		return fmt.Errorf("failed to write temporary file: %w", err)
 	}
 	
@@ -182,20 +802,31 @@ func (c *Config) SaveFeatureConfig(feature string) error {
 	if err := os.Rename(tempFile, path); err != nil {
 		// Try to remove the temporary file
 		os.Remove(tempFile)
-		logger.Error("Failed to rename temporary file: %v", err)
 		return fmt.Errorf("failed to rename temporary file: %w", err)
 	}
 	
-	logger.Info("Saved feature config: %s", path)
-	
 	return nil
 }
 
 // SaveGlobalConfig saves the global configuration to its file
 func (c *Config) SaveGlobalConfig() error {
+	if c.BaseDir == "" {
+		return ErrNoBaseDir
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	return c.writeGlobalConfig()
+}
+
+// writeGlobalConfig writes the global configuration to its file. The caller
+// must hold c.mu.
+func (c *Config) writeGlobalConfig() error {
+	if c.BaseDir == "" {
+		return ErrNoBaseDir
+	}
+
 	path := filepath.Join(c.BaseDir, "config.json")
 
 	// Check if directory exists
@@ -208,22 +839,39 @@ func (c *Config) SaveGlobalConfig() error {
 		return fmt.Errorf("%s is not a directory", c.BaseDir)
 	}
 
-	data, err := json.MarshalIndent(c.Global, "", "  ")
+	// With several layers, only write the settings that are BaseDir's own,
+	// so the other layers' settings aren't copied here and shadowed for good
+	var settings interface{} = c.Global
+	if c.inherited != nil {
+		current, err := jsonObject(c.Global)
+		if err != nil {
+			return fmt.Errorf("failed to marshal global config: %w", err)
+		}
+		owned := layerSettings(current, c.inherited, c.owned)
+		owned["version"] = current["version"]
+		settings = owned
+	}
+
+	data, err := json.MarshalIndent(settings, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal global config: %w", err)
 	}
 
-	// Write directly to the file
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	// Write via a temporary file so an interrupted save can't corrupt the config
+	if err := writeFileAtomic(path, data); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
+	if owned, ok := settings.(map[string]interface{}); ok {
+		c.owned = owned
+	}
 
 	// Only log at debug level for detailed operations
 	logger.LogDebug("Global config saved to %s", path)
 	return nil
 }
 
-// GetEndpoint returns an endpoint by its ID
+// GetEndpoint returns a copy of an endpoint by its ID. Changes to the copy
+// are not visible to other goroutines until passed to UpdateEndpoint.
 func (c *Config) GetEndpoint(feature, id string) (*Endpoint, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -235,13 +883,90 @@ func (c *Config) GetEndpoint(feature, id string) (*Endpoint, error) {
 
 	for i := range featureConfig.Endpoints {
 		if featureConfig.Endpoints[i].ID == id {
-			return &featureConfig.Endpoints[i], nil
+			endpoint := featureConfig.Endpoints[i].clone()
+			return &endpoint, nil
 		}
 	}
 
 	return nil, fmt.Errorf("endpoint %s not found in feature %s", id, feature)
 }
 
+// GetFeature returns a copy of a feature's configuration
+func (c *Config) GetFeature(feature string) (FeatureConfig, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	featureConfig, ok := c.Mocks[feature]
+	if !ok {
+		return FeatureConfig{}, false
+	}
+
+	endpoints := make([]Endpoint, len(featureConfig.Endpoints))
+	for i := range featureConfig.Endpoints {
+		endpoints[i] = featureConfig.Endpoints[i].clone()
+	}
+	featureConfig.Endpoints = endpoints
+
+	return featureConfig, true
+}
+
+// FeatureNames returns the names of all features in sorted order
+func (c *Config) FeatureNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.Mocks))
+	for feature := range c.Mocks {
+		names = append(names, feature)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// FindEndpoint returns a copy of the first endpoint for which match returns
+// true, along with the name of its feature. Disabled features are skipped.
+// match is called with the config read lock held and must not call back
+// into the Config.
+func (c *Config) FindEndpoint(match func(feature string, endpoint Endpoint) bool) (*Endpoint, string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for feature, featureConfig := range c.Mocks {
+		if !featureConfig.IsEnabled() {
+			continue
+		}
+		for i := range featureConfig.Endpoints {
+			if match(feature, featureConfig.Endpoints[i]) {
+				endpoint := featureConfig.Endpoints[i].clone()
+				return &endpoint, feature, true
+			}
+		}
+	}
+
+	return nil, "", false
+}
+
+// clone returns a copy of the endpoint that does not share its responses or
+// their header maps. Response bodies are shared, as they are never mutated
+// in place.
+func (e Endpoint) clone() Endpoint {
+	if e.Tags != nil {
+		e.Tags = append([]string(nil), e.Tags...)
+	}
+	if e.Responses != nil {
+		responses := make(map[string]Response, len(e.Responses))
+		for name, response := range e.Responses {
+			if response.Headers != nil {
+				response.Headers = maps.Clone(response.Headers)
+			}
+			responses[name] = response
+		}
+		e.Responses = responses
+	}
+	return e
+}
+
 // UpdateEndpoint updates an endpoint
 func (c *Config) UpdateEndpoint(feature string, endpoint Endpoint) error {
 	c.mu.Lock()
@@ -254,7 +979,7 @@ func (c *Config) UpdateEndpoint(feature string, endpoint Endpoint) error {
 
 	for i := range featureConfig.Endpoints {
 		if featureConfig.Endpoints[i].ID == endpoint.ID {
-			featureConfig.Endpoints[i] = endpoint
+			featureConfig.Endpoints[i] = endpoint.clone()
 			c.Mocks[feature] = featureConfig
 			return nil
 		}
@@ -263,7 +988,36 @@ func (c *Config) UpdateEndpoint(feature string, endpoint Endpoint) error {
 	return fmt.Errorf("endpoint %s not found in feature %s", endpoint.ID, feature)
 }
 
-// AddEndpoint adds a new endpoint to a feature
+// ModifyEndpoint applies modify to an endpoint and stores the result, all
+// under one lock, so concurrent changes to the same endpoint aren't lost the
+// way they can be with GetEndpoint followed by UpdateEndpoint. Nothing is
+// stored if modify returns an error. It returns a copy of the updated
+// endpoint.
+func (c *Config) ModifyEndpoint(feature, id string, modify func(endpoint *Endpoint) error) (*Endpoint, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	featureConfig, ok := c.Mocks[feature]
+	if !ok {
+		return nil, fmt.Errorf("feature %s not found", feature)
+	}
+
+	for i := range featureConfig.Endpoints {
+		if featureConfig.Endpoints[i].ID == id {
+			endpoint := featureConfig.Endpoints[i].clone()
+			if err := modify(&endpoint); err != nil {
+				return nil, err
+			}
+			featureConfig.Endpoints[i] = endpoint.clone()
+			c.Mocks[feature] = featureConfig
+			return &endpoint, nil
+		}
+	}
+
+	return nil, fmt.Errorf("endpoint %s not found in feature %s", id, feature)
+}
+
+// AddEndpoint adds a new endpoint to a feature, keeping its Active flag as given
 func (c *Config) AddEndpoint(feature string, endpoint Endpoint) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -280,10 +1034,10 @@ func (c *Config) AddEndpoint(feature string, endpoint Endpoint) error {
 		}
 	}
 
-	// Ensure new endpoints are inactive by default
-	endpoint.Active = false
+	// The endpoint's Active flag is kept as provided by the caller
 
-	featureConfig.Endpoints = append(featureConfig.Endpoints, endpoint)
+	// Store a copy so later changes to the caller's maps don't leak in
+	featureConfig.Endpoints = append(featureConfig.Endpoints, endpoint.clone())
 	c.Mocks[feature] = featureConfig
 	return nil
 }
@@ -301,6 +1055,20 @@ func (c *Config) AddFeature(feature FeatureConfig) error {
 	return nil
 }
 
+// RestoreFeature puts a feature back as it was before a change that couldn't
+// be saved, so memory matches what's on disk. existed false removes it, for
+// undoing the creation of a feature.
+func (c *Config) RestoreFeature(feature string, previous FeatureConfig, existed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !existed {
+		delete(c.Mocks, feature)
+		return
+	}
+	c.Mocks[feature] = previous
+}
+
 // DeleteEndpoint deletes an endpoint from a feature
 func (c *Config) DeleteEndpoint(feature, id string) error {
 	c.mu.Lock()
@@ -335,15 +1103,33 @@ func (c *Config) DeleteFeature(feature string) error {
 		return fmt.Errorf("feature %s not found", feature)
 	}
 
-	delete(c.Mocks, feature)
+	// Only BaseDir's files can be deleted. A feature from another layer
+	// would come back on the next Load.
+	path := c.FeaturePath(feature)
+	lower := c.layerFeaturePath(feature)
+	if _, err := os.Stat(path); os.IsNotExist(err) && lower != "" {
+		return fmt.Errorf("feature %s is defined in %s, not in %s; delete it there", feature, lower, c.BaseDir)
+	}
 	
 	// Delete the feature file
-	path := filepath.Join(c.BaseDir, feature+".json")
 	err := os.Remove(path)
 	if err != nil && !os.IsNotExist(err) {
 		logger.Error("Error removing feature file %s: %v", path, err)
 		return fmt.Errorf("failed to remove feature file: %w", err)
 	}
+	delete(c.Mocks, feature)
+
+	// Deleting an override brings back the feature it replaced, as Load would
+	if lower != "" {
+		featureConfig, err := c.loadFeatureConfig(lower)
+		if err != nil {
+			return fmt.Errorf("deleted feature %s, but failed to load %s: %w", feature, lower, err)
+		}
+		featureConfig.Feature = feature
+		c.Mocks[feature] = featureConfig
+		logger.Info("Feature %s deleted from %s; %s applies again", feature, c.BaseDir, lower)
+		return nil
+	}
 	
 	logger.Info("Feature %s deleted successfully", feature)
 	return nil
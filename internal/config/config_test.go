@@ -1,10 +1,16 @@
 package config_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"swoozeki/climock/internal/config"
 	"swoozeki/climock/internal/logger"
@@ -268,13 +274,13 @@ func TestEndpointManagement(t *testing.T) {
 		t.Errorf("Expected 2 endpoints, got %d", len(cfg.Mocks["test"].Endpoints))
 	}
 	
-	// Verify that the new endpoint is inactive by default, regardless of the provided value
+	// Verify that the new endpoint keeps the provided active state
 	addedEndpoint, err := cfg.GetEndpoint("test", "new-endpoint")
 	if err != nil {
 		t.Fatalf("Failed to get newly added endpoint: %v", err)
 	}
-	if addedEndpoint.Active {
-		t.Error("Expected newly added endpoint to be inactive by default")
+	if !addedEndpoint.Active {
+		t.Error("Expected newly added endpoint to stay active as provided")
 	}
 
 	// Test AddEndpoint with duplicate ID
@@ -343,4 +349,929 @@ func TestFeatureManagement(t *testing.T) {
 	if err := cfg.DeleteFeature("non-existent"); err == nil {
 		t.Error("Expected error for deleting non-existent feature, got nil")
 	}
-}
\ No newline at end of file
+}
+// TestLoadSkipsMalformedFeature tests that a corrupt feature file does not
+// prevent the remaining features from loading
+func TestLoadSkipsMalformedFeature(t *testing.T) {
+	tempDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tempDir, "config.json"), []byte(`{"serverConfig": {"port": 3000}}`), 0644); err != nil {
+		t.Fatalf("Failed to write global config file: %v", err)
+	}
+	valid := `{"feature": "users", "endpoints": [{"id": "get-users", "method": "GET", "path": "/api/users"}]}`
+	if err := os.WriteFile(filepath.Join(tempDir, "users.json"), []byte(valid), 0644); err != nil {
+		t.Fatalf("Failed to write feature config file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "broken.json"), []byte(`{"feature": "broken", "endpoints": [`), 0644); err != nil {
+		t.Fatalf("Failed to write feature config file: %v", err)
+	}
+
+	cfg := config.New(tempDir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected load to succeed, got %v", err)
+	}
+
+	if _, ok := cfg.Mocks["users"]; !ok {
+		t.Error("Expected valid feature 'users' to be loaded")
+	}
+	if len(cfg.Mocks) != 1 {
+		t.Errorf("Expected 1 feature, got %d", len(cfg.Mocks))
+	}
+
+	loadErrors := cfg.LoadErrors()
+	if len(loadErrors) != 1 {
+		t.Fatalf("Expected 1 load error, got %d", len(loadErrors))
+	}
+	if loadErrors[0].File != "broken.json" {
+		t.Errorf("Expected load error for broken.json, got %q", loadErrors[0].File)
+	}
+
+	// A clean reload clears previous errors
+	if err := os.Remove(filepath.Join(tempDir, "broken.json")); err != nil {
+		t.Fatalf("Failed to remove broken feature file: %v", err)
+	}
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if len(cfg.LoadErrors()) != 0 {
+		t.Errorf("Expected no load errors after reload, got %v", cfg.LoadErrors())
+	}
+}
+
+// TestLoadNestedFeatures tests that features in subdirectories are loaded
+// and saved back to the same nested file
+func TestLoadNestedFeatures(t *testing.T) {
+	tempDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tempDir, "config.json"), []byte(`{"serverConfig": {"port": 3000}}`), 0644); err != nil {
+		t.Fatalf("Failed to write global config file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "orders.json"), []byte(`{"feature": "orders", "endpoints": []}`), 0644); err != nil {
+		t.Fatalf("Failed to write feature config file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(tempDir, "users"), 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+	profile := `{"feature": "profile", "endpoints": [{"id": "get-profile", "method": "GET", "path": "/api/profile", "defaultResponse": "ok", "responses": {"ok": {"status": 200}}}]}`
+	if err := os.WriteFile(filepath.Join(tempDir, "users", "profile.json"), []byte(profile), 0644); err != nil {
+		t.Fatalf("Failed to write nested feature config file: %v", err)
+	}
+	// Files that aren't JSON are not features
+	for _, name := range []string{"README.md", "profile.json.tmp"} {
+		if err := os.WriteFile(filepath.Join(tempDir, "users", name), []byte("not a feature"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	cfg := config.New(tempDir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if names := cfg.FeatureNames(); strings.Join(names, ",") != "orders,users/profile" {
+		t.Fatalf("Expected features orders and users/profile, got %v", names)
+	}
+	if loadErrors := cfg.LoadErrors(); len(loadErrors) != 0 {
+		t.Errorf("Expected files that aren't JSON to be ignored, got load errors %v", loadErrors)
+	}
+
+	endpoint, err := cfg.GetEndpoint("users/profile", "get-profile")
+	if err != nil {
+		t.Fatalf("Failed to get nested endpoint: %v", err)
+	}
+	endpoint.Active = true
+	if err := cfg.UpdateEndpoint("users/profile", *endpoint); err != nil {
+		t.Fatalf("Failed to update endpoint: %v", err)
+	}
+	if err := cfg.SaveFeatureConfig("users/profile"); err != nil {
+		t.Fatalf("Failed to save nested feature: %v", err)
+	}
+
+	// The change lands in the original nested file and nowhere else
+	reloaded := config.New(tempDir)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if len(reloaded.Mocks) != 2 {
+		t.Errorf("Expected 2 features after saving, got %v", reloaded.FeatureNames())
+	}
+	saved, err := reloaded.GetEndpoint("users/profile", "get-profile")
+	if err != nil {
+		t.Fatalf("Failed to get nested endpoint after reload: %v", err)
+	}
+	if !saved.Active {
+		t.Error("Expected the saved change to be read back from users/profile.json")
+	}
+}
+
+// TestLoadDetectsDuplicates tests that duplicate endpoint IDs and routes are
+// reported on load, and rejected under strict validation
+func TestLoadDetectsDuplicates(t *testing.T) {
+	tempDir := t.TempDir()
+
+	users := `{"feature": "users", "endpoints": [
+		{"id": "get-users", "method": "GET", "path": "/api/users"},
+		{"id": "get-users", "method": "POST", "path": "/api/users"}
+	]}`
+	admin := `{"feature": "admin", "endpoints": [
+		{"id": "list-users", "method": "GET", "path": "/api/users"}
+	]}`
+	if err := os.WriteFile(filepath.Join(tempDir, "config.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatalf("Failed to write global config file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "users.json"), []byte(users), 0644); err != nil {
+		t.Fatalf("Failed to write feature config file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "admin.json"), []byte(admin), 0644); err != nil {
+		t.Fatalf("Failed to write feature config file: %v", err)
+	}
+
+	// Capture warnings, which are only logged in debug mode
+	var buf bytes.Buffer
+	logger.Logger = log.New(&buf, "", 0)
+	logger.IsDebugMode = true
+	defer logger.InitTestLogger()
+
+	cfg := config.New(tempDir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected non-strict load to succeed, got %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "duplicate endpoint ID get-users in feature users") {
+		t.Errorf("Expected duplicate ID warning, got %q", output)
+	}
+	if !strings.Contains(output, "duplicate route GET /api/users in admin/list-users and users/get-users") {
+		t.Errorf("Expected duplicate route warning, got %q", output)
+	}
+
+	// Strict validation turns the warnings into a load error
+	if err := os.WriteFile(filepath.Join(tempDir, "config.json"), []byte(`{"strictValidation": true}`), 0644); err != nil {
+		t.Fatalf("Failed to write global config file: %v", err)
+	}
+	err := cfg.Load()
+	if err == nil {
+		t.Fatal("Expected strict load to fail on duplicates")
+	}
+	if !strings.Contains(err.Error(), "duplicate endpoint ID get-users") {
+		t.Errorf("Expected error to describe the duplicate, got %v", err)
+	}
+}
+
+// TestLoadFlagsEndpointsWithoutResponses tests that endpoints with no
+// responses are reported, except echo endpoints which don't need any
+func TestLoadFlagsEndpointsWithoutResponses(t *testing.T) {
+	tempDir := t.TempDir()
+
+	users := `{"feature": "users", "endpoints": [
+		{"id": "get-users", "method": "GET", "path": "/api/users", "responses": {}},
+		{"id": "echo", "method": "POST", "path": "/api/echo", "responseType": "echo"}
+	]}`
+	if err := os.WriteFile(filepath.Join(tempDir, "config.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatalf("Failed to write global config file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "users.json"), []byte(users), 0644); err != nil {
+		t.Fatalf("Failed to write feature config file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	logger.Logger = log.New(&buf, "", 0)
+	logger.IsDebugMode = true
+	defer logger.InitTestLogger()
+
+	cfg := config.New(tempDir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected non-strict load to succeed, got %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "endpoint get-users in feature users has no responses") {
+		t.Errorf("Expected warning for endpoint without responses, got %q", output)
+	}
+	if strings.Contains(output, "endpoint echo in feature users") {
+		t.Errorf("Expected no warning for echo endpoint, got %q", output)
+	}
+}
+
+// TestSaveGlobalConfigAtomic tests that saving the global config leaves a
+// complete file and no temporary file behind
+func TestSaveGlobalConfigAtomic(t *testing.T) {
+	tempDir := t.TempDir()
+
+	cfg := config.New(tempDir)
+	cfg.Global.ServerConfig.Port = 4000
+	cfg.Global.ProxyConfig.Target = "https://api.example.com"
+
+	if err := cfg.SaveGlobalConfig(); err != nil {
+		t.Fatalf("Failed to save global config: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "config.json"))
+	if err != nil {
+		t.Fatalf("Failed to read saved config: %v", err)
+	}
+
+	var saved config.GlobalConfig
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Saved config is not valid JSON: %v", err)
+	}
+	if saved.ServerConfig.Port != 4000 || saved.ProxyConfig.Target != "https://api.example.com" {
+		t.Errorf("Saved config does not match, got %+v", saved)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "config.json.tmp")); !os.IsNotExist(err) {
+		t.Errorf("Expected no temporary file to remain, got %v", err)
+	}
+}
+
+// TestSaveWithoutBaseDir tests that an in-memory config refuses to save
+// instead of writing to the working directory
+func TestSaveWithoutBaseDir(t *testing.T) {
+	// Run from an empty directory so any stray write would be visible
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	cfg := config.New("")
+	if err := cfg.AddFeature(config.FeatureConfig{Feature: "users"}); err != nil {
+		t.Fatalf("Failed to add feature: %v", err)
+	}
+
+	if err := cfg.SaveFeatureConfig("users"); !errors.Is(err, config.ErrNoBaseDir) {
+		t.Errorf("Expected ErrNoBaseDir saving a feature, got %v", err)
+	}
+	if err := cfg.SaveGlobalConfig(); !errors.Is(err, config.ErrNoBaseDir) {
+		t.Errorf("Expected ErrNoBaseDir saving the global config, got %v", err)
+	}
+
+	entries, err := os.ReadDir(".")
+	if err != nil {
+		t.Fatalf("Failed to read working directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected nothing written to the working directory, found %d entries", len(entries))
+	}
+}
+
+// TestAddEndpointCopiesResponses tests that changing the caller's response
+// maps after AddEndpoint does not affect the stored endpoint
+func TestAddEndpointCopiesResponses(t *testing.T) {
+	cfg := config.New("")
+	if err := cfg.AddFeature(config.FeatureConfig{Feature: "test"}); err != nil {
+		t.Fatalf("Failed to add feature: %v", err)
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	responses := map[string]config.Response{
+		"standard": {Status: 200, Headers: headers},
+	}
+	endpoint := config.Endpoint{
+		ID:              "shared",
+		Method:          "GET",
+		Path:            "/api/shared",
+		DefaultResponse: "standard",
+		Responses:       responses,
+	}
+	if err := cfg.AddEndpoint("test", endpoint); err != nil {
+		t.Fatalf("Failed to add endpoint: %v", err)
+	}
+
+	// Mutate the source maps after creation
+	headers["Content-Type"] = "text/plain"
+	responses["error"] = config.Response{Status: 500}
+
+	stored, err := cfg.GetEndpoint("test", "shared")
+	if err != nil {
+		t.Fatalf("Failed to get endpoint: %v", err)
+	}
+	if len(stored.Responses) != 1 {
+		t.Errorf("Expected 1 stored response, got %d", len(stored.Responses))
+	}
+	if contentType := stored.Responses["standard"].Headers["Content-Type"]; contentType != "application/json" {
+		t.Errorf("Expected stored Content-Type to be unchanged, got %q", contentType)
+	}
+}
+
+// TestAddEndpointKeepsActiveFlag tests that AddEndpoint stores the active
+// state exactly as provided
+func TestAddEndpointKeepsActiveFlag(t *testing.T) {
+	cfg := config.New("")
+	if err := cfg.AddFeature(config.FeatureConfig{Feature: "test"}); err != nil {
+		t.Fatalf("Failed to add feature: %v", err)
+	}
+
+	for _, active := range []bool{true, false} {
+		id := fmt.Sprintf("endpoint-%v", active)
+		if err := cfg.AddEndpoint("test", config.Endpoint{ID: id, Method: "GET", Path: "/api/" + id, Active: active}); err != nil {
+			t.Fatalf("Failed to add endpoint: %v", err)
+		}
+
+		endpoint, err := cfg.GetEndpoint("test", id)
+		if err != nil {
+			t.Fatalf("Failed to get endpoint: %v", err)
+		}
+		if endpoint.Active != active {
+			t.Errorf("Expected %s to have active=%v, got %v", id, active, endpoint.Active)
+		}
+	}
+}
+
+// TestSaveFeatureConfigDeterministic tests that saving an unchanged feature
+// twice produces identical files, with endpoints kept in order
+func TestSaveFeatureConfigDeterministic(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := config.New(tempDir)
+
+	responses := map[string]config.Response{}
+	for _, name := range []string{"standard", "error", "slow", "empty", "premium"} {
+		responses[name] = config.Response{
+			Status:  200,
+			Headers: map[string]string{"Content-Type": "application/json", "X-Name": name, "X-Trace": "1"},
+			Body:    map[string]interface{}{"name": name, "count": 1, "tags": []string{"a", "b"}},
+		}
+	}
+	feature := config.FeatureConfig{
+		Feature: "users",
+		Endpoints: []config.Endpoint{
+			{ID: "z-last", Method: "GET", Path: "/api/z", DefaultResponse: "standard", Responses: responses},
+			{ID: "a-first", Method: "GET", Path: "/api/a", DefaultResponse: "standard", Responses: responses},
+		},
+	}
+	if err := cfg.AddFeature(feature); err != nil {
+		t.Fatalf("Failed to add feature: %v", err)
+	}
+
+	path := filepath.Join(tempDir, "users.json")
+	var previous []byte
+	for i := 0; i < 5; i++ {
+		if err := cfg.SaveFeatureConfig("users"); err != nil {
+			t.Fatalf("Failed to save feature config: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read feature config: %v", err)
+		}
+		if previous != nil && !bytes.Equal(previous, data) {
+			t.Fatalf("Expected identical output on save %d", i+1)
+		}
+		previous = data
+	}
+
+	// Endpoints keep their in-memory order rather than being sorted
+	if strings.Index(string(previous), "z-last") > strings.Index(string(previous), "a-first") {
+		t.Error("Expected endpoint order to be preserved")
+	}
+}
+
+// TestDescriptionRoundTrip tests that endpoint and response descriptions
+// survive a save and load
+func TestDescriptionRoundTrip(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "config.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatalf("Failed to write global config file: %v", err)
+	}
+
+	cfg := config.New(tempDir)
+	feature := config.FeatureConfig{
+		Feature: "users",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "get-users",
+				Method:          "GET",
+				Path:            "/api/users",
+				Description:     "Lists all users",
+				DefaultResponse: "error",
+				Responses: map[string]config.Response{
+					"error": {Status: 500, Description: "Simulated outage"},
+				},
+			},
+		},
+	}
+	if err := cfg.AddFeature(feature); err != nil {
+		t.Fatalf("Failed to add feature: %v", err)
+	}
+	if err := cfg.SaveFeatureConfig("users"); err != nil {
+		t.Fatalf("Failed to save feature config: %v", err)
+	}
+
+	loaded := config.New(tempDir)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	endpoint, err := loaded.GetEndpoint("users", "get-users")
+	if err != nil {
+		t.Fatalf("Failed to get endpoint: %v", err)
+	}
+	if endpoint.Description != "Lists all users" {
+		t.Errorf("Expected endpoint description to round-trip, got %q", endpoint.Description)
+	}
+	if description := endpoint.Responses["error"].Description; description != "Simulated outage" {
+		t.Errorf("Expected response description to round-trip, got %q", description)
+	}
+}
+
+// TestTagsRoundTrip tests that endpoint tags are saved and loaded, and that
+// copies don't share them
+func TestTagsRoundTrip(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "config.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatalf("Failed to write global config file: %v", err)
+	}
+
+	cfg := config.New(tempDir)
+	feature := config.FeatureConfig{
+		Feature: "users",
+		Endpoints: []config.Endpoint{
+			{ID: "login", Method: "POST", Path: "/api/login", Tags: []string{"auth", "public"}, DefaultResponse: "ok",
+				Responses: map[string]config.Response{"ok": {Status: 200}}},
+		},
+	}
+	if err := cfg.AddFeature(feature); err != nil {
+		t.Fatalf("Failed to add feature: %v", err)
+	}
+	if err := cfg.SaveFeatureConfig("users"); err != nil {
+		t.Fatalf("Failed to save feature config: %v", err)
+	}
+
+	loaded := config.New(tempDir)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	endpoint, err := loaded.GetEndpoint("users", "login")
+	if err != nil {
+		t.Fatalf("Failed to get endpoint: %v", err)
+	}
+	if strings.Join(endpoint.Tags, ",") != "auth,public" {
+		t.Errorf("Expected tags to round-trip, got %v", endpoint.Tags)
+	}
+
+	endpoint.Tags[0] = "changed"
+	if again, _ := loaded.GetEndpoint("users", "login"); again.Tags[0] != "auth" {
+		t.Errorf("Expected a copy's tags to be its own, got %v", again.Tags)
+	}
+}
+
+// TestLatencyProfileSample tests interpolating delays between percentile points
+func TestLatencyProfileSample(t *testing.T) {
+	profile := config.LatencyProfile{"p50": 10, "p99": 500}
+	if err := profile.Validate(); err != nil {
+		t.Fatalf("Expected a valid profile, got %v", err)
+	}
+
+	tests := []struct {
+		quantile float64
+		want     time.Duration
+	}{
+		{0, 0},
+		{0.25, 5 * time.Millisecond},
+		{0.5, 10 * time.Millisecond},
+		{0.99, 500 * time.Millisecond},
+		{0.995, 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := profile.Sample(tt.quantile); got != tt.want {
+			t.Errorf("Sample(%v) = %v, want %v", tt.quantile, got, tt.want)
+		}
+	}
+
+	// A p0 point raises the minimum delay
+	withFloor := config.LatencyProfile{"p0": 4, "p100": 8}
+	if got := withFloor.Sample(0.5); got != 6*time.Millisecond {
+		t.Errorf("Expected 6ms halfway between p0 and p100, got %v", got)
+	}
+
+	for _, invalid := range []config.LatencyProfile{
+		{"50": 10},
+		{"p101": 10},
+		{"pfast": 10},
+		{"p50": -1},
+		{"p50": 100, "p99": 10},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected %v to be invalid", invalid)
+		}
+	}
+}
+
+// TestLoadFlagsInvalidLatencyProfile tests that Load reports a bad latencyProfile
+func TestLoadFlagsInvalidLatencyProfile(t *testing.T) {
+	tempDir := t.TempDir()
+
+	users := `{"feature": "users", "endpoints": [
+		{"id": "get-users", "method": "GET", "path": "/api/users", "defaultResponse": "slow",
+		 "responses": {"slow": {"status": 200, "latencyProfile": {"p50": 100, "p90": 10}}}}
+	]}`
+	if err := os.WriteFile(filepath.Join(tempDir, "config.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatalf("Failed to write global config file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "users.json"), []byte(users), 0644); err != nil {
+		t.Fatalf("Failed to write feature config file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	logger.Logger = log.New(&buf, "", 0)
+	logger.IsDebugMode = true
+	defer logger.InitTestLogger()
+
+	cfg := config.New(tempDir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected non-strict load to succeed, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "response slow of endpoint get-users in feature users has an invalid latencyProfile") {
+		t.Errorf("Expected warning for the invalid latency profile, got %q", buf.String())
+	}
+}
+
+// TestLoadRepairsMissingDefaultResponse tests that a defaultResponse naming
+// no response is replaced with the first response, or fails a strict load
+func TestLoadRepairsMissingDefaultResponse(t *testing.T) {
+	tempDir := t.TempDir()
+
+	users := `{"feature": "users", "endpoints": [
+		{"id": "get-users", "method": "GET", "path": "/api/users", "defaultResponse": "removed",
+		 "responses": {"success": {"status": 200}, "error": {"status": 500}}}
+	]}`
+	if err := os.WriteFile(filepath.Join(tempDir, "config.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatalf("Failed to write global config file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "users.json"), []byte(users), 0644); err != nil {
+		t.Fatalf("Failed to write feature config file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	logger.Logger = log.New(&buf, "", 0)
+	logger.IsDebugMode = true
+	defer logger.InitTestLogger()
+
+	cfg := config.New(tempDir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected non-strict load to succeed, got %v", err)
+	}
+	endpoint, err := cfg.GetEndpoint("users", "get-users")
+	if err != nil {
+		t.Fatalf("Failed to get endpoint: %v", err)
+	}
+	if endpoint.DefaultResponse != "error" {
+		t.Errorf("Expected the first response by name to become the default, got %q", endpoint.DefaultResponse)
+	}
+	if !strings.Contains(buf.String(), `endpoint get-users in feature users has defaultResponse "removed", which isn't one of its responses; using "error"`) {
+		t.Errorf("Expected a warning about the repaired default, got %q", buf.String())
+	}
+
+	// Strict validation refuses the config instead of repairing it
+	if err := os.WriteFile(filepath.Join(tempDir, "config.json"), []byte(`{"strictValidation": true}`), 0644); err != nil {
+		t.Fatalf("Failed to write global config file: %v", err)
+	}
+	err = cfg.Load()
+	if err == nil {
+		t.Fatal("Expected strict load to fail on a missing default response")
+	}
+	if !strings.Contains(err.Error(), `has defaultResponse "removed"`) {
+		t.Errorf("Expected error to describe the missing default, got %v", err)
+	}
+}
+
+func TestLoadMigratesVersion0(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Version 0 files have no version field and may leave the port out
+	global := `{"serverConfig": {"host": "localhost"}, "proxyConfig": {"target": "http://example.com"}}`
+	path := filepath.Join(tempDir, "config.json")
+	if err := os.WriteFile(path, []byte(global), 0644); err != nil {
+		t.Fatalf("Failed to write global config file: %v", err)
+	}
+
+	cfg := config.New(tempDir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Global.Version != config.CurrentVersion {
+		t.Errorf("Expected version %d, got %d", config.CurrentVersion, cfg.Global.Version)
+	}
+	if cfg.Global.ServerConfig.Port != config.DefaultPort {
+		t.Errorf("Expected the missing port to become %d, got %d", config.DefaultPort, cfg.Global.ServerConfig.Port)
+	}
+	if cfg.Global.ProxyConfig.Target != "http://example.com" {
+		t.Errorf("Expected other settings to be kept, got proxy target %q", cfg.Global.ProxyConfig.Target)
+	}
+
+	// The migrated config is written back
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read global config file: %v", err)
+	}
+	var saved config.GlobalConfig
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Failed to parse saved config: %v", err)
+	}
+	if saved.Version != config.CurrentVersion || saved.ServerConfig.Port != config.DefaultPort {
+		t.Errorf("Expected the migration to be saved, got %s", data)
+	}
+
+	// Loading a current file leaves it alone
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read global config file: %v", err)
+	}
+	if string(after) != string(data) {
+		t.Errorf("Expected a current config to be unchanged, got %s", after)
+	}
+}
+
+// TestLoadMigratesLayersInMemory tests that migrating layered settings
+// leaves every layer's files alone
+func TestLoadMigratesLayersInMemory(t *testing.T) {
+	base, local := t.TempDir(), t.TempDir()
+	global := `{"serverConfig": {"host": "localhost"}, "proxyConfig": {"target": "http://example.com"}}`
+	baseConfig := filepath.Join(base, "config.json")
+	if err := os.WriteFile(baseConfig, []byte(global), 0644); err != nil {
+		t.Fatalf("Failed to write global config file: %v", err)
+	}
+
+	cfg := config.New(base, local)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Global.Version != config.CurrentVersion || cfg.Global.ServerConfig.Port != config.DefaultPort {
+		t.Errorf("Expected the merged settings to be migrated, got version %d and port %d", cfg.Global.Version, cfg.Global.ServerConfig.Port)
+	}
+
+	if _, err := os.Stat(filepath.Join(local, "config.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected no config.json to be created in %s, got %v", local, err)
+	}
+	if data, err := os.ReadFile(baseConfig); err != nil || string(data) != global {
+		t.Errorf("Expected the base config to be untouched, got %s (%v)", data, err)
+	}
+}
+
+func TestLoadWithComments(t *testing.T) {
+	tempDir := t.TempDir()
+
+	global := `{
+  // Shared by every feature
+  "version": 1,
+  "serverConfig": {"host": "localhost", "port": 3000 /* default */}
+}`
+	if err := os.WriteFile(filepath.Join(tempDir, "config.json"), []byte(global), 0644); err != nil {
+		t.Fatalf("Failed to write global config file: %v", err)
+	}
+
+	// Comment markers inside strings are part of the value
+	feature := `{
+  "feature": "users", // the feature name
+  /*
+   * Endpoints used by the login page
+   */
+  "endpoints": [
+    {
+      "id": "get-user",
+      "method": "GET",
+      "path": "/api/users",
+      "active": true,
+      "defaultResponse": "success",
+      "responses": {
+        "success": {"status": 200, "body": {"homepage": "http://example.com/*", "note": "a \"// quoted\" string"}}
+      }
+    }
+  ]
+}`
+	path := filepath.Join(tempDir, "users.json")
+	if err := os.WriteFile(path, []byte(feature), 0644); err != nil {
+		t.Fatalf("Failed to write feature file: %v", err)
+	}
+
+	cfg := config.New(tempDir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if errs := cfg.LoadErrors(); len(errs) != 0 {
+		t.Fatalf("Expected the commented feature to load, got %v", errs)
+	}
+	if cfg.Global.ServerConfig.Port != 3000 {
+		t.Errorf("Expected port 3000, got %d", cfg.Global.ServerConfig.Port)
+	}
+
+	endpoint, err := cfg.GetEndpoint("users", "get-user")
+	if err != nil {
+		t.Fatalf("Failed to get endpoint: %v", err)
+	}
+	body, _ := endpoint.Responses["success"].Body.(map[string]interface{})
+	if body["homepage"] != "http://example.com/*" || body["note"] != `a "// quoted" string` {
+		t.Errorf("Expected strings to keep their comment markers, got %v", body)
+	}
+
+	// Saving writes plain JSON
+	if err := cfg.SaveFeatureConfig("users"); err != nil {
+		t.Fatalf("Failed to save feature: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read feature file: %v", err)
+	}
+	if !json.Valid(data) {
+		t.Errorf("Expected the saved feature to be plain JSON, got %s", data)
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	input := "{\n  \"a\": 1, // one\n  /* two\n  lines */ \"b\": 2\n}"
+	stripped := config.StripComments([]byte(input))
+	if len(stripped) != len(input) || bytes.Count(stripped, []byte("\n")) != strings.Count(input, "\n") {
+		t.Errorf("Expected offsets and lines to be preserved, got %q", stripped)
+	}
+
+	var value map[string]int
+	if err := json.Unmarshal(stripped, &value); err != nil || value["a"] != 1 || value["b"] != 2 {
+		t.Errorf("Expected the stripped JSON to parse, got %v (%v)", value, err)
+	}
+
+	// An unterminated comment is left for the parser to report
+	if err := json.Unmarshal(config.StripComments([]byte(`{"a": 1} /* open`)), &value); err == nil {
+		t.Error("Expected an unterminated comment to be rejected")
+	}
+}
+
+func TestLoadLayeredDirs(t *testing.T) {
+	base, local := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		filepath.Join(base, "config.json"): `{"version": 1, "serverConfig": {"host": "localhost", "port": 4000}, "proxyConfig": {"target": "http://example.com"}}`,
+		filepath.Join(base, "users.json"): `{"feature": "users", "endpoints": [
+			{"id": "get-user", "method": "GET", "path": "/api/users", "defaultResponse": "shared",
+			 "responses": {"shared": {"status": 200, "body": {"name": "base"}}}}]}`,
+		filepath.Join(base, "orders.json"): `{"feature": "orders", "endpoints": [
+			{"id": "get-orders", "method": "GET", "path": "/api/orders", "defaultResponse": "shared",
+			 "responses": {"shared": {"status": 200, "body": []}}}]}`,
+		// The local directory overrides one setting and one feature
+		filepath.Join(local, "config.json"): `{"serverConfig": {"port": 5000}}`,
+		filepath.Join(local, "users.json"): `{"feature": "users", "endpoints": [
+			{"id": "get-user", "method": "GET", "path": "/api/users", "defaultResponse": "local",
+			 "responses": {"local": {"status": 200, "body": {"name": "local"}}}}]}`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	cfg := config.New(base, local)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if port := cfg.Global.ServerConfig.Port; port != 5000 {
+		t.Errorf("Expected the local port 5000, got %d", port)
+	}
+	if target := cfg.Global.ProxyConfig.Target; target != "http://example.com" {
+		t.Errorf("Expected the base proxy target to be kept, got %q", target)
+	}
+
+	users, err := cfg.GetEndpoint("users", "get-user")
+	if err != nil {
+		t.Fatalf("Failed to get endpoint: %v", err)
+	}
+	if _, ok := users.Responses["local"]; !ok || len(users.Responses) != 1 {
+		t.Errorf("Expected the local users feature to replace the base one, got %v", users.Responses)
+	}
+	if _, ok := cfg.GetFeature("orders"); !ok {
+		t.Error("Expected the base orders feature to be loaded")
+	}
+
+	// Saves go to the last directory, leaving the shared one alone
+	if cfg.BaseDir != local {
+		t.Errorf("Expected saves to go to %s, got %s", local, cfg.BaseDir)
+	}
+	if err := cfg.SaveFeatureConfig("orders"); err != nil {
+		t.Fatalf("Failed to save feature: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(local, "orders.json")); err != nil {
+		t.Errorf("Expected orders to be saved to the local directory: %v", err)
+	}
+	baseOrders := filepath.Join(base, "orders.json")
+	if data, err := os.ReadFile(baseOrders); err != nil || string(data) != files[baseOrders] {
+		t.Errorf("Expected the base orders file to be untouched, got %s (%v)", data, err)
+	}
+}
+
+// TestSaveLayeredGlobalConfig tests that saving with several config
+// directories writes only the top one's own settings, so the shared ones
+// still apply when they change
+func TestSaveLayeredGlobalConfig(t *testing.T) {
+	base, local := t.TempDir(), t.TempDir()
+	baseConfig := filepath.Join(base, "config.json")
+	localConfig := filepath.Join(local, "config.json")
+	files := map[string]string{
+		baseConfig: `{"version": 1, "serverConfig": {"host": "localhost", "port": 4000}, "proxyConfig": {"target": "http://example.com"},
+			"lenientTemplates": true, "defaultHeaders": {"X-Env": "shared"}}`,
+		localConfig: `{"serverConfig": {"port": 5000}, "auditLog": "audit.jsonl"}`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	cfg := config.New(base, local)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cfg.Global.UI = &config.UIConfig{Theme: "light"}
+	cfg.Global.LenientTemplates = false
+	if err := cfg.SaveGlobalConfig(); err != nil {
+		t.Fatalf("Failed to save global config: %v", err)
+	}
+
+	data, err := os.ReadFile(localConfig)
+	if err != nil {
+		t.Fatalf("Failed to read local config: %v", err)
+	}
+	var saved map[string]interface{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Failed to parse local config: %v", err)
+	}
+	for _, key := range []string{"proxyConfig", "defaultHeaders", "editor"} {
+		if _, ok := saved[key]; ok {
+			t.Errorf("Expected %s to be left to the base directory, got %s", key, data)
+		}
+	}
+	if server, _ := saved["serverConfig"].(map[string]interface{}); len(server) != 1 || server["port"] != float64(5000) {
+		t.Errorf("Expected only the local port in serverConfig, got %v", saved["serverConfig"])
+	}
+	if saved["auditLog"] != "audit.jsonl" || saved["lenientTemplates"] != false || saved["version"] != float64(1) {
+		t.Errorf("Expected the local settings and the cleared lenientTemplates, got %s", data)
+	}
+	if ui, _ := saved["ui"].(map[string]interface{}); ui["theme"] != "light" {
+		t.Errorf("Expected the new ui settings, got %v", saved["ui"])
+	}
+
+	// Changes to the base directory still come through
+	if err := os.WriteFile(baseConfig, []byte(`{"version": 1, "serverConfig": {"host": "0.0.0.0", "port": 4000}, "proxyConfig": {"target": "http://other.example.com"}, "lenientTemplates": true}`), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", baseConfig, err)
+	}
+	reloaded := config.New(base, local)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	global := reloaded.Global
+	if global.ProxyConfig.Target != "http://other.example.com" || global.ServerConfig.Host != "0.0.0.0" {
+		t.Errorf("Expected the base directory's new target and host, got %q and %q", global.ProxyConfig.Target, global.ServerConfig.Host)
+	}
+	if global.ServerConfig.Port != 5000 || global.LenientTemplates || global.UI == nil || global.UI.Theme != "light" {
+		t.Errorf("Expected the local settings to still apply, got %+v", global)
+	}
+}
+
+// TestDeleteLayeredFeature tests that only features in the top config
+// directory can be deleted, and that deleting one that overrides a lower
+// feature brings that one back
+func TestDeleteLayeredFeature(t *testing.T) {
+	base, local := t.TempDir(), t.TempDir()
+	feature := func(name, response string) string {
+		return fmt.Sprintf(`{"feature": %q, "endpoints": [{"id": "get", "method": "GET", "path": "/api/%s",
+			"defaultResponse": %q, "responses": {%q: {"status": 200}}}]}`, name, name, response, response)
+	}
+	files := map[string]string{
+		filepath.Join(base, "config.json"): `{"version": 1}`,
+		filepath.Join(base, "orders.json"): feature("orders", "shared"),
+		filepath.Join(base, "users.json"):  feature("users", "shared"),
+		filepath.Join(local, "users.json"): feature("users", "local"),
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	cfg := config.New(base, local)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if err := cfg.DeleteFeature("orders"); err == nil {
+		t.Error("Expected an error deleting a feature from the base directory, got nil")
+	}
+	if _, ok := cfg.GetFeature("orders"); !ok {
+		t.Error("Expected the orders feature to be kept after a refused delete")
+	}
+	if _, err := os.Stat(filepath.Join(base, "orders.json")); err != nil {
+		t.Errorf("Expected the base orders file to be kept: %v", err)
+	}
+
+	if err := cfg.DeleteFeature("users"); err != nil {
+		t.Fatalf("Failed to delete the local users feature: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(local, "users.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected the local users file to be removed, got %v", err)
+	}
+	users, err := cfg.GetEndpoint("users", "get")
+	if err != nil || users.DefaultResponse != "shared" {
+		t.Errorf("Expected the base users feature to apply again, got %v (%v)", users, err)
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// loadLayerSettings records the settings of one layer's config.json at path:
+// in c.owned if dir is BaseDir, otherwise applied over inherited
+func (c *Config) loadLayerSettings(dir, path string, inherited *GlobalConfig) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if dir == c.BaseDir {
+		return json.Unmarshal(StripComments(data), &c.owned)
+	}
+	return json.Unmarshal(StripComments(data), inherited)
+}
+
+// layerFeaturePath returns the file of the last layer other than BaseDir
+// that defines feature, or "" if none does
+func (c *Config) layerFeaturePath(feature string) string {
+	layers := c.layers()
+	for i := len(layers) - 1; i >= 0; i-- {
+		if layers[i] == c.BaseDir {
+			continue
+		}
+		path := filepath.Join(layers[i], filepath.FromSlash(feature)+".json")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// jsonObject returns v encoded as a JSON object and decoded again
+func jsonObject(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]interface{}
+	err = json.Unmarshal(data, &object)
+	return object, err
+}
+
+// layerSettings returns the settings in current that a layer has to set to
+// get current when loaded over inherited: those that differ from it, and
+// those the layer set already, which it keeps owning. Objects are compared
+// key by key, since loading merges them. A setting that inherited has but
+// current leaves out is written as its zero value, to clear it.
+func layerSettings(current, inherited, owned map[string]interface{}) map[string]interface{} {
+	settings := make(map[string]interface{})
+	for key, value := range current {
+		base, inheritedKey := inherited[key]
+		ownedValue, ownedKey := owned[key]
+
+		valueObject, isObject := value.(map[string]interface{})
+		baseObject, baseIsObject := base.(map[string]interface{})
+		if isObject && baseIsObject {
+			ownedObject, _ := ownedValue.(map[string]interface{})
+			if nested := layerSettings(valueObject, baseObject, ownedObject); len(nested) > 0 || ownedKey {
+				settings[key] = nested
+			}
+			continue
+		}
+
+		if ownedKey || !inheritedKey || !reflect.DeepEqual(value, base) {
+			settings[key] = value
+		}
+	}
+
+	for key, base := range inherited {
+		if _, ok := current[key]; !ok {
+			settings[key] = zeroJSON(base)
+		}
+	}
+	return settings
+}
+
+// zeroJSON returns the zero value of a decoded JSON value's type. Scalars
+// need a typed zero, since decoding null into them leaves them unchanged.
+func zeroJSON(value interface{}) interface{} {
+	switch value.(type) {
+	case bool:
+		return false
+	case float64:
+		return 0
+	case string:
+		return ""
+	}
+	return nil
+}
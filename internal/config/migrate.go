@@ -0,0 +1,46 @@
+package config
+
+import (
+	"swoozeki/climock/internal/logger"
+)
+
+// CurrentVersion is the config.json format version written by this build.
+// Files with an older version are migrated when they're loaded.
+const CurrentVersion = 1
+
+// DefaultPort is the server port used when config.json doesn't set one
+const DefaultPort = 3000
+
+// migrations upgrade a global config by one version each: the migration at
+// index i turns version i into version i+1
+var migrations = []func(g *GlobalConfig){
+	migrateToV1,
+}
+
+// migrateToV1 fills in the server port. Before versions existed a missing
+// port made the server listen on a random one, which was never what a
+// config.json meant.
+func migrateToV1(g *GlobalConfig) {
+	if g.ServerConfig.Port == 0 && g.ServerConfig.SocketPath == "" {
+		g.ServerConfig.Port = DefaultPort
+	}
+}
+
+// migrate upgrades g to CurrentVersion and reports whether it changed
+func (g *GlobalConfig) migrate() bool {
+	if g.Version > CurrentVersion {
+		logger.Warn("config.json is version %d, newer than the %d this climock supports; some settings may be ignored",
+			g.Version, CurrentVersion)
+		return false
+	}
+	if g.Version == CurrentVersion {
+		return false
+	}
+
+	for version := g.Version; version < CurrentVersion; version++ {
+		migrations[version](g)
+	}
+	logger.Info("Migrated config.json from version %d to %d", g.Version, CurrentVersion)
+	g.Version = CurrentVersion
+	return true
+}
@@ -267,6 +267,14 @@ func HTTPRequest(method, path, ip string, statusCode int, duration time.Duration
 	Logger.Println(formatMessage(level, "%s %s from %s - %d (%s)", method, path, ip, statusCode, duration))
 }
 
+// Trace logs a request tracing message. Tracing is switched on explicitly,
+// so these are logged even when debug mode is off.
+func Trace(format string, args ...interface{}) {
+	if Logger != nil {
+		Logger.Println(formatMessage("TRACE", format, args...))
+	}
+}
+
 // ProxyError logs a proxy error
 func ProxyError(target string, err error) {
 	if Logger != nil {
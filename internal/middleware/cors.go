@@ -1,6 +1,10 @@
 package middleware
 
-import "github.com/gin-gonic/gin"
+import (
+	"swoozeki/climock/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
 
 // CORSHeaders is a map of CORS headers
 var CORSHeaders = map[string]bool{
@@ -11,19 +15,76 @@ var CORSHeaders = map[string]bool{
 	"Access-Control-Expose-Headers":    true,
 }
 
-// CORSMiddleware returns a middleware that adds CORS headers to all responses
-func CORSMiddleware() gin.HandlerFunc {
+// CORSOptions lets the server adjust the CORS middleware per request
+type CORSOptions struct {
+	// IsHandled reports whether something else, such as a mock, answers an
+	// OPTIONS request instead of the 204 preflight response
+	IsHandled func(c *gin.Context) bool
+	// Policy returns the CORS policy for a request, or nil for the defaults
+	Policy func(c *gin.Context) *config.CORSConfig
+	// Skip reports whether a request is passed on untouched, leaving its
+	// CORS headers, preflight included, to whatever answers it
+	Skip func(c *gin.Context) bool
+}
+
+// CORSMiddleware returns a middleware that adds CORS headers to all responses.
+// OPTIONS requests are answered with a 204 preflight response unless
+// opts.IsHandled reports that something else answers them.
+func CORSMiddleware(opts CORSOptions) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
+		if opts.Skip != nil && opts.Skip(c) {
+			c.Next()
+			return
+		}
+
+		var policy *config.CORSConfig
+		if opts.Policy != nil {
+			policy = opts.Policy(c)
+		}
+		setCORSHeaders(c, policy)
 
 		// Handle preflight OPTIONS requests
-		if c.Request.Method == "OPTIONS" {
+		if c.Request.Method == "OPTIONS" && (opts.IsHandled == nil || !opts.IsHandled(c)) {
 			c.AbortWithStatus(204)
 			return
 		}
 
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// setCORSHeaders sets the default CORS headers, replaced by any set in policy
+func setCORSHeaders(c *gin.Context, policy *config.CORSConfig) {
+	origin, headers, methods := "*", "*", "GET, POST, PUT, DELETE, OPTIONS, PATCH"
+	if policy != nil {
+		origin = valueOr(policy.AllowOrigin, origin)
+		headers = valueOr(policy.AllowHeaders, headers)
+		methods = valueOr(policy.AllowMethods, methods)
+	}
+
+	c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+	c.Writer.Header().Set("Access-Control-Allow-Headers", headers)
+	c.Writer.Header().Set("Access-Control-Allow-Methods", methods)
+	if policy == nil {
+		return
+	}
+
+	// A specific origin means the response differs by Origin
+	if origin != "*" {
+		c.Writer.Header().Add("Vary", "Origin")
+	}
+	if policy.ExposeHeaders != "" {
+		c.Writer.Header().Set("Access-Control-Expose-Headers", policy.ExposeHeaders)
+	}
+	if policy.AllowCredentials {
+		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// valueOr returns value, or fallback if value is empty
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
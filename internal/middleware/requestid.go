@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the ID that correlates a request across the
+// response, the logs, the audit log and the proxy target
+const RequestIDHeader = "X-Request-Id"
+
+// requestIDKey is the gin context key the request ID is stored under
+const requestIDKey = "requestId"
+
+// RequestIDMiddleware returns a middleware that gives every request an ID:
+// the one the client sent in X-Request-Id, or a new one. The ID is set on the
+// request, so the proxy forwards it, and on the response.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" {
+			id = newRequestID()
+			c.Request.Header.Set(RequestIDHeader, id)
+		}
+
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// RequestID returns the ID RequestIDMiddleware gave the request, or "" if
+// the middleware didn't run
+func RequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// newRequestID returns a random 128-bit ID in hex
+func newRequestID() string {
+	b := make([]byte, 16)
+	// crypto/rand only fails if the system's source of randomness does
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
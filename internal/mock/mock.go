@@ -3,55 +3,149 @@ package mock
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
 	"swoozeki/climock/internal/config"
 	"swoozeki/climock/internal/logger"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
 )
 
 // Manager handles mock endpoints and response generation
 type Manager struct {
 	Config *config.Config
+
+	// counters backs the counter template function, keyed by counter name
+	counters   map[string]int
+	countersMu sync.Mutex
+
+	// profile is the active response profile, or empty for none
+	profile   string
+	profileMu sync.RWMutex
 }
 
 // New creates a new mock manager
 func New(cfg *config.Config) *Manager {
 	return &Manager{
-		Config: cfg,
+		Config:   cfg,
+		counters: make(map[string]int),
 	}
 }
 
 // FindEndpoint finds an endpoint matching the given method and path
 func (m *Manager) FindEndpoint(method, path string) (*config.Endpoint, string, error) {
-	for feature, featureConfig := range m.Config.Mocks {
-		for i := range featureConfig.Endpoints {
-			endpoint := &featureConfig.Endpoints[i]
-			if endpoint.Method == method && m.pathMatches(endpoint.Path, path) {
-				return endpoint, feature, nil
-			}
+	endpoint, feature, ok := m.Config.FindEndpoint(func(_ string, endpoint config.Endpoint) bool {
+		if endpoint.Method != method {
+			return false
 		}
+		matched, _ := m.MatchPath(endpoint.Path, path)
+		return matched
+	})
+	if !ok {
+		return nil, "", fmt.Errorf("no matching endpoint found for %s %s", method, path)
 	}
-	return nil, "", fmt.Errorf("no matching endpoint found for %s %s", method, path)
+	return endpoint, feature, nil
 }
 
-// pathMatches checks if a request path matches an endpoint path pattern
-func (m *Manager) pathMatches(pattern, path string) bool {
-	patternParts := strings.Split(pattern, "/")
-	pathParts := strings.Split(path, "/")
+// Candidates describes every endpoint whose path matches the request path,
+// whatever its method or active state, as "feature/id (METHOD, active)".
+// It is used to explain routing decisions when tracing requests.
+func (m *Manager) Candidates(path string) []string {
+	var candidates []string
+	m.Config.FindEndpoint(func(feature string, endpoint config.Endpoint) bool {
+		if matched, _ := m.MatchPath(endpoint.Path, path); matched {
+			state := "inactive"
+			if endpoint.Active {
+				state = "active"
+			}
+			candidates = append(candidates, fmt.Sprintf("%s/%s (%s, %s)", feature, endpoint.ID, endpoint.Method, state))
+		}
+		// Never stop early, so every endpoint is considered
+		return false
+	})
+	sort.Strings(candidates)
+	return candidates
+}
 
-	if len(patternParts) != len(pathParts) {
+// FindConflicts returns the endpoints, as "feature/id", that have the same
+// method and an equivalent path to endpoint, which would make the route
+// ambiguous. Paths are equivalent when their static segments match and their
+// parameters are in the same places, whatever they're named. The endpoint
+// itself, identified by feature and ID, is not a conflict.
+func (m *Manager) FindConflicts(feature string, endpoint config.Endpoint) []string {
+	var conflicts []string
+	m.Config.FindEndpoint(func(otherFeature string, other config.Endpoint) bool {
+		if otherFeature == feature && other.ID == endpoint.ID {
+			return false
+		}
+		if strings.EqualFold(other.Method, endpoint.Method) && m.pathsEquivalent(other.Path, endpoint.Path) {
+			conflicts = append(conflicts, otherFeature+"/"+other.ID)
+		}
+		// Never stop early, so every endpoint is considered
 		return false
+	})
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+// EndpointsByTag returns the endpoints, as "feature/id", tagged with tag,
+// in sorted order
+func (m *Manager) EndpointsByTag(tag string) []string {
+	var tagged []string
+	m.Config.FindEndpoint(func(feature string, endpoint config.Endpoint) bool {
+		if endpoint.HasTag(tag) {
+			tagged = append(tagged, feature+"/"+endpoint.ID)
+		}
+		// Never stop early, so every endpoint is considered
+		return false
+	})
+	sort.Strings(tagged)
+	return tagged
+}
+
+// TagNames returns the tags used by any endpoint, in sorted order
+func (m *Manager) TagNames() []string {
+	seen := make(map[string]bool)
+	m.Config.FindEndpoint(func(_ string, endpoint config.Endpoint) bool {
+		for _, tag := range endpoint.Tags {
+			seen[tag] = true
+		}
+		return false
+	})
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
 
-	for i := range patternParts {
-		if strings.HasPrefix(patternParts[i], ":") {
-			// This is a parameter, so it matches anything
-			continue
+// pathsEquivalent reports whether two endpoint path patterns match exactly
+// the same request paths
+func (m *Manager) pathsEquivalent(a, b string) bool {
+	aParts := strings.Split(m.normalizePath(a), "/")
+	bParts := strings.Split(m.normalizePath(b), "/")
+	if len(aParts) != len(bParts) {
+		return false
+	}
+
+	for i := range aParts {
+		aParam := strings.HasPrefix(aParts[i], ":")
+		bParam := strings.HasPrefix(bParts[i], ":")
+		if aParam != bParam {
+			return false
 		}
-		if patternParts[i] != pathParts[i] {
+		if !aParam && !m.segmentEqual(aParts[i], bParts[i]) {
 			return false
 		}
 	}
@@ -59,25 +153,71 @@ func (m *Manager) pathMatches(pattern, path string) bool {
 	return true
 }
 
-// ExtractParams extracts path parameters from a request path
-func (m *Manager) ExtractParams(pattern, path string) map[string]string {
-	params := make(map[string]string)
+// MatchPath reports whether a request path matches an endpoint path
+// pattern, and if it does, returns the values of the pattern's :name
+// parameters. Matching follows the ignoreTrailingSlash and
+// caseInsensitivePaths settings; parameter values keep their original case.
+func (m *Manager) MatchPath(pattern, path string) (bool, map[string]string) {
+	patternParts := strings.Split(m.normalizePath(pattern), "/")
+	pathParts := strings.Split(m.normalizePath(path), "/")
 
-	patternParts := strings.Split(pattern, "/")
-	pathParts := strings.Split(path, "/")
+	if len(patternParts) != len(pathParts) {
+		return false, nil
+	}
 
+	params := make(map[string]string)
 	for i := range patternParts {
 		if strings.HasPrefix(patternParts[i], ":") {
-			paramName := patternParts[i][1:] // Remove the : prefix
-			params[paramName] = pathParts[i]
+			// This is a parameter, so it matches anything
+			params[patternParts[i][1:]] = pathParts[i]
+			continue
+		}
+		if !m.segmentEqual(patternParts[i], pathParts[i]) {
+			return false, nil
+		}
+	}
+
+	return true, params
+}
+
+// normalizePath applies the configured path normalization before matching.
+// Case folding is handled per segment by segmentEqual so that parameter
+// names and values keep their original case.
+func (m *Manager) normalizePath(path string) string {
+	if m.Config.Global.IgnoreTrailingSlash && len(path) > 1 {
+		path = strings.TrimRight(path, "/")
+		if path == "" {
+			path = "/"
 		}
 	}
+	return path
+}
+
+// segmentEqual compares a static pattern segment with a request path segment
+func (m *Manager) segmentEqual(patternPart, pathPart string) bool {
+	if m.Config.Global.CaseInsensitivePaths {
+		return strings.EqualFold(patternPart, pathPart)
+	}
+	return patternPart == pathPart
+}
 
+// ExtractParams extracts path parameters from a request path. It returns
+// nil if the path doesn't match the pattern; see MatchPath.
+func (m *Manager) ExtractParams(pattern, path string) map[string]string {
+	_, params := m.MatchPath(pattern, path)
 	return params
 }
 
-// GenerateResponse generates a response for the given endpoint and parameters
-func (m *Manager) GenerateResponse(endpoint *config.Endpoint, params map[string]string) (*config.Response, error) {
+// GenerateResponse generates a response for the given endpoint, request path
+// and parameters. body is the request's JSON body, available to templates
+// as .body; nil is the same as an empty object. requestID is the request's
+// correlation ID, available as .requestId.
+func (m *Manager) GenerateResponse(endpoint *config.Endpoint, path string, params map[string]string, body map[string]interface{}, requestID string) (*config.Response, error) {
+	if len(endpoint.Responses) == 0 {
+		logger.Error("Endpoint %s has no responses configured", endpoint.ID)
+		return nil, fmt.Errorf("endpoint %s has no responses configured", endpoint.ID)
+	}
+
 	responseName := endpoint.DefaultResponse
 	response, ok := endpoint.Responses[responseName]
 	if !ok {
@@ -87,107 +227,718 @@ func (m *Manager) GenerateResponse(endpoint *config.Endpoint, params map[string]
 
 	// Process template variables in the response body
 	processedResponse := response
-	if err := m.processResponseBody(&processedResponse, params); err != nil {
-		logger.Error("Failed to process response body: %v", err)
-		return nil, err
+	if err := m.processResponseBody(&processedResponse, path, params, body, requestID); err != nil {
+		if !m.Config.Global.LenientTemplates {
+			logger.Error("Failed to process response body: %v", err)
+			return nil, err
+		}
+		logger.Warn("Serving the raw body of response %s for endpoint %s: %v", responseName, endpoint.ID, err)
+		processedResponse = response
 	}
+	processedResponse.Headers = withDefaultHeaders(response.Headers, m.Config.Global.DefaultHeaders)
 
 	return &processedResponse, nil
 }
 
+// withDefaultHeaders returns headers with each of defaults added unless
+// headers already has it, ignoring case as HTTP does. headers itself belongs
+// to the configuration and is left alone.
+func withDefaultHeaders(headers, defaults map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return headers
+	}
+
+	merged := make(map[string]string, len(headers)+len(defaults))
+	set := make(map[string]bool, len(headers))
+	for name, value := range headers {
+		merged[name] = value
+		set[strings.ToLower(name)] = true
+	}
+	for name, value := range defaults {
+		if !set[strings.ToLower(name)] {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
 // processResponseBody processes template variables in the response body
-func (m *Manager) processResponseBody(response *config.Response, params map[string]string) error {
+func (m *Manager) processResponseBody(response *config.Response, path string, params map[string]string, body map[string]interface{}, requestID string) error {
 	// Skip processing if body is nil
 	if response.Body == nil {
 		return nil
 	}
 
-	// Convert body to JSON string
+	if body == nil {
+		body = map[string]interface{}{}
+	}
+
+	// Create template data
+	data := map[string]interface{}{
+		"params":    params,
+		"now":       time.Now().Format(time.RFC3339),
+		"body":      body,
+		"requestId": requestID,
+	}
+
+	return m.renderBody(response, path, data)
+}
+
+// ErrorResponse returns the configured errorResponse for an internal failure
+// such as a response that can't be generated. Its body is a template in
+// which {{.error}} is the failure message. ok is false when there is no
+// errorResponse, or it can't be rendered either.
+func (m *Manager) ErrorResponse(path, message string) (response *config.Response, ok bool) {
+	configured := m.Config.Global.ErrorResponse
+	if configured == nil {
+		return nil, false
+	}
+
+	// Only the templated body can carry the message. Dropping a binary body
+	// also means a bad one can't fail the error response in turn.
+	errorResponse := *configured
+	errorResponse.Stream, errorResponse.SSE = nil, nil
+	errorResponse.BodyBase64 = ""
+	if errorResponse.Status == 0 {
+		errorResponse.Status = http.StatusInternalServerError
+	}
+
+	data := map[string]interface{}{
+		"error": message,
+		"now":   time.Now().Format(time.RFC3339),
+	}
+	if err := m.renderBody(&errorResponse, path, data); err != nil {
+		logger.Error("Failed to render errorResponse: %v", err)
+		return nil, false
+	}
+	return &errorResponse, true
+}
+
+// renderBody renders a response body as a template with the given data
+func (m *Manager) renderBody(response *config.Response, path string, data map[string]interface{}) error {
+	if response.Body == nil {
+		return nil
+	}
+
+	// Render string bodies as written, without a JSON round trip, so quotes in
+	// template actions and substituted values are left intact
+	if bodyStr, ok := response.Body.(string); ok {
+		rendered, err := m.renderTemplate(bodyStr, path, data)
+		if err != nil {
+			return err
+		}
+		rendered = strings.ReplaceAll(rendered, rawValueMarker, "")
+		// A body that looks like JSON is only served as JSON if it still parses
+		// after rendering, so point out templates (such as range loops) that
+		// broke it rather than silently sending it as a string
+		if trimmed := strings.TrimSpace(rendered); (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && !json.Valid([]byte(trimmed)) {
+			logger.Warn("Rendered response body is not valid JSON: %s", trimmed)
+		}
+		response.Body = rendered
+		return nil
+	}
+
+	// A JSON round trip copies the body, which is shared with the config, into
+	// the types it is served as
 	bodyJSON, err := json.Marshal(response.Body)
 	if err != nil {
 		return fmt.Errorf("failed to marshal response body: %w", err)
 	}
+	var body interface{}
+	if err := json.Unmarshal(bodyJSON, &body); err != nil {
+		return fmt.Errorf("failed to copy response body: %w", err)
+	}
 
-	// Create template data
-	data := map[string]interface{}{
-		"params": params,
-		"now":    time.Now().Format(time.RFC3339),
+	// Render each string on its own rather than the encoded JSON, so values
+	// with quotes or backslashes, like request parameters, can't break it
+	rendered, err := m.renderValue(body, path, data)
+	if err != nil {
+		return err
 	}
 
-	// Process template
-	tmpl, err := template.New("body").Parse(string(bodyJSON))
+	response.Body = rendered
+	return nil
+}
+
+// renderValue renders the strings, object keys included, in a decoded JSON
+// value. Keys are visited in sorted order, each before its value, so
+// counters advance in the same order on every request.
+func (m *Manager) renderValue(value interface{}, path string, data map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		rendered, err := m.renderTemplate(v, path, data)
+		if err != nil {
+			return nil, err
+		}
+		return rawValue(rendered), nil
+
+	case []interface{}:
+		for i, item := range v {
+			rendered, err := m.renderValue(item, path, data)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = rendered
+		}
+		return v, nil
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		rendered := make(map[string]interface{}, len(v))
+		for _, key := range keys {
+			// A key is always a string, even if int or bool filled it
+			renderedKey := key
+			if strings.Contains(key, "{{") {
+				var err error
+				if renderedKey, err = m.renderTemplate(key, path, data); err != nil {
+					return nil, err
+				}
+				renderedKey = strings.ReplaceAll(renderedKey, rawValueMarker, "")
+			}
+			renderedItem, err := m.renderValue(v[key], path, data)
+			if err != nil {
+				return nil, err
+			}
+			rendered[renderedKey] = renderedItem
+		}
+		return rendered, nil
+	}
+
+	return value, nil
+}
+
+// renderTemplate executes text as a template with the given data. path is
+// the request path that pathSegment reads from.
+func (m *Manager) renderTemplate(text, path string, data map[string]interface{}) (string, error) {
+	segments := pathSegments(path)
+	tmpl, err := template.New("body").Funcs(template.FuncMap{
+		"counter":   m.nextCounter,
+		"seq":       seq,
+		"randomInt": randomInt,
+		"uuid":      newUUID,
+		"int":       rawInt,
+		"bool":      rawBool,
+		"pathSegment": func(i int) string {
+			if i < 0 || i >= len(segments) {
+				return ""
+			}
+			return segments[i]
+		},
+	}).Parse(text)
 	if err != nil {
-		return fmt.Errorf("failed to parse response template: %w", err)
+		return "", fmt.Errorf("failed to parse response template: %w", err)
 	}
 
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
-		return fmt.Errorf("failed to execute response template: %w", err)
+		return "", fmt.Errorf("failed to execute response template: %w", err)
 	}
 
-	// Parse the processed JSON back into the response body
-	var processedBody interface{}
-	if err := json.Unmarshal(buf.Bytes(), &processedBody); err != nil {
-		return fmt.Errorf("failed to unmarshal processed response: %w", err)
+	return buf.String(), nil
+}
+
+// pathSegments splits a request path into its non-empty segments, so
+// "/api/users/42" gives ["api", "users", "42"]
+func pathSegments(path string) []string {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
 	}
+	return segments
+}
 
-	response.Body = processedBody
-	return nil
+// rawValueMarker surrounds values from the int and bool template functions.
+// In a structured body every template is a JSON string, so the markers let
+// rawValue turn a string filled by one of them into a number or boolean.
+const rawValueMarker = "\x00"
+
+// rawValue returns a rendered string as the JSON value it holds if that's
+// nothing but a marked value, and without any markers otherwise
+func rawValue(rendered string) interface{} {
+	inner, marked := strings.CutPrefix(rendered, rawValueMarker)
+	inner, closed := strings.CutSuffix(inner, rawValueMarker)
+	if marked && closed && !strings.Contains(inner, rawValueMarker) {
+		var value interface{}
+		if err := json.Unmarshal([]byte(inner), &value); err == nil {
+			return value
+		}
+	}
+	return strings.ReplaceAll(rendered, rawValueMarker, "")
 }
 
-// ToggleEndpoint toggles an endpoint's active state
-func (m *Manager) ToggleEndpoint(feature, id string) error {
-	endpoint, err := m.Config.GetEndpoint(feature, id)
+// rawInt parses value as an integer for the int template function
+func rawInt(value string) (string, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return "", fmt.Errorf("int: %q is not an integer", value)
+	}
+	return rawValueMarker + strconv.Itoa(n) + rawValueMarker, nil
+}
+
+// rawBool parses value as a boolean for the bool template function
+func rawBool(value string) (string, error) {
+	b, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return "", fmt.Errorf("bool: %q is not a boolean", value)
+	}
+	return rawValueMarker + strconv.FormatBool(b) + rawValueMarker, nil
+}
+
+// seq returns 0 through n-1, so templates can build arrays with range:
+// {{range $i, $_ := seq 3}}{{if $i}},{{end}}{"id": {{$i}}}{{end}}
+func seq(n int) []int {
+	if n < 0 {
+		n = 0
+	}
+	items := make([]int, n)
+	for i := range items {
+		items[i] = i
+	}
+	return items
+}
+
+// randomInt returns a random integer between low and high, inclusive
+func randomInt(low, high int) int {
+	if high <= low {
+		return low
+	}
+	return low + rand.IntN(high-low+1)
+}
+
+// newUUID returns a random version 4 UUID. It doesn't need to be
+// cryptographically secure, it only has to look like an ID.
+func newUUID() string {
+	hi, lo := rand.Uint64(), rand.Uint64()
+	hi = hi&^0xf000 | 0x4000           // version 4
+	lo = lo&^(0xc000<<48) | 0x8000<<48 // RFC 4122 variant
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", hi>>32, hi>>16&0xffff, hi&0xffff, lo>>48, lo&0xffffffffffff)
+}
+
+// ResponseDelay returns how long to wait before sending the response: a
+// sample from its latency profile if it has one, otherwise its fixed Delay,
+// or the global defaultDelay when that's 0
+func (m *Manager) ResponseDelay(response *config.Response) time.Duration {
+	if len(response.LatencyProfile) > 0 {
+		return response.LatencyProfile.Sample(rand.Float64())
+	}
+	switch {
+	case response.Delay < 0:
+		// Explicitly no delay, whatever the default
+		return 0
+	case response.Delay == 0:
+		return time.Duration(m.Config.Global.DefaultDelay) * time.Millisecond
+	}
+	return time.Duration(response.Delay) * time.Millisecond
+}
+
+// ValidateRequestBody checks body against the endpoint's request schema and
+// returns one message per violation. An empty or non-JSON body is itself a
+// violation. The error is only set when the schema can't be compiled.
+func (m *Manager) ValidateRequestBody(endpoint *config.Endpoint, body []byte) ([]string, error) {
+	if endpoint.RequestSchema == nil {
+		return nil, nil
+	}
+
+	schemaJSON, err := json.Marshal(endpoint.RequestSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request schema for endpoint %s: %w", endpoint.ID, err)
+	}
+
+	schema, err := jsonschema.CompileString(endpoint.ID+".schema.json", string(schemaJSON))
 	if err != nil {
-		logger.Error("Failed to get endpoint %s in feature %s: %v", id, feature, err)
+		return nil, fmt.Errorf("invalid request schema for endpoint %s: %w", endpoint.ID, err)
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		return []string{"request body is required"}, nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []string{fmt.Sprintf("request body is not valid JSON: %v", err)}, nil
+	}
+
+	err = schema.Validate(value)
+	if err == nil {
+		return nil, nil
+	}
+
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return []string{err.Error()}, nil
+	}
+
+	// The top-level entry only says that validation failed; its causes carry
+	// the useful detail
+	var violations []string
+	for _, basic := range validationErr.BasicOutput().Errors {
+		if basic.KeywordLocation == "" {
+			continue
+		}
+		location := basic.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		violations = append(violations, fmt.Sprintf("%s: %s", location, basic.Error))
+	}
+	if len(violations) == 0 {
+		violations = []string{validationErr.Message}
+	}
+
+	return violations, nil
+}
+
+// nextCounter increments the named counter and returns its new value, so
+// the first call for a name returns 1
+func (m *Manager) nextCounter(name string) int {
+	m.countersMu.Lock()
+	defer m.countersMu.Unlock()
+
+	m.counters[name]++
+	return m.counters[name]
+}
+
+// ResetCounters sets every template counter back to zero
+func (m *Manager) ResetCounters() {
+	m.countersMu.Lock()
+	defer m.countersMu.Unlock()
+
+	m.counters = make(map[string]int)
+}
+
+// saveOrRevert saves a feature after a change. If the save fails, the
+// feature is put back as it was before the change, given by previous and
+// existed from GetFeature, so the in-memory config doesn't drift from the
+// file. Configs without a directory are only ever in memory and keep the change.
+func (m *Manager) saveOrRevert(feature string, previous config.FeatureConfig, existed bool) error {
+	err := m.Config.SaveFeatureConfig(feature)
+	if err == nil || errors.Is(err, config.ErrNoBaseDir) {
 		return err
 	}
 
-	endpoint.Active = !endpoint.Active
-	if err := m.Config.UpdateEndpoint(feature, *endpoint); err != nil {
+	logger.Error("Failed to save feature %s, reverting the change: %v", feature, err)
+	m.Config.RestoreFeature(feature, previous, existed)
+	return err
+}
+
+// ToggleEndpoint toggles an endpoint's active state
+func (m *Manager) ToggleEndpoint(feature, id string) error {
+	previous, existed := m.Config.GetFeature(feature)
+	endpoint, err := m.Config.ModifyEndpoint(feature, id, func(endpoint *config.Endpoint) error {
+		endpoint.Active = !endpoint.Active
+		return nil
+	})
+	if err != nil {
 		logger.Error("Failed to update endpoint %s in feature %s: %v", id, feature, err)
 		return err
 	}
 	
 	logger.Info("Toggled endpoint %s in feature %s to %v", id, feature, endpoint.Active)
 
-	return m.Config.SaveFeatureConfig(feature)
+	return m.saveOrRevert(feature, previous, existed)
+}
+
+// SetFeatureActive sets the active state of every endpoint in a feature,
+// saving the feature config once after all endpoints are updated
+func (m *Manager) SetFeatureActive(feature string, active bool) error {
+	featureConfig, ok := m.Config.GetFeature(feature)
+	if !ok {
+		logger.Error("Feature %s not found", feature)
+		return fmt.Errorf("feature %s not found", feature)
+	}
+
+	for _, endpoint := range featureConfig.Endpoints {
+		_, err := m.Config.ModifyEndpoint(feature, endpoint.ID, func(endpoint *config.Endpoint) error {
+			endpoint.Active = active
+			return nil
+		})
+		if err != nil {
+			logger.Error("Failed to update endpoint %s in feature %s: %v", endpoint.ID, feature, err)
+			return err
+		}
+	}
+
+	logger.Info("Set all endpoints in feature %s to %v", feature, active)
+
+	return m.saveOrRevert(feature, featureConfig, true)
 }
 
 // SetDefaultResponse sets the default response for an endpoint
 func (m *Manager) SetDefaultResponse(feature, id, response string) error {
-	endpoint, err := m.Config.GetEndpoint(feature, id)
+	previous, existed := m.Config.GetFeature(feature)
+	_, err := m.Config.ModifyEndpoint(feature, id, func(endpoint *config.Endpoint) error {
+		if _, ok := endpoint.Responses[response]; !ok {
+			return fmt.Errorf("response %s not found for endpoint %s", response, id)
+		}
+		endpoint.DefaultResponse = response
+		return nil
+	})
 	if err != nil {
-		logger.Error("Failed to get endpoint %s in feature %s: %v", id, feature, err)
+		logger.Error("Failed to set default response for endpoint %s in feature %s: %v", id, feature, err)
 		return err
 	}
+	
+	logger.Info("Set default response for endpoint %s in feature %s to %s", id, feature, response)
+
+	return m.saveOrRevert(feature, previous, existed)
+}
 
-	if _, ok := endpoint.Responses[response]; !ok {
-		logger.Error("Response %s not found for endpoint %s", response, id)
-		return fmt.Errorf("response %s not found for endpoint %s", response, id)
+// ApplyScenario sets the default response of every endpoint listed in the
+// named scenario. All overrides are validated before any are applied.
+func (m *Manager) ApplyScenario(name string) error {
+	scenario, ok := m.Config.Global.Scenarios[name]
+	if !ok {
+		logger.Error("Scenario %s not found", name)
+		return fmt.Errorf("scenario %s not found", name)
 	}
 
-	endpoint.DefaultResponse = response
-	if err := m.Config.UpdateEndpoint(feature, *endpoint); err != nil {
-		logger.Error("Failed to update endpoint %s in feature %s: %v", id, feature, err)
-		return err
+	// Validate the whole scenario first so it is applied all-or-nothing
+	for feature, overrides := range scenario {
+		for id, response := range overrides {
+			endpoint, err := m.Config.GetEndpoint(feature, id)
+			if err != nil {
+				logger.Error("Scenario %s references unknown endpoint: %v", name, err)
+				return fmt.Errorf("scenario %s: %w", name, err)
+			}
+			if _, ok := endpoint.Responses[response]; !ok {
+				logger.Error("Scenario %s references unknown response %s for endpoint %s", name, response, id)
+				return fmt.Errorf("scenario %s: response %s not found for endpoint %s", name, response, id)
+			}
+		}
+	}
+
+	// Keep every feature the scenario touches as it was, so a failure in any
+	// of them can put them all back. Features are saved in a stable order.
+	features := make([]string, 0, len(scenario))
+	previous := make(map[string]config.FeatureConfig, len(scenario))
+	for feature := range scenario {
+		features = append(features, feature)
+		previous[feature], _ = m.Config.GetFeature(feature)
+	}
+	sort.Strings(features)
+	revert := func(saved []string) {
+		for _, feature := range features {
+			m.Config.RestoreFeature(feature, previous[feature], true)
+		}
+		// Features that were already saved need their old files back too
+		for _, feature := range saved {
+			if err := m.Config.SaveFeatureConfig(feature); err != nil {
+				logger.Error("Failed to restore feature %s: %v", feature, err)
+			}
+		}
+	}
+
+	for _, feature := range features {
+		for id, response := range scenario[feature] {
+			_, err := m.Config.ModifyEndpoint(feature, id, func(endpoint *config.Endpoint) error {
+				endpoint.DefaultResponse = response
+				return nil
+			})
+			if err != nil {
+				logger.Error("Failed to update endpoint %s in feature %s: %v", id, feature, err)
+				revert(nil)
+				return err
+			}
+		}
+	}
+
+	// Save each feature once, after all of its endpoints are updated
+	for i, feature := range features {
+		err := m.Config.SaveFeatureConfig(feature)
+		if errors.Is(err, config.ErrNoBaseDir) {
+			// In-memory configs keep the change, as with saveOrRevert
+			return err
+		}
+		if err != nil {
+			logger.Error("Failed to save feature %s, reverting scenario %s: %v", feature, name, err)
+			revert(features[:i])
+			return fmt.Errorf("failed to save feature config: %w", err)
+		}
 	}
-	
-	logger.Info("Set default response for endpoint %s in feature %s to %s", id, feature, response)
 
-	return m.Config.SaveFeatureConfig(feature)
+	logger.Info("Applied scenario %s", name)
+	return nil
+}
+
+// ScenarioNames returns the configured scenario names in sorted order
+func (m *Manager) ScenarioNames() []string {
+	names := make([]string, 0, len(m.Config.Global.Scenarios))
+	for name := range m.Config.Global.Scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProfileNames returns the response profiles used by any endpoint, in
+// sorted order
+func (m *Manager) ProfileNames() []string {
+	seen := make(map[string]bool)
+	m.Config.FindEndpoint(func(_ string, endpoint config.Endpoint) bool {
+		for _, response := range endpoint.Responses {
+			for _, profile := range response.Profiles {
+				seen[profile] = true
+			}
+		}
+		return false
+	})
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetProfile makes every endpoint serve its response tagged with the named
+// profile, and its default response if it has none. An empty name turns
+// profiles off. Unlike scenarios, nothing is saved, so the defaults are
+// untouched.
+func (m *Manager) SetProfile(name string) error {
+	if name != "" {
+		found := false
+		for _, profile := range m.ProfileNames() {
+			if profile == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			logger.Error("Profile %s not found", name)
+			return fmt.Errorf("profile %s not found", name)
+		}
+	}
+
+	m.profileMu.Lock()
+	m.profile = name
+	m.profileMu.Unlock()
+
+	logger.Info("Set response profile to %q", name)
+	return nil
+}
+
+// Profile returns the active response profile, or an empty string
+func (m *Manager) Profile() string {
+	m.profileMu.RLock()
+	defer m.profileMu.RUnlock()
+	return m.profile
+}
+
+// ResponseFor returns the name of the response the endpoint should serve:
+// the one tagged with the active profile, or its default response. If
+// several responses have the tag, the default wins, then the first by name.
+func (m *Manager) ResponseFor(endpoint *config.Endpoint) string {
+	profile := m.Profile()
+	if profile == "" {
+		return endpoint.DefaultResponse
+	}
+
+	hasProfile := func(response config.Response) bool {
+		for _, p := range response.Profiles {
+			if p == profile {
+				return true
+			}
+		}
+		return false
+	}
+
+	if response, ok := endpoint.Responses[endpoint.DefaultResponse]; ok && hasProfile(response) {
+		return endpoint.DefaultResponse
+	}
+	names := make([]string, 0, len(endpoint.Responses))
+	for name := range endpoint.Responses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if hasProfile(endpoint.Responses[name]) {
+			return name
+		}
+	}
+	return endpoint.DefaultResponse
+}
+
+// allowedMethods lists the HTTP methods that new endpoints may use
+var allowedMethods = map[string]bool{
+	"GET":     true,
+	"POST":    true,
+	"PUT":     true,
+	"DELETE":  true,
+	"PATCH":   true,
+	"OPTIONS": true,
+	"HEAD":    true,
+}
+
+// NewEndpoint validates the given fields and builds a new endpoint with a
+// single default response returning status. The method is upper-cased and
+// a leading slash is added to the path if it is missing.
+func NewEndpoint(id, method, path string, status int) (config.Endpoint, error) {
+	if id == "" || method == "" || path == "" {
+		return config.Endpoint{}, fmt.Errorf("all fields are required")
+	}
+	
+	// Validate ID (alphanumeric and hyphens only)
+	for _, c := range id {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '_') {
+			return config.Endpoint{}, fmt.Errorf("endpoint ID can only contain letters, numbers, hyphens, and underscores")
+		}
+	}
+	
+	// Validate method
+	method = strings.ToUpper(method)
+	if !allowedMethods[method] {
+		return config.Endpoint{}, fmt.Errorf("invalid HTTP method: %s", method)
+	}
+	
+	// Validate path (must start with /)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	
+	// Create a basic endpoint with a default response
+	return config.Endpoint{
+		ID:              id,
+		Method:          method,
+		Path:            path,
+		Active:          true,
+		DefaultResponse: "default",
+		Responses: map[string]config.Response{
+			"default": {
+				Status: status,
+				Headers: map[string]string{
+					"Content-Type": "application/json",
+				},
+				Body: map[string]interface{}{
+					"message": "This is a default response",
+				},
+				Delay: 0,
+			},
+		},
+	}, nil
 }
 
 // CreateEndpoint creates a new endpoint
 func (m *Manager) CreateEndpoint(feature string, endpoint config.Endpoint) error {
 	logger.Info("Creating endpoint %s in feature %s", endpoint.ID, feature)
 	
+	previous, existed := m.Config.GetFeature(feature)
 	if err := m.Config.AddEndpoint(feature, endpoint); err != nil {
 		logger.Error("Failed to add endpoint to config: %v", err)
 		return fmt.Errorf("failed to add endpoint to config: %w", err)
 	}
 
-	if err := m.Config.SaveFeatureConfig(feature); err != nil {
+	if err := m.saveOrRevert(feature, previous, existed); err != nil {
 		logger.Error("Failed to save feature config: %v", err)
 		return fmt.Errorf("failed to save feature config: %w", err)
 	}
@@ -205,7 +956,7 @@ func (m *Manager) CreateFeature(feature config.FeatureConfig) error {
 		return fmt.Errorf("failed to add feature to config: %w", err)
 	}
 
-	if err := m.Config.SaveFeatureConfig(feature.Feature); err != nil {
+	if err := m.saveOrRevert(feature.Feature, config.FeatureConfig{}, false); err != nil {
 		logger.Error("Failed to save feature config: %v", err)
 		return fmt.Errorf("failed to save feature config: %w", err)
 	}
@@ -218,12 +969,13 @@ func (m *Manager) CreateFeature(feature config.FeatureConfig) error {
 func (m *Manager) DeleteEndpoint(feature, id string) error {
 	logger.Info("Deleting endpoint %s from feature %s", id, feature)
 	
+	previous, existed := m.Config.GetFeature(feature)
 	if err := m.Config.DeleteEndpoint(feature, id); err != nil {
 		logger.Error("Failed to delete endpoint %s from feature %s: %v", id, feature, err)
 		return err
 	}
 
-	if err := m.Config.SaveFeatureConfig(feature); err != nil {
+	if err := m.saveOrRevert(feature, previous, existed); err != nil {
 		logger.Error("Failed to save feature config after deleting endpoint: %v", err)
 		return err
 	}
@@ -1,7 +1,17 @@
 package mock_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"swoozeki/climock/internal/config"
 	"swoozeki/climock/internal/logger"
@@ -187,7 +197,6 @@ func TestFindEndpoint(t *testing.T) {
 }
 
 // TestPathMatching tests path matching through FindEndpoint
-// We can't test pathMatches directly as it's unexported
 func TestPathMatching(t *testing.T) {
 	cfg := createTestConfig()
 	manager := mock.New(cfg)
@@ -305,6 +314,158 @@ func TestExtractParams(t *testing.T) {
 	}
 }
 
+// TestMatchPath tests matching a path and extracting its parameters in one pass
+func TestMatchPath(t *testing.T) {
+	cfg := createTestConfig()
+	manager := mock.New(cfg)
+
+	tests := []struct {
+		name        string
+		pattern     string
+		path        string
+		shouldMatch bool
+		expected    map[string]string
+	}{
+		{
+			name:        "Static path",
+			pattern:     "/api/simple",
+			path:        "/api/simple",
+			shouldMatch: true,
+			expected:    map[string]string{},
+		},
+		{
+			name:        "Parameters",
+			pattern:     "/api/:version/users/:id",
+			path:        "/api/v1/users/123",
+			shouldMatch: true,
+			expected:    map[string]string{"version": "v1", "id": "123"},
+		},
+		{
+			name:        "Parameter matches any segment",
+			pattern:     "/api/users/:id",
+			path:        "/api/users/a.b-c%20d",
+			shouldMatch: true,
+			expected:    map[string]string{"id": "a.b-c%20d"},
+		},
+		{
+			name:        "Parameter matches an empty segment",
+			pattern:     "/api/users/:id",
+			path:        "/api/users/",
+			shouldMatch: true,
+			expected:    map[string]string{"id": ""},
+		},
+		{
+			name:    "Star is a static segment",
+			pattern: "/api/*",
+			path:    "/api/users",
+		},
+		{
+			name:    "Static segment mismatch",
+			pattern: "/api/users/:id",
+			path:    "/api/products/123",
+		},
+		{
+			name:    "Shorter path",
+			pattern: "/api/users/:id",
+			path:    "/api/users",
+		},
+		{
+			name:    "Longer path",
+			pattern: "/api/users/:id",
+			path:    "/api/users/123/details",
+		},
+		{
+			name:    "Case differs",
+			pattern: "/api/simple",
+			path:    "/API/simple",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, params := manager.MatchPath(tt.pattern, tt.path)
+			if matched != tt.shouldMatch {
+				t.Fatalf("Expected match to be %v for %s against %s, got %v", tt.shouldMatch, tt.path, tt.pattern, matched)
+			}
+			if !matched {
+				if params != nil {
+					t.Errorf("Expected no parameters for a mismatch, got %v", params)
+				}
+				return
+			}
+			if len(params) != len(tt.expected) {
+				t.Errorf("Expected parameters %v, got %v", tt.expected, params)
+			}
+			for key, expectedValue := range tt.expected {
+				if value, ok := params[key]; !ok || value != expectedValue {
+					t.Errorf("Expected parameter %q to be %q, got %q", key, expectedValue, value)
+				}
+			}
+		})
+	}
+
+	// The matching options apply to both the match and the parameters
+	cfg.Global.IgnoreTrailingSlash = true
+	cfg.Global.CaseInsensitivePaths = true
+	matched, params := manager.MatchPath("/api/users/:id", "/API/Users/AbC/")
+	if !matched || params["id"] != "AbC" {
+		t.Errorf("Expected a match with id \"AbC\", got %v %v", matched, params)
+	}
+}
+
+// TestTrailingSlashMatching tests the ignoreTrailingSlash option
+func TestTrailingSlashMatching(t *testing.T) {
+	cfg := createTestConfig()
+	manager := mock.New(cfg)
+
+	// Strict matching is the default
+	if _, _, err := manager.FindEndpoint("GET", "/api/simple/"); err == nil {
+		t.Error("Expected no match for trailing slash with strict matching")
+	}
+
+	cfg.Global.IgnoreTrailingSlash = true
+
+	endpoint, _, err := manager.FindEndpoint("GET", "/api/simple/")
+	if err != nil {
+		t.Fatalf("Expected to find endpoint with trailing slash, got error: %v", err)
+	}
+	if endpoint.ID != "simple-endpoint" {
+		t.Errorf("Expected endpoint ID 'simple-endpoint', got %q", endpoint.ID)
+	}
+
+	params := manager.ExtractParams("/api/users/:id", "/api/users/123/")
+	if params["id"] != "123" {
+		t.Errorf("Expected parameter 'id' to be '123', got %q", params["id"])
+	}
+}
+
+// TestCaseInsensitiveMatching tests the caseInsensitivePaths option
+func TestCaseInsensitiveMatching(t *testing.T) {
+	cfg := createTestConfig()
+	manager := mock.New(cfg)
+
+	// Strict matching is the default
+	if _, _, err := manager.FindEndpoint("GET", "/API/Simple"); err == nil {
+		t.Error("Expected no match for different case with strict matching")
+	}
+
+	cfg.Global.CaseInsensitivePaths = true
+
+	endpoint, _, err := manager.FindEndpoint("GET", "/API/Simple")
+	if err != nil {
+		t.Fatalf("Expected to find endpoint with different case, got error: %v", err)
+	}
+	if endpoint.ID != "simple-endpoint" {
+		t.Errorf("Expected endpoint ID 'simple-endpoint', got %q", endpoint.ID)
+	}
+
+	// Parameter values keep their original case
+	params := manager.ExtractParams("/api/users/:id", "/API/Users/AbC")
+	if params["id"] != "AbC" {
+		t.Errorf("Expected parameter 'id' to be 'AbC', got %q", params["id"])
+	}
+}
+
 // TestGenerateResponse tests the GenerateResponse function
 func TestGenerateResponse(t *testing.T) {
 	cfg := createTestConfig()
@@ -318,7 +479,7 @@ func TestGenerateResponse(t *testing.T) {
 
 	// Test with parameters
 	params := map[string]string{"id": "123"}
-	response, err := manager.GenerateResponse(endpoint, params)
+	response, err := manager.GenerateResponse(endpoint, "/api/users/123", params, nil, "")
 	if err != nil {
 		t.Fatalf("Failed to generate response: %v", err)
 	}
@@ -344,7 +505,7 @@ func TestGenerateResponse(t *testing.T) {
 
 	// Test with non-existent response name
 	endpoint.DefaultResponse = "non-existent"
-	_, err = manager.GenerateResponse(endpoint, params)
+	_, err = manager.GenerateResponse(endpoint, "/api/users/123", params, nil, "")
 	if err == nil {
 		t.Error("Expected error for non-existent response, got nil")
 	}
@@ -353,6 +514,7 @@ func TestGenerateResponse(t *testing.T) {
 // TestToggleEndpoint tests the ToggleEndpoint function
 func TestToggleEndpoint(t *testing.T) {
 	cfg := createTestConfig()
+	cfg.BaseDir = t.TempDir()
 	manager := mock.New(cfg)
 
 	// Get initial state
@@ -396,9 +558,51 @@ func TestToggleEndpoint(t *testing.T) {
 	}
 }
 
+// TestSetFeatureActive tests the SetFeatureActive function
+func TestSetFeatureActive(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.BaseDir = t.TempDir()
+	manager := mock.New(cfg)
+
+	// Capture log output to count saves
+	var logs bytes.Buffer
+	logger.Logger = log.New(&logs, "", 0)
+	logger.IsDebugMode = true
+	defer logger.InitTestLogger()
+
+	if err := manager.SetFeatureActive("test", true); err != nil {
+		t.Fatalf("Failed to set feature active: %v", err)
+	}
+	for _, endpoint := range cfg.Mocks["test"].Endpoints {
+		if !endpoint.Active {
+			t.Errorf("Expected endpoint %s to be active", endpoint.ID)
+		}
+	}
+
+	if err := manager.SetFeatureActive("test", false); err != nil {
+		t.Fatalf("Failed to set feature inactive: %v", err)
+	}
+	for _, endpoint := range cfg.Mocks["test"].Endpoints {
+		if endpoint.Active {
+			t.Errorf("Expected endpoint %s to be inactive", endpoint.ID)
+		}
+	}
+
+	// One save per call, not one per endpoint
+	if saves := strings.Count(logs.String(), "Saved feature config"); saves != 2 {
+		t.Errorf("Expected 2 saves, got %d", saves)
+	}
+
+	// Test with non-existent feature
+	if err := manager.SetFeatureActive("non-existent", true); err == nil {
+		t.Error("Expected error for non-existent feature, got nil")
+	}
+}
+
 // TestSetDefaultResponse tests the SetDefaultResponse function
 func TestSetDefaultResponse(t *testing.T) {
 	cfg := createTestConfig()
+	cfg.BaseDir = t.TempDir()
 	manager := mock.New(cfg)
 
 	// Test setting a valid response
@@ -426,9 +630,70 @@ func TestSetDefaultResponse(t *testing.T) {
 	}
 }
 
+// TestApplyScenario tests the ApplyScenario function
+func TestApplyScenario(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.BaseDir = t.TempDir()
+	manager := mock.New(cfg)
+
+	// Give a second endpoint an error response so the scenario spans endpoints
+	endpoint, err := cfg.GetEndpoint("test", "param-endpoint")
+	if err != nil {
+		t.Fatalf("Failed to get endpoint: %v", err)
+	}
+	endpoint.Responses["error"] = config.Response{Status: 500}
+	if err := cfg.UpdateEndpoint("test", *endpoint); err != nil {
+		t.Fatalf("Failed to update endpoint: %v", err)
+	}
+
+	cfg.Global.Scenarios = map[string]config.Scenario{
+		"outage": {
+			"test": {
+				"simple-endpoint": "error",
+				"param-endpoint":  "error",
+			},
+		},
+		"broken": {
+			"test": {
+				"simple-endpoint":   "standard",
+				"inactive-endpoint": "non-existent",
+			},
+		},
+	}
+
+	if err := manager.ApplyScenario("outage"); err != nil {
+		t.Fatalf("Failed to apply scenario: %v", err)
+	}
+
+	for _, id := range []string{"simple-endpoint", "param-endpoint"} {
+		endpoint, err := cfg.GetEndpoint("test", id)
+		if err != nil {
+			t.Fatalf("Failed to get endpoint %s: %v", id, err)
+		}
+		if endpoint.DefaultResponse != "error" {
+			t.Errorf("Expected default response of %s to be 'error', got %q", id, endpoint.DefaultResponse)
+		}
+	}
+
+	// A scenario with an invalid response must not be partially applied
+	if err := manager.ApplyScenario("broken"); err == nil {
+		t.Error("Expected error for scenario with unknown response, got nil")
+	}
+	endpoint, _ = cfg.GetEndpoint("test", "simple-endpoint")
+	if endpoint.DefaultResponse != "error" {
+		t.Errorf("Expected invalid scenario to leave default response unchanged, got %q", endpoint.DefaultResponse)
+	}
+
+	// Test with non-existent scenario
+	if err := manager.ApplyScenario("non-existent"); err == nil {
+		t.Error("Expected error for non-existent scenario, got nil")
+	}
+}
+
 // TestCreateEndpoint tests the CreateEndpoint function
 func TestCreateEndpoint(t *testing.T) {
 	cfg := createTestConfig()
+	cfg.BaseDir = t.TempDir()
 	manager := mock.New(cfg)
 
 	// Create a new endpoint
@@ -477,6 +742,7 @@ func TestCreateEndpoint(t *testing.T) {
 // TestCreateFeature tests the CreateFeature function
 func TestCreateFeature(t *testing.T) {
 	cfg := createTestConfig()
+	cfg.BaseDir = t.TempDir()
 	manager := mock.New(cfg)
 
 	// Create a new feature
@@ -503,6 +769,7 @@ func TestCreateFeature(t *testing.T) {
 // TestDeleteEndpoint tests the DeleteEndpoint function
 func TestDeleteEndpoint(t *testing.T) {
 	cfg := createTestConfig()
+	cfg.BaseDir = t.TempDir()
 	manager := mock.New(cfg)
 
 	// Delete an endpoint
@@ -541,4 +808,689 @@ func TestDeleteFeature(t *testing.T) {
 	if err := manager.DeleteFeature("non-existent"); err == nil {
 		t.Error("Expected error for non-existent feature, got nil")
 	}
-}
\ No newline at end of file
+}
+// TestGenerateResponseStringBody tests that string bodies are rendered as templates
+func TestGenerateResponseStringBody(t *testing.T) {
+	manager := mock.New(config.New(""))
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"plain text", "User {{.params.id}}", "User 42"},
+		{"JSON string", `{"id": "{{index .params "id"}}"}`, `{"id": "42"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := &config.Endpoint{
+				ID:              "string-endpoint",
+				DefaultResponse: "standard",
+				Responses: map[string]config.Response{
+					"standard": {Status: 200, Body: tt.body},
+				},
+			}
+
+			response, err := manager.GenerateResponse(endpoint, "/api/users/42", map[string]string{"id": "42"}, nil, "")
+			if err != nil {
+				t.Fatalf("Failed to generate response: %v", err)
+			}
+			if response.Body != tt.expected {
+				t.Errorf("Expected body %q, got %v", tt.expected, response.Body)
+			}
+		})
+	}
+}
+
+// TestPathSegmentTemplate tests reading request path segments by index
+func TestPathSegmentTemplate(t *testing.T) {
+	manager := mock.New(config.New(""))
+	endpoint := &config.Endpoint{
+		ID:              "segment-endpoint",
+		DefaultResponse: "standard",
+		Responses: map[string]config.Response{
+			"standard": {Status: 200, Body: map[string]interface{}{
+				"id":      "{{pathSegment 2}}",
+				"missing": "{{pathSegment 9}}",
+			}},
+		},
+	}
+
+	response, err := manager.GenerateResponse(endpoint, "/api/orders/1234", nil, nil, "")
+	if err != nil {
+		t.Fatalf("Failed to generate response: %v", err)
+	}
+	body, ok := response.Body.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected body to be a map[string]interface{}, got %T", response.Body)
+	}
+	if body["id"] != "1234" {
+		t.Errorf("Expected the third path segment, got %v", body["id"])
+	}
+	if body["missing"] != "" {
+		t.Errorf("Expected an empty string for an out-of-range segment, got %v", body["missing"])
+	}
+}
+
+// TestSeqTemplate tests building a JSON array with range over seq
+func TestSeqTemplate(t *testing.T) {
+	manager := mock.New(config.New(""))
+	endpoint := &config.Endpoint{
+		ID:              "list-endpoint",
+		DefaultResponse: "standard",
+		Responses: map[string]config.Response{
+			"standard": {Status: 200, Body: `[{{range $i, $_ := seq 3}}{{if $i}},{{end}}{"index": {{$i}}, "id": "{{uuid}}", "score": {{randomInt 1 10}}}{{end}}]`},
+		},
+	}
+
+	response, err := manager.GenerateResponse(endpoint, "/api/items", nil, nil, "")
+	if err != nil {
+		t.Fatalf("Failed to generate response: %v", err)
+	}
+
+	var items []struct {
+		Index int    `json:"index"`
+		ID    string `json:"id"`
+		Score int    `json:"score"`
+	}
+	if err := json.Unmarshal([]byte(response.Body.(string)), &items); err != nil {
+		t.Fatalf("Expected valid JSON, got %v: %v", response.Body, err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got %d", len(items))
+	}
+	for i, item := range items {
+		if item.Index != i {
+			t.Errorf("Expected item %d to have index %d, got %d", i, i, item.Index)
+		}
+		if len(item.ID) != 36 {
+			t.Errorf("Expected a UUID, got %q", item.ID)
+		}
+		if item.Score < 1 || item.Score > 10 {
+			t.Errorf("Expected a score between 1 and 10, got %d", item.Score)
+		}
+	}
+}
+
+// TestLenientTemplates tests that a broken template is served raw when
+// LenientTemplates is set, and is an error otherwise
+func TestLenientTemplates(t *testing.T) {
+	cfg := config.New("")
+	manager := mock.New(cfg)
+	broken := `{"id": "{{.params.id"}`
+	endpoint := &config.Endpoint{
+		ID:              "broken-endpoint",
+		DefaultResponse: "standard",
+		Responses: map[string]config.Response{
+			"standard": {Status: 200, Body: broken},
+		},
+	}
+
+	if _, err := manager.GenerateResponse(endpoint, "/api/users/42", map[string]string{"id": "42"}, nil, ""); err == nil {
+		t.Error("Expected an error for a broken template, got nil")
+	}
+
+	cfg.Global.LenientTemplates = true
+	response, err := manager.GenerateResponse(endpoint, "/api/users/42", map[string]string{"id": "42"}, nil, "")
+	if err != nil {
+		t.Fatalf("Expected the raw body to be served, got error: %v", err)
+	}
+	if response.Body != broken || response.Status != 200 {
+		t.Errorf("Expected status 200 with the raw body %q, got %d %v", broken, response.Status, response.Body)
+	}
+}
+
+// TestTypedParamTemplates tests that int and bool render unquoted values
+func TestTypedParamTemplates(t *testing.T) {
+	manager := mock.New(config.New(""))
+	newEndpoint := func(body interface{}) *config.Endpoint {
+		return &config.Endpoint{
+			ID:              "typed-endpoint",
+			DefaultResponse: "standard",
+			Responses: map[string]config.Response{
+				"standard": {Status: 200, Body: body},
+			},
+		}
+	}
+	params := map[string]string{"id": "123", "admin": "true", "name": "abc"}
+
+	endpoint := newEndpoint(map[string]interface{}{
+		"id":    "{{int .params.id}}",
+		"admin": "{{bool .params.admin}}",
+		"label": "User {{int .params.id}}",
+	})
+	response, err := manager.GenerateResponse(endpoint, "/api/users/123", params, nil, "")
+	if err != nil {
+		t.Fatalf("Failed to generate response: %v", err)
+	}
+	body := response.Body.(map[string]interface{})
+	if id, ok := body["id"].(float64); !ok || id != 123 {
+		t.Errorf("Expected id to be the number 123, got %#v", body["id"])
+	}
+	if admin, ok := body["admin"].(bool); !ok || !admin {
+		t.Errorf("Expected admin to be the boolean true, got %#v", body["admin"])
+	}
+	if body["label"] != "User 123" {
+		t.Errorf("Expected values inside longer strings to stay strings, got %#v", body["label"])
+	}
+
+	// String bodies get the value as written
+	response, err = manager.GenerateResponse(newEndpoint(`{"id": {{int .params.id}}}`), "/api/users/123", params, nil, "")
+	if err != nil {
+		t.Fatalf("Failed to generate response: %v", err)
+	}
+	if response.Body != `{"id": 123}` {
+		t.Errorf("Expected {\"id\": 123}, got %v", response.Body)
+	}
+
+	if _, err := manager.GenerateResponse(newEndpoint(map[string]interface{}{"id": "{{int .params.name}}"}), "/api/users/abc", params, nil, ""); err == nil {
+		t.Error("Expected an error for a non-numeric value, got nil")
+	}
+}
+
+// TestTemplateValueEscaping tests that substituted values containing JSON
+// syntax, like quotes, end up as data in a structured body instead of
+// breaking it
+func TestTemplateValueEscaping(t *testing.T) {
+	manager := mock.New(config.New(""))
+	endpoint := &config.Endpoint{
+		ID:              "escaped-endpoint",
+		Path:            "/api/users/:id",
+		DefaultResponse: "standard",
+		Responses: map[string]config.Response{
+			"standard": {Status: 200, Body: map[string]interface{}{
+				"id":      "{{.params.id}}",
+				"quoted":  `{{index .params "id"}}`,
+				"ids":     []interface{}{"{{.params.id}}", 1},
+				"message": "User {{.params.id}} not found",
+			}},
+		},
+	}
+
+	id := `4"2\`
+	response, err := manager.GenerateResponse(endpoint, "/api/users/42", map[string]string{"id": id}, nil, "")
+	if err != nil {
+		t.Fatalf("Failed to generate response: %v", err)
+	}
+
+	// The body must round trip through JSON with the values intact
+	encoded, err := json.Marshal(response.Body)
+	if err != nil {
+		t.Fatalf("Failed to encode response body: %v", err)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(encoded, &body); err != nil {
+		t.Fatalf("Expected valid JSON, got %s: %v", encoded, err)
+	}
+	if body["id"] != id || body["quoted"] != id {
+		t.Errorf("Expected id and quoted to be %q, got %#v and %#v", id, body["id"], body["quoted"])
+	}
+	if ids, ok := body["ids"].([]interface{}); !ok || len(ids) != 2 || ids[0] != id || ids[1] != float64(1) {
+		t.Errorf("Expected ids to be [%q 1], got %#v", id, body["ids"])
+	}
+	if body["message"] != "User "+id+" not found" {
+		t.Errorf("Expected the value inside the message, got %#v", body["message"])
+	}
+
+	// The endpoint's own body keeps its templates for the next request
+	configured := endpoint.Responses["standard"].Body.(map[string]interface{})
+	if configured["ids"].([]interface{})[0] != "{{.params.id}}" {
+		t.Errorf("Expected the configured body to be left alone, got %#v", configured["ids"])
+	}
+}
+
+// createProfileConfig creates a config whose responses are tagged with the
+// "errors" and "empty" profiles
+func createProfileConfig() *config.Config {
+	cfg := config.New("")
+	cfg.Mocks["shop"] = config.FeatureConfig{
+		Feature: "shop",
+		Endpoints: []config.Endpoint{
+			{ID: "get-users", Method: "GET", Path: "/api/users", Active: true, DefaultResponse: "ok",
+				Responses: map[string]config.Response{
+					"ok":    {Status: 200},
+					"error": {Status: 500, Profiles: []string{"errors"}},
+				}},
+			{ID: "get-orders", Method: "GET", Path: "/api/orders", Active: true, DefaultResponse: "ok",
+				Responses: map[string]config.Response{
+					"ok":      {Status: 200},
+					"empty":   {Status: 200, Profiles: []string{"empty"}},
+					"timeout": {Status: 504, Profiles: []string{"errors"}},
+				}},
+			{ID: "get-stock", Method: "GET", Path: "/api/stock", Active: true, DefaultResponse: "ok",
+				Responses: map[string]config.Response{
+					"ok": {Status: 200},
+				}},
+		},
+	}
+	return cfg
+}
+
+// TestResponseProfiles tests that switching profiles selects each endpoint's
+// tagged response, falling back to its default
+func TestResponseProfiles(t *testing.T) {
+	cfg := createProfileConfig()
+	manager := mock.New(cfg)
+
+	if names := manager.ProfileNames(); strings.Join(names, ",") != "empty,errors" {
+		t.Errorf("Expected profiles [empty errors], got %v", names)
+	}
+
+	selected := func() map[string]string {
+		result := make(map[string]string)
+		for _, id := range []string{"get-users", "get-orders", "get-stock"} {
+			endpoint, err := cfg.GetEndpoint("shop", id)
+			if err != nil {
+				t.Fatalf("Failed to get endpoint: %v", err)
+			}
+			result[id] = manager.ResponseFor(endpoint)
+		}
+		return result
+	}
+
+	tests := []struct {
+		profile  string
+		expected map[string]string
+	}{
+		{"errors", map[string]string{"get-users": "error", "get-orders": "timeout", "get-stock": "ok"}},
+		{"empty", map[string]string{"get-users": "ok", "get-orders": "empty", "get-stock": "ok"}},
+		{"", map[string]string{"get-users": "ok", "get-orders": "ok", "get-stock": "ok"}},
+	}
+	for _, tt := range tests {
+		if err := manager.SetProfile(tt.profile); err != nil {
+			t.Fatalf("Failed to set profile %q: %v", tt.profile, err)
+		}
+		for id, response := range selected() {
+			if response != tt.expected[id] {
+				t.Errorf("Profile %q: expected %s to serve %s, got %s", tt.profile, id, tt.expected[id], response)
+			}
+		}
+	}
+
+	// Profiles don't change the configured defaults
+	endpoint, _ := cfg.GetEndpoint("shop", "get-users")
+	if endpoint.DefaultResponse != "ok" {
+		t.Errorf("Expected the default response to stay ok, got %s", endpoint.DefaultResponse)
+	}
+
+	if err := manager.SetProfile("missing"); err == nil {
+		t.Error("Expected an error for an unknown profile, got nil")
+	}
+	if manager.Profile() != "" {
+		t.Errorf("Expected an unknown profile to leave the profile unchanged, got %q", manager.Profile())
+	}
+}
+
+// TestNewEndpoint tests endpoint field validation and normalization
+func TestNewEndpoint(t *testing.T) {
+	endpoint, err := mock.NewEndpoint("get-user", "get", "api/users/:id", 200)
+	if err != nil {
+		t.Fatalf("Failed to build endpoint: %v", err)
+	}
+	if endpoint.Method != "GET" || endpoint.Path != "/api/users/:id" {
+		t.Errorf("Expected GET /api/users/:id, got %s %s", endpoint.Method, endpoint.Path)
+	}
+	if endpoint.Responses[endpoint.DefaultResponse].Status != 200 {
+		t.Errorf("Expected default response status 200, got %d", endpoint.Responses[endpoint.DefaultResponse].Status)
+	}
+
+	invalid := []struct{ id, method, path string }{
+		{"", "GET", "/api"},
+		{"bad id", "GET", "/api"},
+		{"get-user", "FETCH", "/api"},
+	}
+	for _, tt := range invalid {
+		if _, err := mock.NewEndpoint(tt.id, tt.method, tt.path, 200); err == nil {
+			t.Errorf("Expected error for %q %q %q, got nil", tt.id, tt.method, tt.path)
+		}
+	}
+}
+
+// TestDisabledFeature tests that endpoints of a disabled feature never match
+func TestDisabledFeature(t *testing.T) {
+	cfg := createTestConfig()
+	manager := mock.New(cfg)
+
+	if _, _, err := manager.FindEndpoint("GET", "/api/simple"); err != nil {
+		t.Fatalf("Expected endpoint to match while the feature is enabled: %v", err)
+	}
+
+	enabled := false
+	feature := cfg.Mocks["test"]
+	feature.Enabled = &enabled
+	cfg.Mocks["test"] = feature
+
+	if _, _, err := manager.FindEndpoint("GET", "/api/simple"); err == nil {
+		t.Error("Expected no match for an active endpoint in a disabled feature")
+	}
+}
+
+// TestResponseDelayLatencyProfile tests that sampled delays follow the
+// configured percentiles, and that Delay is used without a profile
+func TestResponseDelayLatencyProfile(t *testing.T) {
+	manager := mock.New(createTestConfig())
+
+	fixed := &config.Response{Delay: 25}
+	if got := manager.ResponseDelay(fixed); got != 25*time.Millisecond {
+		t.Errorf("Expected the fixed delay of 25ms, got %v", got)
+	}
+
+	response := &config.Response{
+		Delay:          1000, // Ignored in favour of the profile
+		LatencyProfile: config.LatencyProfile{"p50": 10, "p90": 100, "p99": 500},
+	}
+	const samples = 20000
+	delays := make([]time.Duration, samples)
+	for i := range delays {
+		delays[i] = manager.ResponseDelay(response)
+	}
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+
+	// The share of delays below each configured point should match its
+	// percentile within two percentage points, well outside sampling noise
+	for percentile, want := range map[int]time.Duration{
+		50: 10 * time.Millisecond,
+		90: 100 * time.Millisecond,
+		99: 500 * time.Millisecond,
+	} {
+		below := sort.Search(samples, func(i int) bool { return delays[i] >= want })
+		share := float64(below) * 100 / samples
+		if share < float64(percentile)-2 || share > float64(percentile)+2 {
+			t.Errorf("Expected %d%% of delays below %v, got %.1f%%", percentile, want, share)
+		}
+	}
+	if slowest := delays[samples-1]; slowest > 500*time.Millisecond {
+		t.Errorf("Expected no delay above the highest point, got %v", slowest)
+	}
+}
+
+// TestResponseDelayDefault tests that defaultDelay applies to responses
+// without a delay, and not to those that opt out with -1
+func TestResponseDelayDefault(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Global.DefaultDelay = 40
+	manager := mock.New(cfg)
+
+	for _, tt := range []struct {
+		delay int
+		want  time.Duration
+	}{
+		{delay: 0, want: 40 * time.Millisecond},
+		{delay: 25, want: 25 * time.Millisecond},
+		{delay: -1, want: 0},
+	} {
+		if got := manager.ResponseDelay(&config.Response{Delay: tt.delay}); got != tt.want {
+			t.Errorf("Expected delay %d to wait %v, got %v", tt.delay, tt.want, got)
+		}
+	}
+}
+
+// TestFindConflicts tests detecting endpoints that answer the same route
+func TestFindConflicts(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["users"] = config.FeatureConfig{
+		Feature: "users",
+		Endpoints: []config.Endpoint{
+			{ID: "get-user", Method: "GET", Path: "/api/users/:userId"},
+			{ID: "delete-user", Method: "DELETE", Path: "/api/users/:userId"},
+		},
+	}
+	manager := mock.New(cfg)
+
+	tests := []struct {
+		name     string
+		feature  string
+		endpoint config.Endpoint
+		want     []string
+	}{
+		{
+			name:     "same method and path in another feature",
+			feature:  "orders",
+			endpoint: config.Endpoint{ID: "simple", Method: "GET", Path: "/api/simple"},
+			want:     []string{"test/simple-endpoint"},
+		},
+		{
+			name:     "parameters with different names",
+			feature:  "orders",
+			endpoint: config.Endpoint{ID: "user", Method: "GET", Path: "/api/users/:id"},
+			want:     []string{"test/param-endpoint", "users/get-user"},
+		},
+		{
+			name:     "different method",
+			feature:  "orders",
+			endpoint: config.Endpoint{ID: "create", Method: "POST", Path: "/api/simple"},
+		},
+		{
+			name:     "parameter against a static segment",
+			feature:  "orders",
+			endpoint: config.Endpoint{ID: "me", Method: "GET", Path: "/api/users/me"},
+		},
+		{
+			name:     "the endpoint itself",
+			feature:  "users",
+			endpoint: config.Endpoint{ID: "delete-user", Method: "DELETE", Path: "/api/users/:id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := manager.FindConflicts(tt.feature, tt.endpoint)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected conflicts %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestEndpointsByTag tests that endpoints are found by tag across features
+func TestEndpointsByTag(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["users"] = config.FeatureConfig{
+		Feature: "users",
+		Endpoints: []config.Endpoint{
+			{ID: "login", Method: "POST", Path: "/api/login", Tags: []string{"auth"}},
+			{ID: "get-user", Method: "GET", Path: "/api/users/:id"},
+		},
+	}
+	cfg.Mocks["billing"] = config.FeatureConfig{
+		Feature: "billing",
+		Endpoints: []config.Endpoint{
+			{ID: "refresh-card", Method: "POST", Path: "/api/cards/refresh", Tags: []string{"billing", "auth"}},
+			{ID: "get-invoices", Method: "GET", Path: "/api/invoices", Tags: []string{"billing"}},
+		},
+	}
+	manager := mock.New(cfg)
+
+	tests := []struct {
+		tag  string
+		want []string
+	}{
+		{"auth", []string{"billing/refresh-card", "users/login"}},
+		{"billing", []string{"billing/get-invoices", "billing/refresh-card"}},
+		{"unknown", nil},
+		// Tags are matched exactly
+		{"Auth", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got := manager.EndpointsByTag(tt.tag)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if names := manager.TagNames(); strings.Join(names, ",") != "auth,billing" {
+		t.Errorf("Expected tags [auth billing], got %v", names)
+	}
+}
+
+// TestConcurrentSaves tests that concurrent toggles and response changes on
+// the same feature are neither lost nor written out of order. Run it with
+// -race to check the locking as well.
+func TestConcurrentSaves(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.New(dir)
+	cfg.Mocks["users"] = config.FeatureConfig{
+		Feature: "users",
+		Endpoints: []config.Endpoint{
+			{ID: "get-users", Method: "GET", Path: "/api/users", DefaultResponse: "ok",
+				Responses: map[string]config.Response{"ok": {Status: 200}, "error": {Status: 500}}},
+			{ID: "get-orders", Method: "GET", Path: "/api/orders", DefaultResponse: "ok",
+				Responses: map[string]config.Response{"ok": {Status: 200}}},
+		},
+	}
+	manager := mock.New(cfg)
+
+	// An even number of toggles per endpoint leaves it where it started
+	const workers, toggles = 8, 10
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < toggles; j++ {
+				if err := manager.ToggleEndpoint("users", "get-orders"); err != nil {
+					t.Errorf("Failed to toggle endpoint: %v", err)
+				}
+				response := "ok"
+				if (i+j)%2 == 0 {
+					response = "error"
+				}
+				if err := manager.SetDefaultResponse("users", "get-users", response); err != nil {
+					t.Errorf("Failed to set default response: %v", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	orders, err := cfg.GetEndpoint("users", "get-orders")
+	if err != nil {
+		t.Fatalf("Failed to get endpoint: %v", err)
+	}
+	if orders.Active {
+		t.Error("Expected an even number of toggles to leave the endpoint inactive")
+	}
+
+	// The file holds exactly what is in memory
+	data, err := os.ReadFile(filepath.Join(dir, "users.json"))
+	if err != nil {
+		t.Fatalf("Failed to read feature file: %v", err)
+	}
+	var saved config.FeatureConfig
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Expected a complete feature file, got %v:\n%s", err, data)
+	}
+	inMemory, _ := cfg.GetFeature("users")
+	for i, endpoint := range saved.Endpoints {
+		if endpoint.Active != inMemory.Endpoints[i].Active || endpoint.DefaultResponse != inMemory.Endpoints[i].DefaultResponse {
+			t.Errorf("Expected saved endpoint %s to match memory, got active=%v default=%s, want active=%v default=%s",
+				endpoint.ID, endpoint.Active, endpoint.DefaultResponse, inMemory.Endpoints[i].Active, inMemory.Endpoints[i].DefaultResponse)
+		}
+	}
+}
+
+// TestFailedSaveReverts tests that changes which can't be saved are undone in
+// memory, so the config keeps matching the files on disk
+func TestFailedSaveReverts(t *testing.T) {
+	cfg := createTestConfig()
+	// A base directory that is a file makes every save fail
+	cfg.BaseDir = filepath.Join(t.TempDir(), "not-a-directory")
+	if err := os.WriteFile(cfg.BaseDir, nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	manager := mock.New(cfg)
+
+	if err := manager.CreateFeature(config.FeatureConfig{Feature: "new-feature"}); err == nil {
+		t.Fatal("Expected creating a feature to fail")
+	}
+	if _, ok := cfg.GetFeature("new-feature"); ok {
+		t.Error("Expected the unsaved feature to be removed")
+	}
+
+	endpoint, err := mock.NewEndpoint("new-endpoint", "GET", "/api/new", 200)
+	if err != nil {
+		t.Fatalf("Failed to build endpoint: %v", err)
+	}
+	if err := manager.CreateEndpoint("test", endpoint); err == nil {
+		t.Fatal("Expected creating an endpoint to fail")
+	}
+	if _, err := cfg.GetEndpoint("test", "new-endpoint"); err == nil {
+		t.Error("Expected the unsaved endpoint to be removed")
+	}
+
+	if err := manager.ToggleEndpoint("test", "simple-endpoint"); err == nil {
+		t.Fatal("Expected toggling an endpoint to fail")
+	}
+	if toggled, _ := cfg.GetEndpoint("test", "simple-endpoint"); toggled == nil || !toggled.Active {
+		t.Error("Expected the unsaved toggle to be undone")
+	}
+}
+
+// TestApplyScenarioSaveFailure tests that a scenario whose save fails for
+// one feature is undone for all of them, in memory and on disk, and that an
+// in-memory config keeps the whole scenario
+func TestApplyScenarioSaveFailure(t *testing.T) {
+	newConfig := func(baseDir string) *config.Config {
+		cfg := config.New(baseDir)
+		for _, feature := range []string{"accounts", "shop/orders"} {
+			cfg.Mocks[feature] = config.FeatureConfig{
+				Feature: feature,
+				Endpoints: []config.Endpoint{
+					{ID: "get", Method: "GET", Path: "/api/" + feature, Active: true, DefaultResponse: "ok",
+						Responses: map[string]config.Response{"ok": {Status: 200}, "error": {Status: 500}}},
+				},
+			}
+		}
+		cfg.Global.Scenarios = map[string]config.Scenario{
+			"outage": {"accounts": {"get": "error"}, "shop/orders": {"get": "error"}},
+		}
+		return cfg
+	}
+	defaults := func(cfg *config.Config) string {
+		var responses []string
+		for _, feature := range []string{"accounts", "shop/orders"} {
+			endpoint, err := cfg.GetEndpoint(feature, "get")
+			if err != nil {
+				t.Fatalf("Failed to get endpoint: %v", err)
+			}
+			responses = append(responses, endpoint.DefaultResponse)
+		}
+		return strings.Join(responses, ",")
+	}
+
+	cfg := newConfig(t.TempDir())
+	if err := cfg.SaveFeatureConfig("accounts"); err != nil {
+		t.Fatalf("Failed to save feature: %v", err)
+	}
+	// A file where the shop directory should be makes the second save fail
+	if err := os.WriteFile(filepath.Join(cfg.BaseDir, "shop"), nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	manager := mock.New(cfg)
+
+	if err := manager.ApplyScenario("outage"); err == nil {
+		t.Fatal("Expected applying the scenario to fail")
+	}
+	if got := defaults(cfg); got != "ok,ok" {
+		t.Errorf("Expected every feature to be reverted, got %s", got)
+	}
+	data, err := os.ReadFile(cfg.FeaturePath("accounts"))
+	if err != nil {
+		t.Fatalf("Failed to read feature file: %v", err)
+	}
+	if !strings.Contains(string(data), `"defaultResponse": "ok"`) {
+		t.Errorf("Expected the saved feature to be restored on disk, got %s", data)
+	}
+
+	// Without a directory the scenario is kept, as other changes are
+	cfg = newConfig("")
+	if err := mock.New(cfg).ApplyScenario("outage"); !errors.Is(err, config.ErrNoBaseDir) {
+		t.Errorf("Expected ErrNoBaseDir, got %v", err)
+	}
+	if got := defaults(cfg); got != "error,error" {
+		t.Errorf("Expected the whole scenario to be applied in memory, got %s", got)
+	}
+}
@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxCacheEntries bounds the number of proxied responses kept in the cache
+const MaxCacheEntries = 100
+
+// maxCachedBody is the largest response body that is cached, matching what
+// the response recorder keeps
+const maxCachedBody = 1024 * 1024
+
+// cachedResponse is a proxied response kept for replay
+type cachedResponse struct {
+	status  int
+	headers http.Header
+	body    []byte
+	expires time.Time
+}
+
+// responseCache is a small in-memory cache of successful proxied GET
+// responses, keyed by method, path and query
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+}
+
+// newResponseCache creates an empty response cache
+func newResponseCache() *responseCache {
+	return &responseCache{entries: make(map[string]cachedResponse)}
+}
+
+// cacheKey identifies a request in the cache
+func cacheKey(req *http.Request) string {
+	return req.Method + " " + req.URL.RequestURI()
+}
+
+// isCacheableRequest reports whether a request may be answered from, or
+// stored in, the cache
+func isCacheableRequest(req *http.Request) bool {
+	return req.Method == http.MethodGet && !hasNoStore(req.Header)
+}
+
+// isCacheableResponse reports whether a proxied response may be stored
+func isCacheableResponse(status int, headers http.Header) bool {
+	return status >= 200 && status < 300 && !hasNoStore(headers)
+}
+
+// hasNoStore reports whether Cache-Control forbids storing the message
+func hasNoStore(headers http.Header) bool {
+	for _, value := range headers.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// get returns the cached response for key if it hasn't expired
+func (c *responseCache) get(key string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return cachedResponse{}, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return cachedResponse{}, false
+	}
+	return entry, true
+}
+
+// put stores a response for ttl. When the cache is full, expired entries are
+// dropped first, then the entry closest to expiring.
+func (c *responseCache) put(key string, entry cachedResponse, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entry.expires = now.Add(ttl)
+
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= MaxCacheEntries {
+		oldestKey := ""
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldestKey == "" || e.expires.Before(c.entries[oldestKey].expires) {
+				oldestKey = k
+			}
+		}
+		if len(c.entries) >= MaxCacheEntries {
+			delete(c.entries, oldestKey)
+		}
+	}
+
+	c.entries[key] = entry
+}
+
+// clear drops every cached response
+func (c *responseCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]cachedResponse)
+}
@@ -2,12 +2,14 @@ package proxy
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"regexp"
+	"strings"
 	"time"
 
 	"swoozeki/climock/internal/config"
@@ -20,24 +22,70 @@ import (
 // Manager handles proxying requests to the real server
 type Manager struct {
 	Config *config.Config
-	proxy  *httputil.ReverseProxy
+	proxy  *httputil.ReverseProxy // nil when no target is configured
+	cache  *responseCache
 }
 
 // New creates a new proxy manager
 func New(cfg *config.Config) (*Manager, error) {
-	targetURL, err := url.Parse(cfg.Global.ProxyConfig.Target)
+	proxy, err := buildProxy(cfg.Global.ProxyConfig.Target, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	proxy := createReverseProxy(targetURL, cfg)
-
 	return &Manager{
 		Config: cfg,
 		proxy:  proxy,
+		cache:  newResponseCache(),
 	}, nil
 }
 
+// buildProxy creates a reverse proxy for target, or returns nil if target is empty
+func buildProxy(target string, cfg *config.Config) (*httputil.ReverseProxy, error) {
+	if strings.TrimSpace(target) == "" {
+		return nil, nil
+	}
+
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+
+	return createReverseProxy(targetURL, cfg), nil
+}
+
+// NormalizeTarget checks a proxy target entered by a user and returns it in
+// the form to store. A bare host such as localhost:8080 gets http:// added;
+// any scheme other than http or https is rejected.
+func NormalizeTarget(target string) (string, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return "", fmt.Errorf("proxy target cannot be empty")
+	}
+
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid proxy target %q: %w", target, err)
+	}
+	if targetURL.Scheme != "http" && targetURL.Scheme != "https" {
+		return "", fmt.Errorf("proxy target must start with http:// or https://")
+	}
+	if targetURL.Host == "" {
+		return "", fmt.Errorf("proxy target %q has no host", target)
+	}
+
+	return target, nil
+}
+
+// HasTarget returns whether a proxy target is configured
+func (m *Manager) HasTarget() bool {
+	return m.proxy != nil
+}
+
 // createReverseProxy creates a configured reverse proxy for the given target URL
 func createReverseProxy(targetURL *url.URL, cfg *config.Config) *httputil.ReverseProxy {
 	proxy := httputil.NewSingleHostReverseProxy(targetURL)
@@ -47,8 +95,12 @@ func createReverseProxy(targetURL *url.URL, cfg *config.Config) *httputil.Revers
 	proxy.Director = func(req *http.Request) {
 		originalDirector(req)
 
-		// Apply path rewriting
-		for pattern, replacement := range cfg.Global.ProxyConfig.PathRewrite {
+		// Apply path rewriting, using the request's own rules if it has them
+		pathRewrite := cfg.Global.ProxyConfig.PathRewrite
+		if rules, ok := req.Context().Value(pathRewriteKey{}).(map[string]string); ok {
+			pathRewrite = rules
+		}
+		for pattern, replacement := range pathRewrite {
 			re, err := regexp.Compile(pattern)
 			if err != nil {
 				continue
@@ -66,11 +118,17 @@ func createReverseProxy(targetURL *url.URL, cfg *config.Config) *httputil.Revers
 	// to prevent duplicate headers when our middleware adds them
 	originalModifyResponse := proxy.ModifyResponse
 	proxy.ModifyResponse = func(resp *http.Response) error {
-		// Remove any existing CORS headers to prevent duplicates
-		for header := range middleware.CORSHeaders {
-			resp.Header.Del(header)
+		// Remove any existing CORS headers to prevent duplicates, unless
+		// they're meant to reach the client
+		if !cfg.Global.KeepsUpstreamCORS() {
+			for header := range middleware.CORSHeaders {
+				resp.Header.Del(header)
+			}
 		}
 		
+		// The request ID middleware has already set the client's copy
+		resp.Header.Del(middleware.RequestIDHeader)
+		
 		// Call the original modifier if it exists
 		if originalModifyResponse != nil {
 			return originalModifyResponse(resp)
@@ -96,8 +154,45 @@ func createReverseProxy(targetURL *url.URL, cfg *config.Config) *httputil.Revers
 	return proxy
 }
 
+// pathRewriteKey is the request context key for rules that replace the
+// global path rewrite for that request
+type pathRewriteKey struct{}
+
+// HandleWithPathRewrite proxies a request like Handle, but rewrites its path
+// with pathRewrite instead of the global rules
+func (m *Manager) HandleWithPathRewrite(c *gin.Context, pathRewrite map[string]string) {
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), pathRewriteKey{}, pathRewrite))
+	m.Handle(c)
+}
+
 // Handle handles a request by proxying it to the real server
 func (m *Manager) Handle(c *gin.Context) {
+	if m.proxy == nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "No proxy target configured"})
+		return
+	}
+
+	// Answer repeat GETs from the cache while they're fresh
+	ttl := time.Duration(m.Config.Global.ProxyConfig.CacheTTL) * time.Second
+	useCache := ttl > 0 && isCacheableRequest(c.Request)
+	if useCache {
+		if cached, ok := m.cache.get(cacheKey(c.Request)); ok {
+			for key, values := range cached.headers {
+				c.Writer.Header()[key] = values
+			}
+			c.Writer.WriteHeader(cached.status)
+			if _, err := c.Writer.Write(cached.body); err != nil {
+				logger.Error("Failed to write cached proxy response: %v", err)
+			}
+			logger.Info("%s %s - proxied (cached) - %d [%s]",
+				c.Request.Method,
+				c.Request.URL.Path,
+				cached.status,
+				middleware.RequestID(c))
+			return
+		}
+	}
+
 	// Create a response recorder to capture the status code and response body
 	responseRecorder := &responseRecorder{
 		ResponseWriter: c.Writer,
@@ -125,11 +220,12 @@ func (m *Manager) Handle(c *gin.Context) {
 		// Only log if a response was actually written
 		if responseRecorder.written {
 			// Log the proxied request with method, path and status
-			logger.Info("%s %s - proxied - %d (%s)",
+			logger.Info("%s %s - proxied - %d (%s) [%s]",
 				c.Request.Method,
 				c.Request.URL.Path,
 				responseRecorder.statusCode,
-				time.Since(start))
+				time.Since(start),
+				middleware.RequestID(c))
 		}
 	}()
 	
@@ -138,12 +234,22 @@ func (m *Manager) Handle(c *gin.Context) {
 	m.proxy.Transport = &headerCopyingTransport{
 		originalTransport: originalTransport,
 		responseRecorder: responseRecorder,
+		keepCORS:         m.Config.Global.KeepsUpstreamCORS(),
 	}
 	
 	m.proxy.ServeHTTP(responseRecorder, c.Request)
 	
 	// Restore original transport
 	m.proxy.Transport = originalTransport
+
+	// Keep successful responses for repeat requests, unless they were too big to record in full
+	if useCache && !responseRecorder.truncated && isCacheableResponse(responseRecorder.statusCode, responseRecorder.headers) {
+		m.cache.put(cacheKey(c.Request), cachedResponse{
+			status:  responseRecorder.statusCode,
+			headers: responseRecorder.headers.Clone(),
+			body:    responseRecorder.body,
+		}, ttl)
+	}
 }
 
 // responseRecorder is a wrapper for http.ResponseWriter that captures the status code and response body
@@ -152,6 +258,7 @@ type responseRecorder struct {
 	statusCode int
 	written    bool
 	body       []byte // Buffer to store the response body
+	truncated  bool   // Whether the body outgrew the buffer
 	headers    http.Header // Store headers separately
 }
 
@@ -189,8 +296,10 @@ func (r *responseRecorder) Write(b []byte) (int, error) {
 	}
 	
 	// Store a copy of the response body (up to a reasonable size limit)
-	if len(r.body) < 1024*1024 { // Limit to 1MB to prevent memory issues
+	if len(r.body)+len(b) <= maxCachedBody { // Limit to 1MB to prevent memory issues
 		r.body = append(r.body, b...)
+	} else {
+		r.truncated = true
 	}
 	
 	return r.ResponseWriter.Write(b)
@@ -231,16 +340,17 @@ func (r *responseRecorder) Push(target string, opts *http.PushOptions) error {
 
 // UpdateTarget updates the proxy target
 func (m *Manager) UpdateTarget(target string) error {
-	targetURL, err := url.Parse(target)
+	// Create a new proxy with the updated target
+	proxy, err := buildProxy(target, m.Config)
 	if err != nil {
 		logger.Error("Failed to parse target URL: %v", err)
 		return err
 	}
 
 	m.Config.Global.ProxyConfig.Target = target
-	
-	// Create a new proxy with the updated target
-	m.proxy = createReverseProxy(targetURL, m.Config)
+	m.proxy = proxy
+	m.cache.clear()
+	m.recordHistory(target)
 	
 	// Save the global config
 	err = m.Config.SaveGlobalConfig()
@@ -255,9 +365,36 @@ func (m *Manager) UpdateTarget(target string) error {
 	return nil
 }
 
-// UpdatePathRewrite updates the path rewrite rules
+// MaxProxyHistory is the number of recent proxy targets kept in the history
+const MaxProxyHistory = 5
+
+// recordHistory moves target to the front of the proxy history, dropping
+// any earlier entry for it and the oldest entries beyond MaxProxyHistory
+func (m *Manager) recordHistory(target string) {
+	if strings.TrimSpace(target) == "" {
+		return
+	}
+
+	history := []string{target}
+	for _, previous := range m.Config.Global.ProxyHistory {
+		if previous != target && len(history) < MaxProxyHistory {
+			history = append(history, previous)
+		}
+	}
+	m.Config.Global.ProxyHistory = history
+}
+
+// UpdatePathRewrite updates the path rewrite rules. Every pattern must be a
+// valid regular expression; if one isn't, nothing is changed.
 func (m *Manager) UpdatePathRewrite(pathRewrite map[string]string) error {
+	for pattern := range pathRewrite {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid path rewrite pattern %q: %w", pattern, err)
+		}
+	}
+
 	m.Config.Global.ProxyConfig.PathRewrite = pathRewrite
+	m.cache.clear()
 	return m.Config.SaveGlobalConfig()
 }
 
@@ -279,15 +416,23 @@ func (m *Manager) IsChangeOrigin() bool {
 // SetChangeOrigin sets whether the proxy changes the origin
 func (m *Manager) SetChangeOrigin(changeOrigin bool) error {
 	m.Config.Global.ProxyConfig.ChangeOrigin = changeOrigin
+	m.cache.clear()
 	return m.Config.SaveGlobalConfig()
 }
 
+// ClearCache drops every cached proxy response
+func (m *Manager) ClearCache() {
+	m.cache.clear()
+}
+
 
 // headerCopyingTransport is a custom http.RoundTripper that ensures all headers
 // from the server response are properly copied to our response
 type headerCopyingTransport struct {
 	originalTransport http.RoundTripper
 	responseRecorder  *responseRecorder
+	// keepCORS passes the target's CORS headers through
+	keepCORS bool
 }
 
 // RoundTrip implements the http.RoundTripper interface
@@ -308,7 +453,11 @@ func (t *headerCopyingTransport) RoundTrip(req *http.Request) (*http.Response, e
 	// Skip CORS headers as they will be set by the middleware.CORSMiddleware
 	for key, values := range resp.Header {
 		// Skip CORS headers
-		if middleware.CORSHeaders[key] {
+		if middleware.CORSHeaders[key] && !t.keepCORS {
+			continue
+		}
+		// The response keeps the request's own ID, not one the target sent
+		if key == middleware.RequestIDHeader {
 			continue
 		}
 		
@@ -1,11 +1,19 @@
 package proxy_test
 
 import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"swoozeki/climock/internal/config"
 	"swoozeki/climock/internal/logger"
 	"swoozeki/climock/internal/proxy"
+
+	"github.com/gin-gonic/gin"
 )
 
 func init() {
@@ -13,9 +21,10 @@ func init() {
 	logger.InitTestLogger()
 }
 
-// createTestConfig creates a test configuration for proxy tests
-func createTestConfig() *config.Config {
-	cfg := config.New(".")
+// createTestConfig creates a test configuration for proxy tests, saved to a
+// temporary directory
+func createTestConfig(t *testing.T) *config.Config {
+	cfg := config.New(t.TempDir())
 
 	// Set up global config with proxy settings
 	cfg.Global = config.GlobalConfig{
@@ -37,7 +46,7 @@ func createTestConfig() *config.Config {
 
 // TestNew tests the New function
 func TestNew(t *testing.T) {
-	cfg := createTestConfig()
+	cfg := createTestConfig(t)
 
 	// Test with valid target URL
 	manager, err := proxy.New(cfg)
@@ -56,9 +65,32 @@ func TestNew(t *testing.T) {
 	}
 }
 
+// TestEmptyTarget tests that no reverse proxy is created without a target
+func TestEmptyTarget(t *testing.T) {
+	cfg := createTestConfig(t)
+	cfg.Global.ProxyConfig.Target = ""
+
+	manager, err := proxy.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create proxy manager: %v", err)
+	}
+	if manager.HasTarget() {
+		t.Error("Expected no proxy target for an empty target URL")
+	}
+
+	cfg.Global.ProxyConfig.Target = "https://api.example.com"
+	manager, err = proxy.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create proxy manager: %v", err)
+	}
+	if !manager.HasTarget() {
+		t.Error("Expected a proxy target to be configured")
+	}
+}
+
 // TestUpdateTarget tests the UpdateTarget function
 func TestUpdateTarget(t *testing.T) {
-	cfg := createTestConfig()
+	cfg := createTestConfig(t)
 	manager, err := proxy.New(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create proxy manager: %v", err)
@@ -81,9 +113,92 @@ func TestUpdateTarget(t *testing.T) {
 	}
 }
 
+// TestNormalizeTarget tests that bare hosts get an http:// scheme and
+// unsupported targets are rejected
+func TestNormalizeTarget(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "localhost:8080", want: "http://localhost:8080"},
+		{input: "  api.example.com/v1 ", want: "http://api.example.com/v1"},
+		{input: "https://example.com", want: "https://example.com"},
+		{input: "http://localhost:9000", want: "http://localhost:9000"},
+		{input: "", wantErr: true},
+		{input: "ftp://example.com", wantErr: true},
+		{input: "http://", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := proxy.NormalizeTarget(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NormalizeTarget(%q): expected an error, got %q", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NormalizeTarget(%q): unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeTarget(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+// TestProxyHistory tests that target changes are remembered, most recent
+// first, without duplicates and up to MaxProxyHistory entries
+func TestProxyHistory(t *testing.T) {
+	cfg := createTestConfig(t)
+	manager, err := proxy.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create proxy manager: %v", err)
+	}
+
+	targets := []string{
+		"http://localhost:8001",
+		"http://localhost:8002",
+		"http://localhost:8001",
+		"http://localhost:8003",
+		"http://localhost:8004",
+		"http://localhost:8005",
+		"http://localhost:8006",
+	}
+	for _, target := range targets {
+		if err := manager.UpdateTarget(target); err != nil {
+			t.Fatalf("Failed to update target to %s: %v", target, err)
+		}
+	}
+
+	want := []string{
+		"http://localhost:8006",
+		"http://localhost:8005",
+		"http://localhost:8004",
+		"http://localhost:8003",
+		"http://localhost:8001",
+	}
+	if len(want) != proxy.MaxProxyHistory {
+		t.Fatalf("Test expects a history of %d, MaxProxyHistory is %d", len(want), proxy.MaxProxyHistory)
+	}
+	if strings.Join(cfg.Global.ProxyHistory, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected history %v, got %v", want, cfg.Global.ProxyHistory)
+	}
+
+	// The history is saved with the global config
+	reloaded := config.New(cfg.BaseDir)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if len(reloaded.Global.ProxyHistory) != proxy.MaxProxyHistory {
+		t.Errorf("Expected saved history of %d targets, got %v", proxy.MaxProxyHistory, reloaded.Global.ProxyHistory)
+	}
+}
+
 // TestUpdatePathRewrite tests the UpdatePathRewrite function
 func TestUpdatePathRewrite(t *testing.T) {
-	cfg := createTestConfig()
+	cfg := createTestConfig(t)
 	manager, err := proxy.New(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create proxy manager: %v", err)
@@ -108,11 +223,19 @@ func TestUpdatePathRewrite(t *testing.T) {
 			t.Errorf("Expected path rewrite rule %q -> %q, got %q", pattern, replacement, pathRewrite[pattern])
 		}
 	}
+
+	// Rules with a pattern that isn't a valid regex are rejected as a whole
+	if err := manager.UpdatePathRewrite(map[string]string{"^/ok": "", "(": ""}); err == nil {
+		t.Error("Expected error for invalid path rewrite pattern, got nil")
+	}
+	if len(manager.GetPathRewrite()) != len(newPathRewrite) {
+		t.Errorf("Expected rules to be unchanged after a rejected update, got %v", manager.GetPathRewrite())
+	}
 }
 
 // TestSetChangeOrigin tests the SetChangeOrigin function
 func TestSetChangeOrigin(t *testing.T) {
-	cfg := createTestConfig()
+	cfg := createTestConfig(t)
 	manager, err := proxy.New(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create proxy manager: %v", err)
@@ -142,4 +265,89 @@ func TestSetChangeOrigin(t *testing.T) {
 	if !manager.IsChangeOrigin() {
 		t.Error("Expected changeOrigin to be true after second update")
 	}
-}
\ No newline at end of file
+}
+// TestProxyCache tests that successful GETs are served from the cache within the TTL
+func TestProxyCache(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/no-store" {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path":%q,"hit":%d}`, r.URL.RequestURI(), hits.Load())
+	}))
+	defer upstream.Close()
+
+	cfg := createTestConfig(t)
+	cfg.Global.ProxyConfig.Target = upstream.URL
+	cfg.Global.ProxyConfig.CacheTTL = 60
+	manager, err := proxy.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create proxy manager: %v", err)
+	}
+
+	router := gin.New()
+	router.Any("/*path", manager.Handle)
+	climock := httptest.NewServer(router)
+	defer climock.Close()
+
+	type result struct {
+		status  int
+		body    string
+		headers http.Header
+	}
+	request := func(method, target string) result {
+		t.Helper()
+		req, err := http.NewRequest(method, climock.URL+target, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return result{status: resp.StatusCode, body: string(body), headers: resp.Header}
+	}
+	expectHits := func(step string, want int32) {
+		t.Helper()
+		if got := hits.Load(); got != want {
+			t.Errorf("%s: expected %d upstream hits, got %d", step, want, got)
+		}
+	}
+
+	first := request("GET", "/data?page=1")
+	second := request("GET", "/data?page=1")
+	expectHits("repeated GET", 1)
+	if second.status != http.StatusOK || second.body != first.body {
+		t.Errorf("Expected the cached response %q, got %d %q", first.body, second.status, second.body)
+	}
+	if second.headers.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected cached headers to be replayed, got %v", second.headers)
+	}
+
+	// The query is part of the key
+	request("GET", "/data?page=2")
+	expectHits("different query", 2)
+
+	// Only GETs are cached, and no-store responses are never kept
+	request("POST", "/data?page=1")
+	request("POST", "/data?page=1")
+	expectHits("POST", 4)
+	request("GET", "/no-store")
+	request("GET", "/no-store")
+	expectHits("no-store", 6)
+
+	// Clearing the cache sends the next request upstream again
+	manager.ClearCache()
+	request("GET", "/data?page=1")
+	expectHits("after ClearCache", 7)
+
+	// Without a TTL nothing is cached
+	cfg.Global.ProxyConfig.CacheTTL = 0
+	request("GET", "/data?page=3")
+	request("GET", "/data?page=3")
+	expectHits("no TTL", 9)
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Audit sources record how a request was answered
+const (
+	auditSourceMocked   = "mocked"
+	auditSourceProxied  = "proxied"
+	auditSourceFallback = "fallback"
+)
+
+// auditEntry is one line of the audit log
+type auditEntry struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"requestId,omitempty"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Source     string    `json:"source"`
+	Feature    string    `json:"feature,omitempty"`
+	Endpoint   string    `json:"endpoint,omitempty"`
+	Response   string    `json:"response,omitempty"`
+	Status     int       `json:"status"`
+	DurationMs float64   `json:"durationMs"`
+}
+
+// auditLog appends audit entries to a JSONL file. The file is opened on the
+// first write and reopened when the configured path changes.
+type auditLog struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// write appends entry to the file at path
+func (a *auditLog) write(path string, entry auditEntry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil || a.path != path {
+		a.closeLocked()
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		a.file, a.path = file, path
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	if _, err := a.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// close closes the audit log file if it's open
+func (a *auditLog) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.closeLocked()
+}
+
+func (a *auditLog) closeLocked() {
+	if a.file != nil {
+		a.file.Close()
+		a.file, a.path = nil, ""
+	}
+}
@@ -1,10 +1,22 @@
 package server
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"swoozeki/climock/internal/config"
@@ -23,21 +35,34 @@ func init() {
 
 // Server represents the mock server
 type Server struct {
-	Config      *config.Config
-	MockManager *mock.Manager
+	Config       *config.Config
+	MockManager  *mock.Manager
 	ProxyManager *proxy.Manager
-	router      *gin.Engine
-	httpServer  *http.Server
-	isRunning   bool
+	// router is rebuilt when the settings it depends on change; see serveHTTP
+	router       atomic.Pointer[routes]
+	httpServer   *http.Server
+	isRunning    bool
+	// listener is the running server's listener. Stop closes it itself in
+	// case Serve hasn't picked it up yet, which would leave the port bound.
+	listener     net.Listener
+	// socketPath is the Unix socket the running server listens on, if any
+	socketPath   string
+	// boundPort is the port picked by the system when the config asks for
+	// port 0, while the server is running
+	boundPort    int
+	// trace logs how each request is routed; see SetTrace
+	trace        atomic.Bool
+	// audit writes the audit log configured in GlobalConfig.AuditLog
+	audit        auditLog
 }
 
 // New creates a new server
 func New(cfg *config.Config, mockManager *mock.Manager, proxyManager *proxy.Manager) *Server {
 	server := &Server{
-		Config:      cfg,
-		MockManager: mockManager,
+		Config:       cfg,
+		MockManager:  mockManager,
 		ProxyManager: proxyManager,
-		isRunning:   false,
+		isRunning:    false,
 	}
 	
 	// Initialize router
@@ -53,18 +78,47 @@ func (s *Server) Start() error {
 		return fmt.Errorf("server is already running")
 	}
 
-	// Create HTTP server
-	addr := fmt.Sprintf("%s:%d", s.Config.Global.ServerConfig.Host, s.Config.Global.ServerConfig.Port)
+	// Listen on a Unix socket when one is configured, otherwise on TCP
+	network, addr := "tcp", s.GetAddress()
+	socketPath := s.Config.Global.ServerConfig.SocketPath
+	if socketPath != "" {
+		network = "unix"
+		// A socket left behind by an unclean exit would make the bind fail
+		if info, err := os.Stat(socketPath); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(socketPath)
+		}
+	}
+
+	// Create HTTP server. Request contexts are cancelled on shutdown so that
+	// long-lived responses, like looping event streams, don't hold up Stop.
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
 	s.httpServer = &http.Server{
-		Addr:    addr,
-		Handler: s.router,
+		Addr:        addr,
+		Handler:     http.HandlerFunc(s.serveHTTP),
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+	s.httpServer.RegisterOnShutdown(cancelRequests)
+
+	// Bind before returning so callers see bind errors and can connect immediately
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		cancelRequests()
+		logger.Error("Error starting server: %v", err)
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	s.listener = listener
+	s.socketPath = socketPath
+	if tcpAddr, ok := listener.Addr().(*net.TCPAddr); ok && network == "tcp" && s.Config.Global.ServerConfig.Port == 0 {
+		s.boundPort = tcpAddr.Port
 	}
 
-	// Start server in a goroutine
+	// Serve in a goroutine. It keeps its own reference to the server, since
+	// a restart replaces s.httpServer before this one's Serve has returned.
+	httpServer := s.httpServer
 	go func() {
-		logger.Info("Server started at %s", addr)
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("Error starting server: %v", err)
+		logger.Info("Server started at %s", listener.Addr())
+		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Error("Error serving: %v", err)
 		}
 	}()
 
@@ -78,7 +132,7 @@ func (s *Server) Stop() error {
 		return fmt.Errorf("server is not running")
 	}
 
-	logger.Info("Stopping server at %s:%d", s.Config.Global.ServerConfig.Host, s.Config.Global.ServerConfig.Port)
+	logger.Info("Stopping server at %s", s.GetAddress())
 	
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -87,6 +141,21 @@ func (s *Server) Stop() error {
 		logger.Error("Error shutting down server: %v", err)
 		return err
 	}
+	// Shutdown only closes listeners Serve is tracking; this one is already
+	// closed unless Stop raced the serving goroutine
+	s.listener.Close()
+	s.listener = nil
+
+	// Closing the listener normally unlinks the socket; make sure it's gone
+	if s.socketPath != "" {
+		if err := os.Remove(s.socketPath); err != nil && !os.IsNotExist(err) {
+			logger.Error("Failed to remove socket %s: %v", s.socketPath, err)
+		}
+		s.socketPath = ""
+	}
+
+	s.audit.close()
+	s.boundPort = 0
 
 	s.isRunning = false
 	logger.Info("Server stopped")
@@ -98,72 +167,499 @@ func (s *Server) IsRunning() bool {
 	return s.isRunning
 }
 
-// GetAddress returns the server address
+// GetAddress returns the server address, or the socket path when listening
+// on a Unix socket
 func (s *Server) GetAddress() string {
-	return fmt.Sprintf("%s:%d", s.Config.Global.ServerConfig.Host, s.Config.Global.ServerConfig.Port)
+	if s.socketPath != "" {
+		return s.socketPath
+	}
+	if socketPath := s.Config.Global.ServerConfig.SocketPath; socketPath != "" {
+		return socketPath
+	}
+	return fmt.Sprintf("%s:%d", s.Config.Global.ServerConfig.Host, s.port())
+}
+
+// port returns the configured port, or the one the system picked if the
+// config asks for port 0
+func (s *Server) port() int {
+	if s.boundPort != 0 {
+		return s.boundPort
+	}
+	return s.Config.Global.ServerConfig.Port
+}
+
+// GetDisplayAddress returns the address clients should connect to. It
+// differs from GetAddress when binding every interface, such as 0.0.0.0,
+// which isn't an address a client can connect to.
+func (s *Server) GetDisplayAddress() string {
+	serverConfig := s.Config.Global.ServerConfig
+	if s.socketPath != "" || serverConfig.SocketPath != "" {
+		return s.GetAddress()
+	}
+
+	host := serverConfig.AdvertiseHost
+	if host == "" {
+		host = serverConfig.Host
+		if host == "" || net.ParseIP(host) != nil && net.ParseIP(host).IsUnspecified() {
+			host = "localhost"
+		}
+	}
+	return net.JoinHostPort(host, strconv.Itoa(s.port()))
+}
+
+// routes is a Gin engine and the settings its routes were built from
+type routes struct {
+	engine   *gin.Engine
+	settings routeSettings
+}
+
+// routeSettings are the config settings that decide which routes and
+// middleware the router has
+type routeSettings struct {
+	healthPath string
+	readyPath  string
+	cors       bool
+}
+
+// routeSettings returns the current route settings from the config
+func (s *Server) routeSettings() routeSettings {
+	return routeSettings{
+		healthPath: s.Config.Global.HealthCheckPath(),
+		readyPath:  s.Config.Global.ReadinessPath(),
+		cors:       s.Config.Global.IsCORSEnabled(),
+	}
 }
 
 // setupRoutes sets up the server routes
-func (s *Server) setupRoutes() {
-	// Create a new router
-	s.router = gin.New()
+func (s *Server) setupRoutes() *routes {
+	settings := s.routeSettings()
+
+	// Create a new router. Unmatched paths, with a trailing slash or not,
+	// belong to the mocks, so don't redirect them to a built-in route.
+	router := gin.New()
+	router.RedirectTrailingSlash = false
 	// Add recovery middleware
-	s.router.Use(gin.Recovery())
-	// Add CORS middleware
-	s.router.Use(middleware.CORSMiddleware())
+	router.Use(gin.Recovery())
+	// Tag every request, preflights and health checks included, with an ID
+	router.Use(middleware.RequestIDMiddleware())
+	// Add CORS middleware, letting OPTIONS mocks answer instead of the preflight
+	// shortcut, endpoints set their own CORS policy and the proxy target
+	// answer for itself when its headers are preserved
+	if settings.cors {
+		router.Use(middleware.CORSMiddleware(middleware.CORSOptions{
+			IsHandled: s.hasActiveMock,
+			Policy:    s.corsPolicy,
+			Skip:      s.preservesUpstreamCORS,
+		}))
+	}
+
+	// Built-in routes are registered explicitly, so Gin matches them before
+	// any mock matching or proxying
+	s.addReservedRoute(router, settings.healthPath, s.healthCheck("ok"))
+	s.addReservedRoute(router, settings.readyPath, s.healthCheck("ready"))
+
+	// Everything else goes to the mocks. NoRoute, unlike a /*path wildcard,
+	// can't conflict with the routes above, whatever their paths.
+	router.NoRoute(s.handleRequest)
+
+	built := &routes{engine: router, settings: settings}
+	s.router.Store(built)
+	return built
+}
+
+// serveHTTP serves a request with the router, first rebuilding it if the
+// config has changed the settings it was built from. Everything else is read
+// from the config on each request, so routes follow the config the same way.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	current := s.router.Load()
+	if current.settings != s.routeSettings() {
+		// Requests racing here may each rebuild; whichever is stored last wins
+		current = s.setupRoutes()
+	}
+	current.engine.ServeHTTP(w, r)
+}
+
+// addReservedRoute registers handler for GET and HEAD requests to path.
+// Gin panics on paths it can't register, so paths that aren't plain, or
+// that are already taken, are logged and left to the mocks instead.
+func (s *Server) addReservedRoute(router *gin.Engine, path string, handler gin.HandlerFunc) {
+	if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, ":*") {
+		logger.Error("Cannot serve the built-in route %q; paths must start with / and have no : or *", path)
+		return
+	}
+	for _, route := range router.Routes() {
+		if route.Path == path {
+			logger.Error("Cannot serve two built-in routes on %s", path)
+			return
+		}
+	}
 
-	// Add a catch-all route to handle all requests
-	s.router.Any("/*path", s.handleRequest)
+	router.GET(path, handler)
+	router.HEAD(path, handler)
 }
 
+// hasActiveMock reports whether an active mock answers the request
+func (s *Server) hasActiveMock(c *gin.Context) bool {
+	endpoint, _, err := s.MockManager.FindEndpoint(c.Request.Method, c.Request.URL.Path)
+	return err == nil && endpoint.Active
+}
+
+// preservesUpstreamCORS reports whether a request is proxied with the
+// target's CORS headers passed through. Preflights are judged by the method
+// they ask about, so the target answers them for the requests it serves.
+func (s *Server) preservesUpstreamCORS(c *gin.Context) bool {
+	if !s.Config.Global.ProxyConfig.PreserveUpstreamCORS || !s.canProxy() {
+		return false
+	}
+
+	method := c.Request.Method
+	if requested := c.GetHeader("Access-Control-Request-Method"); method == http.MethodOptions && requested != "" && !s.hasActiveMock(c) {
+		method = strings.ToUpper(requested)
+	}
+
+	endpoint, _, err := s.MockManager.FindEndpoint(method, c.Request.URL.Path)
+	return err != nil || !endpoint.Active || endpoint.ResponseType == config.ResponseTypeProxy
+}
+
+// corsPolicy returns the CORS policy of the active mock answering the request,
+// if it has one. For a preflight request, that's the mock for the method
+// named in Access-Control-Request-Method.
+func (s *Server) corsPolicy(c *gin.Context) *config.CORSConfig {
+	method := c.Request.Method
+	if requested := c.GetHeader("Access-Control-Request-Method"); method == http.MethodOptions && requested != "" && !s.hasActiveMock(c) {
+		method = strings.ToUpper(requested)
+	}
+
+	endpoint, _, err := s.MockManager.FindEndpoint(method, c.Request.URL.Path)
+	if err != nil || !endpoint.Active {
+		return nil
+	}
+	return endpoint.CORS
+}
+
+// healthCheck returns the handler for a health or readiness route, which
+// answers with a 200 and status. An active user mock on the same method and
+// path takes precedence, so a probe can be made to fail on purpose.
+func (s *Server) healthCheck(status string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.hasActiveMock(c) {
+			s.handleRequest(c)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": status})
+	}
+}
+
+// MethodOverrideHeader is the header used to tunnel the real request method
+// when HonorMethodOverride is enabled
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
 // handleRequest handles an incoming request
 func (s *Server) handleRequest(c *gin.Context) {
 	method := c.Request.Method
 	path := c.Request.URL.Path
 
+	// Let clients that can't send the real method tunnel it through a header
+	if s.Config.Global.HonorMethodOverride {
+		if override := c.GetHeader(MethodOverrideHeader); override != "" {
+			method = strings.ToUpper(override)
+		}
+	}
+
+	// Record how the request was answered once it has been
+	entry := auditEntry{Time: time.Now(), RequestID: middleware.RequestID(c), Method: method, Path: path}
+	defer s.writeAudit(c, &entry)
+
 	// Try to find a matching endpoint
-	endpoint, _, err := s.MockManager.FindEndpoint(method, path)
+	endpoint, feature, err := s.MockManager.FindEndpoint(method, path)
+	if s.IsTracing() {
+		logger.Trace("%s %s: candidates %v", method, path, s.MockManager.Candidates(path))
+	}
 	if err != nil || !endpoint.Active {
+		// In mock-only mode, or without a target, there is nowhere to forward the request
+		if !s.canProxy() {
+			if s.IsTracing() {
+				logger.Trace("%s %s: no active match, serving the fallback response", method, path)
+			}
+			entry.Source = auditSourceFallback
+			s.sendFallbackResponse(c)
+			return
+		}
+
 		// No matching endpoint or endpoint is inactive, proxy the request
-		s.ProxyManager.Handle(c)
+		if s.IsTracing() {
+			logger.Trace("%s %s: no active match, proxying to %s", method, path, s.ProxyManager.GetTargetURL())
+		}
+		entry.Source = auditSourceProxied
+		s.proxyRequest(c, endpoint)
 		return
 	}
 
-	// Handle the mock response
+	if s.IsTracing() {
+		logger.Trace("%s %s: matched %s/%s", method, path, feature, endpoint.ID)
+	}
+
+	// Handle the mock response. The endpoint is a copy, so its default
+	// response afterwards is the one that was served.
 	s.handleMockResponse(c, endpoint, path)
+	entry.Source, entry.Feature, entry.Endpoint = auditSourceMocked, feature, endpoint.ID
+	switch endpoint.ResponseType {
+	case config.ResponseTypeProxy:
+		entry.Source = auditSourceProxied
+		if !s.canProxy() {
+			entry.Source = auditSourceFallback
+		}
+	case config.ResponseTypeEcho:
+		// Echo replies aren't one of the named responses
+	default:
+		entry.Response = endpoint.DefaultResponse
+	}
+}
+
+// proxyRequest forwards a request to the proxy target. endpoint is the
+// matched endpoint, if any, whose own path rewrite rules replace the global
+// ones.
+func (s *Server) proxyRequest(c *gin.Context, endpoint *config.Endpoint) {
+	if endpoint != nil && len(endpoint.ProxyPathRewrite) > 0 {
+		s.ProxyManager.HandleWithPathRewrite(c, endpoint.ProxyPathRewrite)
+		return
+	}
+	s.ProxyManager.Handle(c)
+}
+
+// canProxy reports whether unmatched requests can be forwarded: proxying is
+// enabled and there is a target
+func (s *Server) canProxy() bool {
+	return s.Config.Global.IsProxyEnabled() && s.ProxyManager.HasTarget()
+}
+
+// writeAudit completes entry with the response status and duration and
+// appends it to the audit log, if one is configured
+func (s *Server) writeAudit(c *gin.Context, entry *auditEntry) {
+	path := s.Config.Global.AuditLog
+	if path == "" {
+		return
+	}
+
+	entry.Status = c.Writer.Status()
+	entry.DurationMs = float64(time.Since(entry.Time).Microseconds()) / 1000
+	if err := s.audit.write(path, *entry); err != nil {
+		logger.Error("%v", err)
+	}
 }
 
 // handleMockResponse generates and sends a mock response
 func (s *Server) handleMockResponse(c *gin.Context, endpoint *config.Endpoint, path string) {
+	// Echo endpoints describe the request instead of serving a configured response
+	if endpoint.ResponseType == config.ResponseTypeEcho {
+		s.sendEchoResponse(c)
+		return
+	}
+
+	// Proxy endpoints forward to the upstream like unmatched requests do
+	if endpoint.ResponseType == config.ResponseTypeProxy {
+		if !s.canProxy() {
+			logger.Warn("Endpoint %s proxies its requests, but there is no proxy target; serving the fallback response", endpoint.ID)
+			s.sendFallbackResponse(c)
+			return
+		}
+		if s.IsTracing() {
+			logger.Trace("%s %s: endpoint %s proxies to %s", c.Request.Method, path, endpoint.ID, s.ProxyManager.GetTargetURL())
+		}
+		s.proxyRequest(c, endpoint)
+		return
+	}
+
+	// Reject bodies that don't match the endpoint's schema, like a real API would
+	if endpoint.RequestSchema != nil && !s.validateRequestBody(c, endpoint) {
+		return
+	}
+
+	// Serve the response tagged with the active profile, if there is one
+	endpoint.DefaultResponse = s.MockManager.ResponseFor(endpoint)
+
+	// Let the request pick a response for itself when the override is enabled.
+	// The endpoint is a copy, so this doesn't change the configured default.
+	if header := s.Config.Global.ResponseOverrideHeader; header != "" {
+		if name := c.GetHeader(header); name != "" {
+			if _, ok := endpoint.Responses[name]; ok {
+				endpoint.DefaultResponse = name
+			} else {
+				logger.Warn("Unknown response %q requested via %s for endpoint %s, using default", name, header, endpoint.ID)
+			}
+		}
+	}
+
+	if s.IsTracing() {
+		logger.Trace("%s %s: serving response %q of %s", c.Request.Method, path, endpoint.DefaultResponse, endpoint.ID)
+	}
+
 	// Extract path parameters
 	params := s.MockManager.ExtractParams(endpoint.Path, path)
 
 	// Generate response
-	response, err := s.MockManager.GenerateResponse(endpoint, params)
+	response, err := s.MockManager.GenerateResponse(endpoint, path, params, requestBodyJSON(c), middleware.RequestID(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to generate response: %v", err),
-		})
+		s.sendInternalError(c, fmt.Sprintf("Failed to generate response: %v", err))
 		return
 	}
 
-	// Apply delay if specified
-	if response.Delay > 0 {
-		time.Sleep(time.Duration(response.Delay) * time.Millisecond)
+	// Apply delay if specified, giving up without a response if the client
+	// goes away first
+	if delay := s.MockManager.ResponseDelay(response); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-c.Request.Context().Done():
+			logger.Info("%s %s - canceled during a %s delay [%s]", c.Request.Method, c.Request.URL.Path, delay, middleware.RequestID(c))
+			return
+		}
 	}
 
 	// Send the response
-	s.sendResponse(c, response)
+	s.sendResponse(c, response, endpoint.ETag)
+}
+
+// validateRequestBody checks the request body against the endpoint's schema
+// and writes an error response when it doesn't conform. The body is restored
+// afterwards so later handling can still read it.
+func (s *Server) validateRequestBody(c *gin.Context, endpoint *config.Endpoint) bool {
+	body := readRequestBody(c)
+
+	violations, err := s.MockManager.ValidateRequestBody(endpoint, body)
+	if err != nil {
+		logger.Error("Failed to validate request body: %v", err)
+		s.sendInternalError(c, fmt.Sprintf("Failed to validate request body: %v", err))
+		return false
+	}
+
+	if len(violations) > 0 {
+		logger.Info("Request body for %s %s failed schema validation", c.Request.Method, c.Request.URL.Path)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Request body does not match schema",
+			"details": violations,
+		})
+		return false
+	}
+
+	return true
+}
+
+// readRequestBody reads the request body, putting it back so it can be
+// read again
+func readRequestBody(c *gin.Context) []byte {
+	if c.Request.Body == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		logger.Error("Failed to read request body: %v", err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return body
+}
+
+// requestBodyJSON returns the request body parsed as a JSON object, or an
+// empty map when it's empty or isn't one
+func requestBodyJSON(c *gin.Context) map[string]interface{} {
+	body := map[string]interface{}{}
+	if data := readRequestBody(c); len(data) > 0 {
+		// A JSON null leaves the map nil
+		if err := json.Unmarshal(data, &body); err != nil || body == nil {
+			body = map[string]interface{}{}
+		}
+	}
+	return body
+}
+
+// sendInternalError answers a request whose mock response failed with the
+// configured errorResponse, or a JSON error when there is none
+func (s *Server) sendInternalError(c *gin.Context, message string) {
+	if response, ok := s.MockManager.ErrorResponse(c.Request.URL.Path, message); ok {
+		s.sendResponse(c, response, false)
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// sendFallbackResponse answers a request that matched no active mock and
+// can't be proxied, using the configured fallback response if there is one
+func (s *Server) sendFallbackResponse(c *gin.Context) {
+	if fallback := s.Config.Global.FallbackResponse; fallback != nil {
+		response := *fallback
+		if response.Status == 0 {
+			response.Status = http.StatusNotFound
+		}
+		s.sendResponse(c, &response, false)
+		return
+	}
+
+	s.sendNoMockFound(c)
+}
+
+// sendNoMockFound writes the default 404 for a request no mock answers: a
+// JSON error naming the method and path, served as application/json
+func (s *Server) sendNoMockFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": fmt.Sprintf("No mock found for %s %s", c.Request.Method, c.Request.URL.Path),
+	})
+
+	logger.Info("%s %s - unmatched - %d [%s]",
+		c.Request.Method,
+		c.Request.URL.Path,
+		c.Writer.Status(),
+		middleware.RequestID(c))
+}
+
+// sendEchoResponse sends a JSON description of the incoming request
+func (s *Server) sendEchoResponse(c *gin.Context) {
+	headers := make(map[string]string)
+	for key, values := range c.Request.Header {
+		headers[key] = strings.Join(values, ", ")
+	}
+
+	query := make(map[string]string)
+	for key, values := range c.Request.URL.Query() {
+		query[key] = strings.Join(values, ", ")
+	}
+
+	// Parse the body as JSON when possible, otherwise echo it as a string
+	var body interface{}
+	if data := readRequestBody(c); len(data) > 0 {
+		if err := json.Unmarshal(data, &body); err != nil {
+			body = string(data)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"method":  c.Request.Method,
+		"path":    c.Request.URL.Path,
+		"headers": headers,
+		"query":   query,
+		"body":    body,
+	})
+
+	logger.Info("%s %s - echoed - %d [%s]",
+		c.Request.Method,
+		c.Request.URL.Path,
+		c.Writer.Status(),
+		middleware.RequestID(c))
 }
 
 // setResponseHeaders sets the response headers
 func (s *Server) setResponseHeaders(c *gin.Context, headers map[string]string) {
 	// List of CORS headers that should not be overridden
 	corsHeaders := middleware.CORSHeaders
+	// Without the middleware, mocks are free to set their own
+	skipCORS := s.Config.Global.IsCORSEnabled()
 
 	for key, value := range headers {
 		// Skip CORS headers that are already set by the middleware
-		if corsHeaders[key] {
+		if skipCORS && corsHeaders[key] {
 			continue
 		}
 		c.Header(key, value)
@@ -185,24 +681,125 @@ func (s *Server) writeStringJSONBody(c *gin.Context, bodyStr string) bool {
 }
 
 
+// isRawContentType reports whether a string body with this content type should
+// be written as-is rather than JSON-encoded
+func isRawContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") || mediaType == "application/xml"
+}
+
+// headerValue returns the value of a header from a response header map,
+// matching the name case-insensitively
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
 // sendResponse sends the response to the client
-func (s *Server) sendResponse(c *gin.Context, response *config.Response) {
+func (s *Server) sendResponse(c *gin.Context, response *config.Response, etag bool) {
+	// Decode binary bodies first, so a bad one can still be answered with an error
+	var binaryBody []byte
+	if response.BodyBase64 != "" && response.SSE == nil && response.Stream == nil {
+		decoded, err := base64.StdEncoding.DecodeString(response.BodyBase64)
+		if err != nil {
+			logger.Error("Failed to decode bodyBase64: %v", err)
+			s.sendInternalError(c, fmt.Sprintf("Failed to decode bodyBase64: %v", err))
+			return
+		}
+		binaryBody = decoded
+	}
+	
 	// Set response headers
 	s.setResponseHeaders(c, response.Headers)
 
+	// Answer conditional requests for an unchanged body without sending it
+	if etag && s.writeETag(c, response) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		s.logMockedRequest(c)
+		return
+	}
+
 	// Set response status
 	c.Status(response.Status)
 
-	// Handle string JSON bodies
+	// Send only the headers for statuses that can't have a body, and for
+	// mocks without one, rather than rendering a nil body as JSON null
+	if !bodyAllowedForStatus(response.Status) || hasNoBody(response) {
+		c.Writer.WriteHeaderNow()
+		s.logMockedRequest(c)
+		return
+	}
+
+	// Event streams and streamed bodies are written and flushed piece by piece, uncompressed
+	if response.SSE != nil {
+		s.sendEventStream(c, response)
+		s.logMockedRequest(c)
+		return
+	}
+	if response.Stream != nil {
+		s.sendStreamResponse(c, response)
+		s.logMockedRequest(c)
+		return
+	}
+
+	// Compress the body as it's written, whichever way it's rendered below
+	if s.shouldCompress(c, response) {
+		c.Header("Content-Encoding", "gzip")
+		c.Header("Vary", "Accept-Encoding")
+		c.Writer.Header().Del("Content-Length")
+
+		gz := gzip.NewWriter(c.Writer)
+		defer gz.Close()
+		c.Writer = &gzipWriter{ResponseWriter: c.Writer, gz: gz}
+	}
+
+	// Go's server picks Content-Length or chunked encoding by itself, so
+	// steer it when the response asks for something else
+	switch {
+	case response.ContentLength != nil:
+		c.Header("Content-Length", strconv.Itoa(*response.ContentLength))
+	case response.OmitContentLength:
+		// Identity encoding ends the body by closing the connection
+		c.Header("Transfer-Encoding", "identity")
+	case response.ForceChunked:
+		// Flushing before the handler returns commits to chunked encoding
+		c.Writer.Header().Del("Content-Length")
+		defer c.Writer.Flush()
+	}
+
+	// Write binary bodies raw, bypassing JSON encoding
+	if binaryBody != nil {
+		if headerValue(response.Headers, "Content-Type") == "" {
+			c.Header("Content-Type", "application/octet-stream")
+		}
+		if _, err := c.Writer.Write(binaryBody); err != nil {
+			logger.Error("Failed to write response: %v", err)
+		}
+		s.logMockedRequest(c)
+		return
+	}
+
 	if bodyStr, ok := response.Body.(string); ok {
+		// Write text and XML bodies raw with their declared content type
+		if isRawContentType(headerValue(response.Headers, "Content-Type")) {
+			if _, err := c.Writer.WriteString(bodyStr); err != nil {
+				logger.Error("Failed to write response: %v", err)
+			}
+			s.logMockedRequest(c)
+			return
+		}
+
+		// Handle string JSON bodies
 		if s.writeStringJSONBody(c, bodyStr) {
-			// Log the request
-			start := time.Now()
-			logger.Info("%s %s - mocked - %d (%s)",
-				c.Request.Method,
-				c.Request.URL.Path,
-				c.Writer.Status(),
-				time.Since(start))
+			s.logMockedRequest(c)
 			return
 		}
 	}
@@ -210,26 +807,290 @@ func (s *Server) sendResponse(c *gin.Context, response *config.Response) {
 	// Otherwise, render as JSON
 	c.JSON(response.Status, response.Body)
 
-	// Log the request
+	s.logMockedRequest(c)
+}
+
+// bodyAllowedForStatus reports whether a response with the given status may
+// have a body: not 204 No Content or 304 Not Modified
+func bodyAllowedForStatus(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// hasNoBody reports whether a response has nothing to send after its headers
+func hasNoBody(response *config.Response) bool {
+	return response.Body == nil && response.BodyBase64 == "" && response.SSE == nil && response.Stream == nil
+}
+
+// sendStreamResponse writes a response's body in chunks, flushing each one
+// and pausing between them. It stops early if the client goes away.
+func (s *Server) sendStreamResponse(c *gin.Context, response *config.Response) {
+	chunks, contentType, err := streamChunks(response)
+	if err != nil {
+		logger.Error("Failed to encode streamed response: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to encode streamed response: %v", err),
+		})
+		return
+	}
+
+	if headerValue(response.Headers, "Content-Type") == "" {
+		c.Header("Content-Type", contentType)
+	}
+	// Keep proxies and the browser from holding event streams back
+	if strings.HasPrefix(c.Writer.Header().Get("Content-Type"), "text/event-stream") && headerValue(response.Headers, "Cache-Control") == "" {
+		c.Header("Cache-Control", "no-cache")
+	}
+
+	s.writeChunks(c, chunks, time.Duration(response.Stream.Interval)*time.Millisecond, false)
+}
+
+// sendEventStream writes a response's Server-Sent Events, flushing each one
+// and pausing between them, then closes the connection or, with loop set,
+// starts over until the client goes away
+func (s *Server) sendEventStream(c *gin.Context, response *config.Response) {
+	events := make([]string, 0, len(response.SSE.Events))
+	for _, event := range response.SSE.Events {
+		formatted, err := formatSSEEvent(event)
+		if err != nil {
+			logger.Error("Failed to encode event: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": fmt.Sprintf("Failed to encode event: %v", err),
+			})
+			return
+		}
+		events = append(events, formatted)
+	}
+
+	for name, value := range map[string]string{
+		"Content-Type":      "text/event-stream",
+		"Cache-Control":     "no-cache",
+		"Connection":        "keep-alive",
+		"X-Accel-Buffering": "no",
+	} {
+		if headerValue(response.Headers, name) == "" {
+			c.Header(name, value)
+		}
+	}
+
+	s.writeChunks(c, events, time.Duration(response.SSE.Interval)*time.Millisecond, response.SSE.Loop)
+}
+
+// formatSSEEvent renders an event in the text/event-stream format. Data
+// spanning several lines is sent as one data field per line.
+func formatSSEEvent(event config.SSEEvent) (string, error) {
+	data, ok := event.Data.(string)
+	if !ok {
+		encoded, err := json.Marshal(event.Data)
+		if err != nil {
+			return "", err
+		}
+		data = string(encoded)
+	}
+
+	var b strings.Builder
+	if event.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", event.ID)
+	}
+	if event.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event.Event)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	return b.String(), nil
+}
+
+// writeChunks writes and flushes each chunk with a pause between them,
+// repeating them with loop set. It stops early if the client goes away.
+func (s *Server) writeChunks(c *gin.Context, chunks []string, interval time.Duration, loop bool) {
+	// HEAD gets the headers only; there's no body to pace, and a looping stream would never end
+	if len(chunks) == 0 || c.Request.Method == http.MethodHead {
+		c.Writer.WriteHeaderNow()
+		return
+	}
+
+	for i := 0; loop || i < len(chunks); i++ {
+		if i > 0 && interval > 0 {
+			select {
+			case <-time.After(interval):
+			case <-c.Request.Context().Done():
+				return
+			}
+		} else if c.Request.Context().Err() != nil {
+			return
+		}
+		if _, err := c.Writer.WriteString(chunks[i%len(chunks)]); err != nil {
+			logger.Error("Failed to write streamed chunk: %v", err)
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// streamChunks splits a streamed response into the chunks to send, and picks
+// a content type for when the response doesn't set one
+func streamChunks(response *config.Response) ([]string, string, error) {
+	if len(response.Stream.Chunks) > 0 {
+		return response.Stream.Chunks, "text/plain; charset=utf-8", nil
+	}
+
+	body, ok := response.Body.(string)
+	contentType := "text/plain; charset=utf-8"
+	if !ok {
+		data, err := json.Marshal(response.Body)
+		if err != nil {
+			return nil, "", err
+		}
+		body = string(data)
+		contentType = "application/json"
+	}
+
+	size := response.Stream.ChunkSize
+	if size <= 0 || size >= len(body) {
+		return []string{body}, contentType, nil
+	}
+	var chunks []string
+	for len(body) > size {
+		chunks = append(chunks, body[:size])
+		body = body[size:]
+	}
+	return append(chunks, body), contentType, nil
+}
+
+// writeETag sets the ETag header for a successful response with a body and
+// reports whether the request's If-None-Match already has it. Streams are
+// skipped since their body isn't known up front.
+func (s *Server) writeETag(c *gin.Context, response *config.Response) bool {
+	if response.Status < 200 || response.Status >= 300 || response.SSE != nil || response.Stream != nil {
+		return false
+	}
+
+	body, ok := response.Body.(string)
+	if response.BodyBase64 != "" {
+		body, ok = response.BodyBase64, true
+	}
+	if !ok {
+		data, err := json.Marshal(response.Body)
+		if err != nil {
+			logger.Error("Failed to compute ETag: %v", err)
+			return false
+		}
+		body = string(data)
+	}
+	sum := sha256.Sum256([]byte(body))
+	tag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	// A gzipped body isn't byte-for-byte the same representation
+	if s.shouldCompress(c, response) {
+		tag = "W/" + tag
+	}
+	c.Header("ETag", tag)
+
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+	return etagMatches(c.GetHeader("If-None-Match"), tag)
+}
+
+// etagMatches reports whether an If-None-Match header lists tag, using the
+// weak comparison that If-None-Match calls for
+func etagMatches(ifNoneMatch, tag string) bool {
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldCompress reports whether a mock response should be gzipped: it or the
+// global config asks for it, the client accepts gzip, and there is a body
+// that isn't already encoded
+func (s *Server) shouldCompress(c *gin.Context, response *config.Response) bool {
+	if !response.Compress && !s.Config.Global.CompressResponses {
+		return false
+	}
+	if c.Request.Method == http.MethodHead || response.Status == http.StatusNoContent || response.Status == http.StatusNotModified {
+		return false
+	}
+	if headerValue(response.Headers, "Content-Encoding") != "" {
+		return false
+	}
+	return acceptsGzip(c.GetHeader("Accept-Encoding"))
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		// q=0 explicitly refuses the coding
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if value, err := strconv.ParseFloat(q, 64); err == nil && value == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipWriter compresses everything written through the gin response writer
+type gzipWriter struct {
+	gin.ResponseWriter
+	gz *gzip.Writer
+}
+
+// Write compresses b into the response
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	return w.gz.Write(b)
+}
+
+// WriteString compresses s into the response
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.gz.Write([]byte(s))
+}
+
+// logMockedRequest logs a request that was answered with a mock response
+func (s *Server) logMockedRequest(c *gin.Context) {
 	start := time.Now()
-	logger.Info("%s %s - mocked - %d (%s)",
+	logger.Info("%s %s - mocked - %d (%s) [%s]",
 		c.Request.Method,
 		c.Request.URL.Path,
 		c.Writer.Status(),
-		time.Since(start))
+		time.Since(start),
+		middleware.RequestID(c))
+}
+
+// SetTrace turns request tracing on or off. While it's on, every request logs
+// the endpoints considered for it and what was served: a mock, the proxy or
+// the fallback. Requests are still served normally.
+func (s *Server) SetTrace(on bool) {
+	s.trace.Store(on)
 }
 
-// Reload reloads the server configuration
+// IsTracing returns whether request tracing is on
+func (s *Server) IsTracing() bool {
+	return s.trace.Load()
+}
+
+// Reload reloads the server configuration. The UI also calls it after each
+// edit it saves, so template counters are left alone; reloading on request,
+// as ctrl+r does, resets them separately.
 func (s *Server) Reload() error {
 	// Reload configuration
 	if err := s.Config.Load(); err != nil {
 		return err
 	}
 
-	// Update routes if the server is running
-	if s.isRunning {
-		s.setupRoutes()
-	}
+	// Cached proxy responses may no longer match the configuration. The
+	// routes follow the new settings by themselves, see serveHTTP.
+	s.ProxyManager.ClearCache()
 
 	return nil
 }
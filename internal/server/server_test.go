@@ -1,10 +1,23 @@
 package server_test
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"io"
+	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"swoozeki/climock/internal/config"
 	"swoozeki/climock/internal/logger"
@@ -575,4 +588,2252 @@ func TestDifferentMethods(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", http.StatusNoContent, resp.StatusCode)
 		}
 	})
-}
\ No newline at end of file
+}
+
+// startServer creates and starts a server for the given config, stopping it when the test ends
+func startServer(t *testing.T, cfg *config.Config) *server.Server {
+	t.Helper()
+
+	proxyManager, err := proxy.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create proxy manager: %v", err)
+	}
+
+	srv := server.New(cfg, mock.New(cfg), proxyManager)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	t.Cleanup(func() {
+		// Tests may stop the server themselves
+		if srv.IsRunning() {
+			if err := srv.Stop(); err != nil {
+				t.Logf("Error stopping server: %v", err)
+			}
+		}
+		// Drop keep-alive connections so the next test on this port starts fresh
+		http.DefaultClient.CloseIdleConnections()
+	})
+
+	return srv
+}
+
+// TestEchoEndpoint tests that echo endpoints return a description of the request
+func TestEchoEndpoint(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:           "echo-endpoint",
+				Method:       "POST",
+				Path:         "/api/echo",
+				Active:       true,
+				ResponseType: config.ResponseTypeEcho,
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	req, err := http.NewRequest("POST", "http://"+srv.GetAddress()+"/api/echo?page=2", strings.NewReader(`{"name":"Ada"}`))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("X-Test-Header", "hello")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var body struct {
+		Method  string                 `json:"method"`
+		Path    string                 `json:"path"`
+		Headers map[string]string      `json:"headers"`
+		Query   map[string]string      `json:"query"`
+		Body    map[string]interface{} `json:"body"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+
+	if body.Method != "POST" {
+		t.Errorf("Expected method to be 'POST', got %q", body.Method)
+	}
+	if body.Path != "/api/echo" {
+		t.Errorf("Expected path to be '/api/echo', got %q", body.Path)
+	}
+	if body.Headers["X-Test-Header"] != "hello" {
+		t.Errorf("Expected X-Test-Header to be 'hello', got %q", body.Headers["X-Test-Header"])
+	}
+	if body.Query["page"] != "2" {
+		t.Errorf("Expected query page to be '2', got %q", body.Query["page"])
+	}
+	if body.Body["name"] != "Ada" {
+		t.Errorf("Expected body name to be 'Ada', got %v", body.Body["name"])
+	}
+}
+
+// TestConcurrentToggle tests that toggling an endpoint while the server is
+// serving it does not race. Run with -race to detect data races.
+func TestConcurrentToggle(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.BaseDir = t.TempDir()
+	srv := startServer(t, cfg)
+	mockManager := mock.New(cfg)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if err := mockManager.ToggleEndpoint("test", "active-endpoint"); err != nil {
+				t.Errorf("Failed to toggle endpoint: %v", err)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		resp, err := http.Get("http://" + srv.GetAddress() + "/api/active")
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	wg.Wait()
+}
+
+// TestStringBodyTemplate tests that string bodies are templated before being served
+func TestStringBodyTemplate(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "greeting",
+				Method:          "GET",
+				Path:            "/api/greeting/:name",
+				Active:          true,
+				DefaultResponse: "standard",
+				Responses: map[string]config.Response{
+					"standard": {Status: 200, Body: "Hello {{.params.name}}"},
+				},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	resp, err := http.Get("http://" + srv.GetAddress() + "/api/greeting/Ada")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Non-JSON strings are still served as a JSON string
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("Expected JSON content type, got %q", contentType)
+	}
+
+	var body string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	if body != "Hello Ada" {
+		t.Errorf("Expected body to be 'Hello Ada', got %q", body)
+	}
+}
+
+// TestRawContentTypes tests that text and XML string bodies are written raw
+// with their declared content type
+func TestRawContentTypes(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+	}{
+		{"html", "/api/page", "text/html; charset=utf-8", "<h1>Hello</h1>"},
+		{"xml", "/api/feed", "application/xml", `<?xml version="1.0"?><feed><id>1</id></feed>`},
+	}
+
+	var endpoints []config.Endpoint
+	for _, tt := range tests {
+		endpoints = append(endpoints, config.Endpoint{
+			ID:              tt.name,
+			Method:          "GET",
+			Path:            tt.path,
+			Active:          true,
+			DefaultResponse: "standard",
+			Responses: map[string]config.Response{
+				"standard": {
+					Status:  200,
+					Headers: map[string]string{"Content-Type": tt.contentType},
+					Body:    tt.body,
+				},
+			},
+		})
+	}
+
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{Feature: "test", Endpoints: endpoints}
+	srv := startServer(t, cfg)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get("http://" + srv.GetAddress() + tt.path)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if contentType := resp.Header.Get("Content-Type"); contentType != tt.contentType {
+				t.Errorf("Expected Content-Type %q, got %q", tt.contentType, contentType)
+			}
+
+			data, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %v", err)
+			}
+			if string(data) != tt.body {
+				t.Errorf("Expected raw body %q, got %q", tt.body, string(data))
+			}
+		})
+	}
+}
+
+// TestMethodOverride tests that the method override header is honored only
+// when enabled
+func TestMethodOverride(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "update-user",
+				Method:          "PUT",
+				Path:            "/api/users/1",
+				Active:          true,
+				DefaultResponse: "success",
+				Responses: map[string]config.Response{
+					"success": {Status: 200, Body: map[string]string{"source": "put-mock"}},
+				},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	send := func() *http.Response {
+		req, err := http.NewRequest("POST", "http://"+srv.GetAddress()+"/api/users/1", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set(server.MethodOverrideHeader, "put")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		return resp
+	}
+
+	// Off by default, so the POST does not match the PUT mock
+	resp := send()
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		t.Error("Expected override header to be ignored by default")
+	}
+
+	cfg.Global.HonorMethodOverride = true
+	resp = send()
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	if body["source"] != "put-mock" {
+		t.Errorf("Expected source to be 'put-mock', got %q", body["source"])
+	}
+}
+
+// TestFallbackResponse tests unmatched requests when there is no proxy target
+func TestFallbackResponse(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Global.ProxyConfig.Target = ""
+	srv := startServer(t, cfg)
+
+	// Without a configured fallback, a 404 JSON error is returned
+	resp, err := http.Get("http://" + srv.GetAddress() + "/api/unknown")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+	var errorBody map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&errorBody); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	resp.Body.Close()
+	if !strings.Contains(errorBody["error"], "GET /api/unknown") {
+		t.Errorf("Expected error to name the request, got %q", errorBody["error"])
+	}
+
+	// A configured fallback is served instead, also for inactive endpoints
+	cfg.Global.FallbackResponse = &config.Response{
+		Status: http.StatusServiceUnavailable,
+		Body:   map[string]string{"message": "offline"},
+	}
+	for _, path := range []string{"/api/unknown", "/api/inactive"} {
+		resp, err := http.Get("http://" + srv.GetAddress() + path)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Expected status code %d for %s, got %d", http.StatusServiceUnavailable, path, resp.StatusCode)
+		}
+		var body map[string]string
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to parse response body: %v", err)
+		}
+		resp.Body.Close()
+		if body["message"] != "offline" {
+			t.Errorf("Expected fallback body for %s, got %v", path, body)
+		}
+	}
+}
+
+// TestMockOnlyMode tests that unmatched requests get a 404 when proxying is disabled
+func TestMockOnlyMode(t *testing.T) {
+	cfg := createTestConfig()
+	proxyEnabled := false
+	cfg.Global.ProxyEnabled = &proxyEnabled
+	srv := startServer(t, cfg)
+
+	resp, err := http.Get("http://" + srv.GetAddress() + "/api/unknown")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("Expected JSON content type, got %q", contentType)
+	}
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	if body["error"] != "No mock found for GET /api/unknown" {
+		t.Errorf("Expected error naming the request, got %q", body["error"])
+	}
+
+	// Active mocks are still served
+	resp, err = http.Get("http://" + srv.GetAddress() + "/api/active")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d for active mock, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+// TestRequestSchema tests that bodies are validated against an endpoint's request schema
+func TestRequestSchema(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:     "create-user",
+				Method: "POST",
+				Path:   "/api/users",
+				Active: true,
+				RequestSchema: map[string]interface{}{
+					"type":     "object",
+					"required": []interface{}{"name"},
+					"properties": map[string]interface{}{
+						"name": map[string]interface{}{"type": "string"},
+						"age":  map[string]interface{}{"type": "integer", "minimum": 0},
+					},
+				},
+				DefaultResponse: "created",
+				Responses: map[string]config.Response{
+					"created": {Status: 201, Body: map[string]string{"status": "created"}},
+				},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	post := func(body string) *http.Response {
+		resp, err := http.Post("http://"+srv.GetAddress()+"/api/users", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		return resp
+	}
+
+	// A conforming body gets the configured response
+	resp := post(`{"name": "Ada", "age": 36}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected status code %d for a conforming body, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	// A non-conforming body is rejected with the validation errors
+	for _, body := range []string{`{"age": -1}`, `not json`, ``} {
+		resp := post(body)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for %q, got %d", http.StatusBadRequest, body, resp.StatusCode)
+		}
+
+		var result struct {
+			Error   string   `json:"error"`
+			Details []string `json:"details"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to parse response body: %v", err)
+		}
+		resp.Body.Close()
+
+		if result.Error == "" || len(result.Details) == 0 {
+			t.Errorf("Expected an error with details for %q, got %+v", body, result)
+		}
+	}
+
+	// Each violation is reported
+	resp = post(`{"age": -1}`)
+	var result struct {
+		Details []string `json:"details"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	resp.Body.Close()
+	details := strings.Join(result.Details, "\n")
+	if !strings.Contains(details, "name") || !strings.Contains(details, "/age") {
+		t.Errorf("Expected violations for name and /age, got %v", result.Details)
+	}
+}
+
+// TestHealthEndpoints tests that the health and readiness routes answer even without mocks
+func TestHealthEndpoints(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks = map[string]config.FeatureConfig{}
+	srv := startServer(t, cfg)
+
+	get := func(path string) (int, map[string]string) {
+		resp, err := http.Get("http://" + srv.GetAddress() + path)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var body map[string]string
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to parse response body for %s: %v", path, err)
+		}
+		return resp.StatusCode, body
+	}
+
+	// The proxy target isn't running, so a proxied request would fail
+	for path, want := range map[string]string{"/healthz": "ok", "/readyz": "ready"} {
+		status, body := get(path)
+		if status != http.StatusOK {
+			t.Errorf("Expected status code %d for %s, got %d", http.StatusOK, path, status)
+		}
+		if body["status"] != want {
+			t.Errorf("Expected status %q for %s, got %v", want, path, body)
+		}
+	}
+
+	// The paths can be configured
+	cfg.Global.HealthPath = "/_health"
+	if status, _ := get("/_health"); status != http.StatusOK {
+		t.Errorf("Expected status code %d for the configured health path, got %d", http.StatusOK, status)
+	}
+
+	// An active user mock on the same path takes precedence
+	if err := cfg.AddFeature(config.FeatureConfig{
+		Feature: "health",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "unhealthy",
+				Method:          "GET",
+				Path:            "/_health",
+				Active:          true,
+				DefaultResponse: "down",
+				Responses: map[string]config.Response{
+					"down": {Status: 503, Body: map[string]string{"status": "down"}},
+				},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add feature: %v", err)
+	}
+	status, body := get("/_health")
+	if status != http.StatusServiceUnavailable || body["status"] != "down" {
+		t.Errorf("Expected the user mock to answer with 503, got %d %v", status, body)
+	}
+}
+
+// TestEndpointWithoutResponses tests that an endpoint with no responses gets
+// a clear 500 instead of a panic
+func TestEndpointWithoutResponses(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{ID: "empty", Method: "GET", Path: "/api/empty", Active: true, DefaultResponse: "standard"},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	resp, err := http.Get("http://" + srv.GetAddress() + "/api/empty")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, resp.StatusCode)
+	}
+
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	if !strings.Contains(body["error"], "endpoint empty has no responses configured") {
+		t.Errorf("Expected error to explain the missing responses, got %q", body["error"])
+	}
+}
+
+// TestCounterTemplate tests that the counter template function increments
+// per request, keeps going when an edit is saved and reloaded, and starts
+// over when the counters are reset
+func TestCounterTemplate(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.BaseDir = t.TempDir()
+	cfg.Mocks["orders"] = config.FeatureConfig{
+		Feature: "orders",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "create-order",
+				Method:          "POST",
+				Path:            "/api/orders",
+				Active:          true,
+				DefaultResponse: "created",
+				Responses: map[string]config.Response{
+					"created": {Status: 201, Body: `{"order": {{counter "orders"}}}`},
+				},
+			},
+			{
+				ID:              "list-orders",
+				Method:          "GET",
+				Path:            "/api/orders",
+				DefaultResponse: "ok",
+				Responses: map[string]config.Response{
+					"ok": {Status: 200, Body: []interface{}{}},
+				},
+			},
+		},
+	}
+	if err := cfg.SaveGlobalConfig(); err != nil {
+		t.Fatalf("Failed to save global config: %v", err)
+	}
+	for _, feature := range cfg.FeatureNames() {
+		if err := cfg.SaveFeatureConfig(feature); err != nil {
+			t.Fatalf("Failed to save feature %s: %v", feature, err)
+		}
+	}
+	srv := startServer(t, cfg)
+
+	nextOrder := func() int {
+		resp, err := http.Post("http://"+srv.GetAddress()+"/api/orders", "application/json", nil)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var body struct {
+			Order int `json:"order"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to parse response body: %v", err)
+		}
+		return body.Order
+	}
+
+	for want := 1; want <= 3; want++ {
+		if got := nextOrder(); got != want {
+			t.Errorf("Expected order %d, got %d", want, got)
+		}
+	}
+
+	// The UI reloads the server after every edit it saves, such as a toggle
+	if err := srv.MockManager.ToggleEndpoint("orders", "list-orders"); err != nil {
+		t.Fatalf("Failed to toggle endpoint: %v", err)
+	}
+	if err := srv.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if got := nextOrder(); got != 4 {
+		t.Errorf("Expected counter to keep going at 4 after a toggle, got %d", got)
+	}
+
+	srv.MockManager.ResetCounters()
+	if got := nextOrder(); got != 1 {
+		t.Errorf("Expected counter to restart at 1 after a reset, got %d", got)
+	}
+}
+
+// TestResponseOverrideHeader tests that a request header can select one of
+// the endpoint's responses when the override is enabled
+func TestResponseOverrideHeader(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "get-user",
+				Method:          "GET",
+				Path:            "/api/users/1",
+				Active:          true,
+				DefaultResponse: "standard",
+				Responses: map[string]config.Response{
+					"standard": {Status: 200, Body: map[string]string{"name": "Ada"}},
+					"error":    {Status: 500, Body: map[string]string{"error": "boom"}},
+				},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	get := func(response string) int {
+		req, err := http.NewRequest("GET", "http://"+srv.GetAddress()+"/api/users/1", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("X-Mock-Response", response)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// The header is ignored until the override is enabled
+	if status := get("error"); status != http.StatusOK {
+		t.Errorf("Expected status code %d with the override disabled, got %d", http.StatusOK, status)
+	}
+
+	cfg.Global.ResponseOverrideHeader = "X-Mock-Response"
+	if status := get("error"); status != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for the overridden response, got %d", http.StatusInternalServerError, status)
+	}
+
+	// Unknown response names fall back to the default
+	if status := get("missing"); status != http.StatusOK {
+		t.Errorf("Expected status code %d for an unknown response, got %d", http.StatusOK, status)
+	}
+
+	// The override only applies to the single request
+	endpoint, err := cfg.GetEndpoint("test", "get-user")
+	if err != nil {
+		t.Fatalf("Failed to get endpoint: %v", err)
+	}
+	if endpoint.DefaultResponse != "standard" {
+		t.Errorf("Expected default response to stay 'standard', got %q", endpoint.DefaultResponse)
+	}
+}
+
+// TestUnixSocket tests serving mocks over a Unix domain socket
+func TestUnixSocket(t *testing.T) {
+	cfg := createTestConfig()
+	socketPath := filepath.Join(t.TempDir(), "climock.sock")
+	cfg.Global.ServerConfig.SocketPath = socketPath
+	srv := startServer(t, cfg)
+
+	if srv.GetAddress() != socketPath {
+		t.Errorf("Expected address %q, got %q", socketPath, srv.GetAddress())
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	// The host is ignored; every connection goes to the socket
+	resp, err := client.Get("http://climock/api/active")
+	if err != nil {
+		t.Fatalf("Failed to send request over socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	if err := srv.Stop(); err != nil {
+		t.Fatalf("Failed to stop server: %v", err)
+	}
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("Expected socket file to be removed on stop, got %v", err)
+	}
+}
+
+// TestRequestTracing tests that tracing logs the endpoints considered and the one served
+func TestRequestTracing(t *testing.T) {
+	var logs bytes.Buffer
+	logger.Logger = log.New(&logs, "", 0)
+	defer logger.InitTestLogger()
+
+	cfg := createTestConfig()
+	srv := startServer(t, cfg)
+
+	get := func(path string) {
+		resp, err := http.Get("http://" + srv.GetAddress() + path)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// Nothing is traced until tracing is switched on
+	get("/api/active")
+	if strings.Contains(logs.String(), "TRACE") {
+		t.Fatalf("Expected no trace output with tracing off, got:\n%s", logs.String())
+	}
+
+	srv.SetTrace(true)
+	get("/api/active")
+	output := logs.String()
+	for _, want := range []string{
+		"GET /api/active: candidates [test/active-endpoint (GET, active)]",
+		"GET /api/active: matched test/active-endpoint",
+		`GET /api/active: serving response "success" of active-endpoint`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected trace output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	// Inactive endpoints are listed as candidates but the request is proxied
+	logs.Reset()
+	get("/api/inactive")
+	output = logs.String()
+	if !strings.Contains(output, "test/inactive-endpoint (GET, inactive)") || !strings.Contains(output, "no active match, proxying to http://localhost:9000") {
+		t.Errorf("Expected the inactive endpoint to be traced as proxied, got:\n%s", output)
+	}
+}
+
+// TestCompressedResponse tests that responses marked compress are gzipped for
+// clients that accept it, and only for them
+func TestCompressedResponse(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "get-users",
+				Method:          "GET",
+				Path:            "/api/users",
+				Active:          true,
+				DefaultResponse: "success",
+				Responses: map[string]config.Response{
+					"success": {Status: 200, Body: map[string]string{"name": "Ada"}, Compress: true},
+				},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	get := func(acceptEncoding string) *http.Response {
+		req, err := http.NewRequest("GET", "http://"+srv.GetAddress()+"/api/users", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		// Setting the header ourselves stops the client from decompressing transparently
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		return resp
+	}
+
+	resp := get("br, gzip")
+	defer resp.Body.Close()
+	if encoding := resp.Header.Get("Content-Encoding"); encoding != "gzip" {
+		t.Fatalf("Expected Content-Encoding gzip, got %q", encoding)
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("Expected a valid gzip body: %v", err)
+	}
+	body, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("Failed to decompress body: %v", err)
+	}
+	if string(body) != `{"name":"Ada"}` {
+		t.Errorf("Expected the decompressed JSON body, got %q", body)
+	}
+
+	// Clients that don't accept gzip get the plain body
+	for _, acceptEncoding := range []string{"", "gzip;q=0"} {
+		plain := get(acceptEncoding)
+		body, _ := io.ReadAll(plain.Body)
+		plain.Body.Close()
+		if plain.Header.Get("Content-Encoding") != "" || string(body) != `{"name":"Ada"}` {
+			t.Errorf("Accept-Encoding %q: expected an uncompressed body, got %q (%q)", acceptEncoding, body, plain.Header.Get("Content-Encoding"))
+		}
+	}
+}
+
+// TestStreamedResponse tests that streamed bodies arrive chunk by chunk
+func TestStreamedResponse(t *testing.T) {
+	const interval = 150 * time.Millisecond
+
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "events",
+				Method:          "GET",
+				Path:            "/api/events",
+				Active:          true,
+				DefaultResponse: "stream",
+				Responses: map[string]config.Response{
+					"stream": {
+						Status:  200,
+						Headers: map[string]string{"Content-Type": "text/event-stream"},
+						Stream: &config.StreamConfig{
+							Chunks:   []string{"data: one\n\n", "data: two\n\n", "data: three\n\n"},
+							Interval: int(interval / time.Millisecond),
+						},
+					},
+				},
+			},
+			{
+				ID:              "users",
+				Method:          "GET",
+				Path:            "/api/users",
+				Active:          true,
+				DefaultResponse: "chunked",
+				Responses: map[string]config.Response{
+					"chunked": {
+						Status: 200,
+						Body:   map[string]string{"name": "Ada"},
+						Stream: &config.StreamConfig{ChunkSize: 4},
+					},
+				},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	start := time.Now()
+	resp, err := http.Get("http://" + srv.GetAddress() + "/api/events")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Expected Content-Type text/event-stream, got %q", contentType)
+	}
+	if resp.Header.Get("Cache-Control") != "no-cache" {
+		t.Errorf("Expected Cache-Control no-cache for an event stream, got %q", resp.Header.Get("Cache-Control"))
+	}
+
+	// The first event arrives before the pause, the rest after it
+	reader := bufio.NewReader(resp.Body)
+	first, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read the first chunk: %v", err)
+	}
+	if first != "data: one\n" {
+		t.Errorf("Expected the first event, got %q", first)
+	}
+	if elapsed := time.Since(start); elapsed >= interval {
+		t.Errorf("Expected the first chunk to be flushed right away, took %v", elapsed)
+	}
+	rest, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to read the remaining chunks: %v", err)
+	}
+	if string(rest) != "\ndata: two\n\ndata: three\n\n" {
+		t.Errorf("Expected the remaining events, got %q", rest)
+	}
+	if elapsed := time.Since(start); elapsed < 2*interval {
+		t.Errorf("Expected the chunks to be spaced %v apart, all arrived in %v", interval, elapsed)
+	}
+
+	// Bodies split by size still add up to the whole body
+	chunked, err := http.Get("http://" + srv.GetAddress() + "/api/users")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	body, _ := io.ReadAll(chunked.Body)
+	chunked.Body.Close()
+	if string(body) != `{"name":"Ada"}` || chunked.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected the JSON body in full, got %q (%s)", body, chunked.Header.Get("Content-Type"))
+	}
+}
+
+// readSSEEvent reads one event from an event stream and returns its fields
+func readSSEEvent(t *testing.T, reader *bufio.Reader) map[string]string {
+	t.Helper()
+
+	fields := make(map[string]string)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return fields
+		}
+		name, value, _ := strings.Cut(line, ": ")
+		fields[name] = value
+	}
+}
+
+// TestSSEResponse tests that SSE responses send their events in order
+func TestSSEResponse(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "events",
+				Method:          "GET",
+				Path:            "/api/events",
+				Active:          true,
+				DefaultResponse: "events",
+				Responses: map[string]config.Response{
+					"events": {
+						Status: 200,
+						SSE: &config.SSEConfig{
+							Events: []config.SSEEvent{
+								{Event: "greeting", Data: "hello", ID: "1"},
+								{Data: map[string]int{"count": 2}},
+							},
+							Interval: 50,
+						},
+					},
+				},
+			},
+			{
+				ID:              "ticks",
+				Method:          "GET",
+				Path:            "/api/ticks",
+				Active:          true,
+				DefaultResponse: "ticks",
+				Responses: map[string]config.Response{
+					"ticks": {
+						Status: 200,
+						SSE: &config.SSEConfig{
+							Events: []config.SSEEvent{{Data: "tick"}},
+							Loop:   true,
+						},
+					},
+				},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	resp, err := http.Get("http://" + srv.GetAddress() + "/api/events")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Expected Content-Type text/event-stream, got %q", contentType)
+	}
+	if resp.Header.Get("Cache-Control") != "no-cache" {
+		t.Errorf("Expected Cache-Control no-cache, got %q", resp.Header.Get("Cache-Control"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	first := readSSEEvent(t, reader)
+	if first["data"] != "hello" || first["event"] != "greeting" || first["id"] != "1" {
+		t.Errorf("Unexpected first event: %v", first)
+	}
+	second := readSSEEvent(t, reader)
+	if second["data"] != `{"count":2}` {
+		t.Errorf("Expected JSON data in the second event, got %v", second)
+	}
+
+	// Without loop, the stream ends after the last event
+	if rest, _ := io.ReadAll(reader); len(rest) != 0 {
+		t.Errorf("Expected the stream to end, got %q", rest)
+	}
+
+	// With loop, events repeat until the client goes away, and stopping the
+	// server doesn't wait for the stream
+	ticks, err := http.Get("http://" + srv.GetAddress() + "/api/ticks")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer ticks.Body.Close()
+	tickReader := bufio.NewReader(ticks.Body)
+	for i := 0; i < 3; i++ {
+		if event := readSSEEvent(t, tickReader); event["data"] != "tick" {
+			t.Fatalf("Expected looping tick events, got %v", event)
+		}
+	}
+	start := time.Now()
+	if err := srv.Stop(); err != nil {
+		t.Fatalf("Failed to stop server: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Stop to end the looping stream promptly, took %v", elapsed)
+	}
+}
+
+// TestHeadAndOptionsMocks tests that HEAD mocks send headers without a body
+// and that OPTIONS mocks answer instead of the CORS preflight shortcut
+func TestHeadAndOptionsMocks(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "head-users",
+				Method:          "HEAD",
+				Path:            "/api/users",
+				Active:          true,
+				DefaultResponse: "success",
+				Responses: map[string]config.Response{
+					"success": {
+						Status:  200,
+						Headers: map[string]string{"X-Total-Count": "42"},
+						Body:    map[string]string{"ignored": "for HEAD"},
+					},
+				},
+			},
+			{
+				ID:              "options-users",
+				Method:          "OPTIONS",
+				Path:            "/api/users",
+				Active:          true,
+				DefaultResponse: "success",
+				Responses: map[string]config.Response{
+					"success": {
+						Status:  200,
+						Headers: map[string]string{"Allow": "GET, HEAD, OPTIONS"},
+						Body:    map[string]string{"methods": "GET, HEAD, OPTIONS"},
+					},
+				},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	// Read HEAD over a raw connection, since the client would drop a body anyway
+	conn, err := net.Dial("tcp", srv.GetAddress())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "HEAD /api/users HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	raw, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	head, body, _ := strings.Cut(string(raw), "\r\n\r\n")
+	if !strings.HasPrefix(head, "HTTP/1.1 200") || !strings.Contains(head, "X-Total-Count: 42") {
+		t.Errorf("Expected a 200 with the mocked headers, got:\n%s", head)
+	}
+	if body != "" {
+		t.Errorf("Expected no body for HEAD, got %q", body)
+	}
+
+	// A mocked OPTIONS endpoint gets its configured response, with CORS headers
+	req, err := http.NewRequest("OPTIONS", "http://"+srv.GetAddress()+"/api/users", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(data) != `{"methods":"GET, HEAD, OPTIONS"}` {
+		t.Errorf("Expected the mocked OPTIONS response, got %d %q", resp.StatusCode, data)
+	}
+	if resp.Header.Get("Allow") != "GET, HEAD, OPTIONS" || resp.Header.Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected the mocked and CORS headers, got %v", resp.Header)
+	}
+
+	// Other OPTIONS requests still get the preflight 204
+	req, _ = http.NewRequest("OPTIONS", "http://"+srv.GetAddress()+"/api/other", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected the preflight 204 for an unmocked OPTIONS, got %d", resp.StatusCode)
+	}
+}
+
+// TestEndpointCORSPolicy tests that an endpoint's CORS settings replace the defaults
+func TestEndpointCORSPolicy(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "private",
+				Method:          "GET",
+				Path:            "/api/private",
+				Active:          true,
+				DefaultResponse: "success",
+				Responses:       map[string]config.Response{"success": {Status: 200}},
+				CORS: &config.CORSConfig{
+					AllowOrigin:      "https://app.example.com",
+					AllowCredentials: true,
+				},
+			},
+			{
+				ID:              "public",
+				Method:          "GET",
+				Path:            "/api/public",
+				Active:          true,
+				DefaultResponse: "success",
+				Responses:       map[string]config.Response{"success": {Status: 200}},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	send := func(method, path, requestMethod string) *http.Response {
+		req, err := http.NewRequest(method, "http://"+srv.GetAddress()+path, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		if requestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", requestMethod)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := send("GET", "/api/private", "")
+	if origin := resp.Header.Values("Access-Control-Allow-Origin"); len(origin) != 1 || origin[0] != "https://app.example.com" {
+		t.Errorf("Expected only the endpoint's origin, got %v", origin)
+	}
+	if resp.Header.Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("Expected credentials to be allowed, got %q", resp.Header.Get("Access-Control-Allow-Credentials"))
+	}
+	if resp.Header.Get("Access-Control-Allow-Methods") == "" {
+		t.Error("Expected unset fields to keep their defaults")
+	}
+
+	// The preflight for the endpoint's method uses its policy too
+	preflight := send("OPTIONS", "/api/private", "GET")
+	if preflight.StatusCode != http.StatusNoContent || preflight.Header.Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("Expected a preflight with the endpoint's origin, got %d %q", preflight.StatusCode, preflight.Header.Get("Access-Control-Allow-Origin"))
+	}
+
+	// Other endpoints keep the wildcard
+	if origin := send("GET", "/api/public", "").Header.Get("Access-Control-Allow-Origin"); origin != "*" {
+		t.Errorf("Expected the default origin for other endpoints, got %q", origin)
+	}
+}
+
+// TestETagResponse tests that an endpoint with etag set answers a matching
+// If-None-Match with a 304
+func TestETagResponse(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "get-users",
+				Method:          "GET",
+				Path:            "/api/users",
+				Active:          true,
+				ETag:            true,
+				DefaultResponse: "success",
+				Responses: map[string]config.Response{
+					"success": {Status: 200, Body: map[string]string{"name": "Ada"}},
+				},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	get := func(ifNoneMatch string) (*http.Response, string) {
+		req, err := http.NewRequest("GET", "http://"+srv.GetAddress()+"/api/users", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		return resp, string(body)
+	}
+
+	resp, body := get("")
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("Expected a 200 with an ETag, got %d %q", resp.StatusCode, etag)
+	}
+	if !strings.Contains(body, "Ada") {
+		t.Errorf("Expected the body on the first request, got %q", body)
+	}
+
+	resp, body = get(etag)
+	if resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("Expected 304 for a matching If-None-Match, got %d", resp.StatusCode)
+	}
+	if body != "" || resp.Header.Get("ETag") != etag {
+		t.Errorf("Expected an empty 304 with the same ETag, got %q with %q", body, resp.Header.Get("ETag"))
+	}
+
+	if resp, _ := get(`"stale"`); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for a different ETag, got %d", resp.StatusCode)
+	}
+}
+
+// TestDisplayAddress tests the address shown to users when binding every
+// interface
+func TestDisplayAddress(t *testing.T) {
+	tests := []struct {
+		host, advertise, bind, display string
+	}{
+		{"localhost", "", "localhost:3000", "localhost:3000"},
+		{"0.0.0.0", "", "0.0.0.0:3000", "localhost:3000"},
+		{"", "", ":3000", "localhost:3000"},
+		{"::", "", ":::3000", "localhost:3000"},
+		{"0.0.0.0", "192.168.1.10", "0.0.0.0:3000", "192.168.1.10:3000"},
+	}
+
+	for _, tt := range tests {
+		cfg := createTestConfig()
+		cfg.Global.ServerConfig = config.ServerConfig{Host: tt.host, Port: 3000, AdvertiseHost: tt.advertise}
+		srv := server.New(cfg, mock.New(cfg), nil)
+
+		if addr := srv.GetAddress(); addr != tt.bind {
+			t.Errorf("Host %q: expected bind address %q, got %q", tt.host, tt.bind, addr)
+		}
+		if addr := srv.GetDisplayAddress(); addr != tt.display {
+			t.Errorf("Host %q: expected display address %q, got %q", tt.host, tt.display, addr)
+		}
+	}
+}
+
+// TestAuditLog tests that mocked and proxied requests are written to the
+// audit log
+func TestAuditLog(t *testing.T) {
+	cfg := createTestConfig()
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	cfg.Global.AuditLog = auditPath
+	srv := startServer(t, cfg)
+
+	for _, path := range []string{"/api/active", "/api/unmatched"} {
+		resp, err := http.Get("http://" + srv.GetAddress() + path)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// Stopping waits for the handlers, and so the audit writes, to finish. It
+	// also waits for connections that never sent a request, like one the
+	// client dialed but didn't need, so close those first.
+	http.DefaultClient.CloseIdleConnections()
+	if err := srv.Stop(); err != nil {
+		t.Fatalf("Failed to stop server: %v", err)
+	}
+
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 audit lines, got %d: %s", len(lines), data)
+	}
+
+	var entries []map[string]interface{}
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Expected a JSON line, got %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	mocked := entries[0]
+	if mocked["source"] != "mocked" || mocked["feature"] != "test" || mocked["endpoint"] != "active-endpoint" || mocked["response"] != "success" {
+		t.Errorf("Expected the mocked request to name its endpoint and response, got %v", mocked)
+	}
+	if mocked["method"] != "GET" || mocked["path"] != "/api/active" || mocked["status"] != float64(200) {
+		t.Errorf("Expected GET /api/active with status 200, got %v", mocked)
+	}
+	if _, ok := mocked["durationMs"]; !ok {
+		t.Error("Expected the entry to record a duration")
+	}
+
+	proxied := entries[1]
+	if proxied["source"] != "proxied" || proxied["path"] != "/api/unmatched" {
+		t.Errorf("Expected the unmatched request to be recorded as proxied, got %v", proxied)
+	}
+	if _, ok := proxied["endpoint"]; ok {
+		t.Errorf("Expected no endpoint for a proxied request, got %v", proxied["endpoint"])
+	}
+}
+
+// TestResponseProfile tests that the server serves the response tagged with
+// the active profile
+func TestResponseProfile(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "get-users",
+				Method:          "GET",
+				Path:            "/api/users",
+				Active:          true,
+				DefaultResponse: "success",
+				Responses: map[string]config.Response{
+					"success": {Status: 200},
+					"outage":  {Status: 503, Profiles: []string{"errors"}},
+				},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	status := func() int {
+		resp, err := http.Get("http://" + srv.GetAddress() + "/api/users")
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if err := srv.MockManager.SetProfile("errors"); err != nil {
+		t.Fatalf("Failed to set profile: %v", err)
+	}
+	if code := status(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected the errors profile to serve 503, got %d", code)
+	}
+
+	if err := srv.MockManager.SetProfile(""); err != nil {
+		t.Fatalf("Failed to clear profile: %v", err)
+	}
+	if code := status(); code != http.StatusOK {
+		t.Errorf("Expected the default response without a profile, got %d", code)
+	}
+}
+
+// TestProxyResponseType tests that a matched endpoint with the proxy response
+// type forwards its requests to the upstream
+func TestProxyResponseType(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"source": "upstream", "path": r.URL.Path})
+	}))
+	defer upstream.Close()
+
+	cfg := createTestConfig()
+	cfg.Global.ProxyConfig.Target = upstream.URL
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:           "live-orders",
+				Method:       "GET",
+				Path:         "/api/orders",
+				Active:       true,
+				Description:  "Always served by the real API",
+				ResponseType: config.ResponseTypeProxy,
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	resp, err := http.Get("http://" + srv.GetAddress() + "/api/orders")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || body["source"] != "upstream" || body["path"] != "/api/orders" {
+		t.Errorf("Expected the request to reach the upstream, got %d %v", resp.StatusCode, body)
+	}
+}
+
+// TestEndpointProxyPathRewrite tests that an endpoint's proxyPathRewrite
+// replaces the global rules for its proxied requests only
+func TestEndpointProxyPathRewrite(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer upstream.Close()
+
+	cfg := createTestConfig()
+	cfg.Global.ProxyConfig.Target = upstream.URL
+	cfg.Global.ProxyConfig.PathRewrite = map[string]string{"^/api": ""}
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:               "legacy-orders",
+				Method:           "GET",
+				Path:             "/api/orders",
+				Active:           false,
+				DefaultResponse:  "success",
+				Responses:        map[string]config.Response{"success": {Status: 200}},
+				ProxyPathRewrite: map[string]string{"^/api/orders": "/v1/legacy/orders"},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	upstreamPath := func(path string) string {
+		resp, err := http.Get("http://" + srv.GetAddress() + path)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		return string(body)
+	}
+
+	if path := upstreamPath("/api/orders"); path != "/v1/legacy/orders" {
+		t.Errorf("Expected the endpoint's rewrite, got upstream path %q", path)
+	}
+	if path := upstreamPath("/api/users"); path != "/users" {
+		t.Errorf("Expected the global rewrite for other requests, got upstream path %q", path)
+	}
+}
+
+// TestCustomErrorResponse tests that a response that can't be generated is
+// answered with the configured errorResponse envelope
+func TestCustomErrorResponse(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Global.ErrorResponse = &config.Response{
+		Status: http.StatusServiceUnavailable,
+		Body: map[string]interface{}{
+			"error": map[string]interface{}{
+				"code":    "MOCK_FAILURE",
+				"message": "{{.error}}",
+			},
+		},
+	}
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "broken",
+				Method:          "GET",
+				Path:            "/api/broken",
+				Active:          true,
+				DefaultResponse: "success",
+				Responses: map[string]config.Response{
+					// The error message quotes "abc", which has to be escaped
+					"success": {Status: 200, Body: map[string]interface{}{"count": "{{int `abc`}}"}},
+				},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	resp, err := http.Get("http://" + srv.GetAddress() + "/api/broken")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Error.Code != "MOCK_FAILURE" {
+		t.Errorf("Expected code MOCK_FAILURE, got %q", body.Error.Code)
+	}
+	if !strings.HasPrefix(body.Error.Message, "Failed to generate response:") || !strings.Contains(body.Error.Message, `"abc"`) {
+		t.Errorf("Expected the failure message, got %q", body.Error.Message)
+	}
+}
+
+// TestBase64Body tests that bodyBase64 is decoded and written raw with the
+// configured content type
+func TestBase64Body(t *testing.T) {
+	// A 1x1 transparent PNG
+	const pixel = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg=="
+	want, err := base64.StdEncoding.DecodeString(pixel)
+	if err != nil {
+		t.Fatalf("Failed to decode fixture: %v", err)
+	}
+
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "avatar",
+				Method:          "GET",
+				Path:            "/api/avatar.png",
+				Active:          true,
+				DefaultResponse: "standard",
+				Responses: map[string]config.Response{
+					"standard": {
+						Status:     200,
+						Headers:    map[string]string{"Content-Type": "image/png"},
+						BodyBase64: pixel,
+					},
+				},
+			},
+			{
+				ID:              "broken",
+				Method:          "GET",
+				Path:            "/api/broken.png",
+				Active:          true,
+				DefaultResponse: "standard",
+				Responses: map[string]config.Response{
+					"standard": {Status: 200, BodyBase64: "not base64!"},
+				},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	resp, err := http.Get("http://" + srv.GetAddress() + "/api/avatar.png")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "image/png" {
+		t.Errorf("Expected Content-Type image/png, got %q", contentType)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("Expected the decoded PNG bytes, got %q", data)
+	}
+
+	// A body that isn't valid base64 is an internal error
+	resp, err = http.Get("http://" + srv.GetAddress() + "/api/broken.png")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status 500 for an invalid bodyBase64, got %d", resp.StatusCode)
+	}
+}
+
+// TestBodyFraming tests the per-response Content-Length and chunked
+// encoding controls
+func TestBodyFraming(t *testing.T) {
+	tooLong := 20
+	tests := []struct {
+		name              string
+		response          config.Response
+		wantChunked       bool
+		wantContentLength int64
+		wantReadErr       bool
+	}{
+		{"default", config.Response{Status: 200, Body: "hello world"}, false, 11, false},
+		{"forceChunked", config.Response{Status: 200, Body: "hello world", ForceChunked: true}, true, -1, false},
+		{"omitContentLength", config.Response{Status: 200, Body: "hello world", OmitContentLength: true}, false, -1, false},
+		// The connection closes before the declared length arrives
+		{"contentLength", config.Response{Status: 200, Body: "hello world", ContentLength: &tooLong}, false, 20, true},
+	}
+
+	var endpoints []config.Endpoint
+	for _, tt := range tests {
+		response := tt.response
+		response.Headers = map[string]string{"Content-Type": "text/plain"}
+		endpoints = append(endpoints, config.Endpoint{
+			ID:              tt.name,
+			Method:          "GET",
+			Path:            "/api/" + tt.name,
+			Active:          true,
+			DefaultResponse: "standard",
+			Responses:       map[string]config.Response{"standard": response},
+		})
+	}
+
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{Feature: "test", Endpoints: endpoints}
+	srv := startServer(t, cfg)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get("http://" + srv.GetAddress() + "/api/" + tt.name)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			chunked := len(resp.TransferEncoding) > 0 && resp.TransferEncoding[0] == "chunked"
+			if chunked != tt.wantChunked {
+				t.Errorf("Expected chunked transfer %v, got Transfer-Encoding %v", tt.wantChunked, resp.TransferEncoding)
+			}
+			if resp.ContentLength != tt.wantContentLength {
+				t.Errorf("Expected Content-Length %d, got %d", tt.wantContentLength, resp.ContentLength)
+			}
+
+			data, err := io.ReadAll(resp.Body)
+			if (err != nil) != tt.wantReadErr {
+				t.Errorf("Expected a read error %v, got %v", tt.wantReadErr, err)
+			}
+			if string(data) != "hello world" {
+				t.Errorf("Expected body %q, got %q", "hello world", string(data))
+			}
+		})
+	}
+}
+
+// TestCORSDisabled tests that with corsEnabled off no CORS headers are
+// added, and those set by mocks or the proxy target pass through
+func TestCORSDisabled(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "https://upstream.example")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	disabled := false
+	cfg := createTestConfig()
+	cfg.Global.CORSEnabled = &disabled
+	cfg.Global.ProxyConfig.Target = upstream.URL
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "plain",
+				Method:          "GET",
+				Path:            "/api/plain",
+				Active:          true,
+				DefaultResponse: "standard",
+				Responses:       map[string]config.Response{"standard": {Status: 200, Body: map[string]interface{}{"ok": true}}},
+			},
+			{
+				ID:              "own-cors",
+				Method:          "GET",
+				Path:            "/api/own-cors",
+				Active:          true,
+				DefaultResponse: "standard",
+				Responses: map[string]config.Response{"standard": {
+					Status:  200,
+					Headers: map[string]string{"Access-Control-Allow-Origin": "https://app.example"},
+				}},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   string
+	}{
+		{"mock", "GET", "/api/plain", ""},
+		// Nothing answers preflights early, so they reach the target
+		{"preflight", "OPTIONS", "/api/plain", "https://upstream.example"},
+		{"mock headers", "GET", "/api/own-cors", "https://app.example"},
+		{"proxied", "GET", "/api/upstream", "https://upstream.example"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "http://"+srv.GetAddress()+tt.path, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("Origin", "https://client.example")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if got := resp.Header.Get("Access-Control-Allow-Origin"); got != tt.want {
+				t.Errorf("Expected Access-Control-Allow-Origin %q, got %q", tt.want, got)
+			}
+			if got := resp.Header.Get("Access-Control-Allow-Methods"); got != "" {
+				t.Errorf("Expected no Access-Control-Allow-Methods, got %q", got)
+			}
+		})
+	}
+}
+
+// TestPreserveUpstreamCORS tests that proxied responses keep the target's
+// CORS headers while mocks still get the server's own
+func TestPreserveUpstreamCORS(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "https://upstream.example")
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("X-Upstream-Method", r.Method)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	cfg := createTestConfig()
+	cfg.Global.ProxyConfig.Target = upstream.URL
+	cfg.Global.ProxyConfig.PreserveUpstreamCORS = true
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "mocked",
+				Method:          "GET",
+				Path:            "/api/mocked",
+				Active:          true,
+				DefaultResponse: "standard",
+				Responses:       map[string]config.Response{"standard": {Status: 200}},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	send := func(method, path string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, "http://"+srv.GetAddress()+path, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("Origin", "https://client.example")
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := send("GET", "/api/proxied")
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://upstream.example" {
+		t.Errorf("Expected the upstream Access-Control-Allow-Origin, got %q", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Expected the upstream Access-Control-Allow-Credentials, got %q", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Methods"); got != "" {
+		t.Errorf("Expected no local Access-Control-Allow-Methods, got %q", got)
+	}
+
+	// The target answers preflights for the requests it serves
+	resp = send("OPTIONS", "/api/proxied")
+	if got := resp.Header.Get("X-Upstream-Method"); got != "OPTIONS" {
+		t.Errorf("Expected the preflight to reach the upstream, got status %d", resp.StatusCode)
+	}
+
+	// Mocks still get the server's CORS headers
+	resp = send("GET", "/api/mocked")
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Expected the local Access-Control-Allow-Origin for a mock, got %q", got)
+	}
+	resp = send("OPTIONS", "/api/mocked")
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("X-Upstream-Method") != "" {
+		t.Errorf("Expected a local preflight response for a mock, got status %d", resp.StatusCode)
+	}
+}
+
+// lockedBuffer is a bytes.Buffer that the server's goroutines can log to
+// while the test reads it
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestDelayCanceled tests that a delayed response is abandoned as soon as the
+// client goes away
+func TestDelayCanceled(t *testing.T) {
+	var logs lockedBuffer
+	logger.Logger = log.New(&logs, "", 0)
+	logger.IsDebugMode = true
+	// Registered first so it runs after the server has stopped
+	t.Cleanup(logger.InitTestLogger)
+
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "slow",
+				Method:          "GET",
+				Path:            "/api/slow",
+				Active:          true,
+				DefaultResponse: "standard",
+				Responses: map[string]config.Response{
+					"standard": {Status: 200, Body: map[string]interface{}{"done": true}, Delay: 10000},
+				},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://"+srv.GetAddress()+"/api/slow", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	if err := <-done; err == nil {
+		t.Fatal("Expected the canceled request to fail")
+	}
+
+	// The handler notices well before the delay is up
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(logs.String(), "GET /api/slow - canceled during a 10s delay") {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the handler to give up on the canceled request, got logs:\n%s", logs.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if strings.Contains(logs.String(), "GET /api/slow - mocked") {
+		t.Errorf("Expected no response to be written, got logs:\n%s", logs.String())
+	}
+}
+
+// TestRequestBodyTemplate tests that templates can use the request's JSON
+// body, and see an empty one when it isn't JSON
+func TestRequestBodyTemplate(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "create-user",
+				Method:          "POST",
+				Path:            "/api/users",
+				Active:          true,
+				DefaultResponse: "standard",
+				Responses: map[string]config.Response{
+					"standard": {Status: 201, Body: map[string]interface{}{
+						"name":     "{{.body.user.name}}",
+						"greeting": "{{with .body.user}}Hello {{.name}}{{else}}Hello stranger{{end}}",
+					}},
+				},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	post := func(contentType, payload string) map[string]string {
+		t.Helper()
+		resp, err := http.Post("http://"+srv.GetAddress()+"/api/users", contentType, strings.NewReader(payload))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var body map[string]string
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to parse response body: %v", err)
+		}
+		return body
+	}
+
+	if body := post("application/json", `{"user":{"name":"Ada"}}`); body["name"] != "Ada" || body["greeting"] != "Hello Ada" {
+		t.Errorf("Expected the posted name in the response, got %v", body)
+	}
+	if body := post("text/plain", "not json"); body["greeting"] != "Hello stranger" {
+		t.Errorf("Expected a non-JSON body to be empty, got %v", body)
+	}
+}
+
+// TestDefaultHeadersAndDelay tests that the global defaultHeaders and
+// defaultDelay apply to mock responses that don't set their own
+func TestDefaultHeadersAndDelay(t *testing.T) {
+	const defaultDelay = 300 * time.Millisecond
+
+	cfg := createTestConfig()
+	cfg.Global.DefaultHeaders = map[string]string{"X-Mock": "true"}
+	cfg.Global.DefaultDelay = int(defaultDelay / time.Millisecond)
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "plain",
+				Method:          "GET",
+				Path:            "/api/plain",
+				Active:          true,
+				DefaultResponse: "standard",
+				Responses:       map[string]config.Response{"standard": {Status: 200}},
+			},
+			{
+				ID:              "own",
+				Method:          "GET",
+				Path:            "/api/own",
+				Active:          true,
+				DefaultResponse: "standard",
+				Responses: map[string]config.Response{"standard": {
+					Status:  200,
+					Headers: map[string]string{"x-mock": "custom"},
+					Delay:   1,
+				}},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	get := func(path string) (*http.Response, time.Duration) {
+		t.Helper()
+		start := time.Now()
+		resp, err := http.Get("http://" + srv.GetAddress() + path)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		return resp, time.Since(start)
+	}
+
+	resp, elapsed := get("/api/plain")
+	if got := resp.Header.Get("X-Mock"); got != "true" {
+		t.Errorf("Expected the default X-Mock header, got %q", got)
+	}
+	if elapsed < defaultDelay {
+		t.Errorf("Expected the default delay of %v, took %v", defaultDelay, elapsed)
+	}
+
+	// A response's own header and delay win, whatever the header's case
+	resp, elapsed = get("/api/own")
+	if got := resp.Header.Values("X-Mock"); len(got) != 1 || got[0] != "custom" {
+		t.Errorf("Expected only the response's own X-Mock header, got %v", got)
+	}
+	if elapsed >= defaultDelay {
+		t.Errorf("Expected the response's own delay instead of the default, took %v", elapsed)
+	}
+}
+
+// TestRequestID tests that each request gets an X-Request-Id, generated
+// unless the client sent one, that templates, the proxy target and the audit
+// log all see
+func TestRequestID(t *testing.T) {
+	var upstreamID string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamID = r.Header.Get("X-Request-Id")
+		// A target that answers with its own ID mustn't replace the request's
+		w.Header().Set("X-Request-Id", "from-upstream")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	cfg := createTestConfig()
+	cfg.Global.ProxyConfig.Target = upstream.URL
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	cfg.Global.AuditLog = auditPath
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "whoami",
+				Method:          "GET",
+				Path:            "/api/whoami",
+				Active:          true,
+				DefaultResponse: "standard",
+				Responses: map[string]config.Response{
+					"standard": {Status: 200, Body: map[string]interface{}{"requestId": "{{.requestId}}"}},
+				},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	get := func(path, id string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("GET", "http://"+srv.GetAddress()+path, nil)
+		if id != "" {
+			req.Header.Set("X-Request-Id", id)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		return resp
+	}
+	bodyID := func(resp *http.Response) string {
+		t.Helper()
+		defer resp.Body.Close()
+		var body map[string]string
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return body["requestId"]
+	}
+
+	// Without a header, an ID is generated
+	resp := get("/api/whoami", "")
+	generated := resp.Header.Get("X-Request-Id")
+	if generated == "" {
+		t.Fatal("Expected a generated X-Request-Id")
+	}
+	if id := bodyID(resp); id != generated {
+		t.Errorf("Expected the template to see %q, got %q", generated, id)
+	}
+	if again := get("/api/whoami", ""); again.Header.Get("X-Request-Id") == generated {
+		t.Errorf("Expected each request to get its own ID, got %q twice", generated)
+	} else {
+		again.Body.Close()
+	}
+
+	// The client's own ID is kept
+	resp = get("/api/whoami", "client-id")
+	if id := resp.Header.Get("X-Request-Id"); id != "client-id" {
+		t.Errorf("Expected the client's ID to be kept, got %q", id)
+	}
+	if id := bodyID(resp); id != "client-id" {
+		t.Errorf("Expected the template to see the client's ID, got %q", id)
+	}
+
+	// Proxied requests forward the ID
+	resp = get("/api/unmatched", "")
+	resp.Body.Close()
+	proxiedID := resp.Header.Get("X-Request-Id")
+	if proxiedID == "" || proxiedID != upstreamID {
+		t.Errorf("Expected the upstream to receive the response's ID %q, got %q", proxiedID, upstreamID)
+	}
+
+	// Stopping waits for the handlers, and so the audit writes, to finish. It
+	// also waits for connections that never sent a request, like one the
+	// client dialed but didn't need, so close those first.
+	http.DefaultClient.CloseIdleConnections()
+	if err := srv.Stop(); err != nil {
+		t.Fatalf("Failed to stop server: %v", err)
+	}
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var last map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil {
+		t.Fatalf("Expected a JSON line, got %q: %v", lines[len(lines)-1], err)
+	}
+	if last["requestId"] != proxiedID {
+		t.Errorf("Expected the audit entry to record %q, got %v", proxiedID, last["requestId"])
+	}
+}
+
+// TestNoContentResponse tests that 204 responses, and responses without a
+// body, are sent with nothing after the headers rather than a JSON null.
+// The request is made over a raw connection, since Go's client hides what
+// the server sends after a 204.
+func TestNoContentResponse(t *testing.T) {
+	cfg := createTestConfig()
+	jsonHeaders := map[string]string{"Content-Type": "application/json"}
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{ID: "delete-item", Method: "DELETE", Path: "/api/items/:id", Active: true, DefaultResponse: "deleted",
+				Responses: map[string]config.Response{"deleted": {Status: 204, Headers: jsonHeaders}}},
+			// A body configured for a 204 by mistake is dropped
+			{ID: "archive-item", Method: "DELETE", Path: "/api/archive/:id", Active: true, DefaultResponse: "archived",
+				Responses: map[string]config.Response{"archived": {Status: 204, Headers: jsonHeaders, Body: map[string]interface{}{"ok": true}}}},
+			{ID: "empty", Method: "GET", Path: "/api/empty", Active: true, DefaultResponse: "empty",
+				Responses: map[string]config.Response{"empty": {Status: 200, Headers: jsonHeaders}}},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	tests := []struct {
+		method, path string
+		status       string
+	}{
+		{"DELETE", "/api/items/1", "204 No Content"},
+		{"DELETE", "/api/archive/1", "204 No Content"},
+		{"GET", "/api/empty", "200 OK"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			conn, err := net.Dial("tcp", srv.GetAddress())
+			if err != nil {
+				t.Fatalf("Failed to connect: %v", err)
+			}
+			defer conn.Close()
+			if _, err := io.WriteString(conn, tt.method+" "+tt.path+" HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"); err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			raw, err := io.ReadAll(conn)
+			if err != nil {
+				t.Fatalf("Failed to read response: %v", err)
+			}
+
+			head, body, found := strings.Cut(string(raw), "\r\n\r\n")
+			if !found {
+				t.Fatalf("Expected a complete response, got %q", raw)
+			}
+			if !strings.HasPrefix(head, "HTTP/1.1 "+tt.status) {
+				t.Errorf("Expected status %s, got %q", tt.status, head)
+			}
+			if body != "" {
+				t.Errorf("Expected nothing after the headers, got %q", body)
+			}
+			if strings.Contains(head, "Content-Length: 4") {
+				t.Errorf("Expected no Content-Length for a null body, got %q", head)
+			}
+		})
+	}
+}
+
+// TestReservedRoutes tests that the health route is answered by the health
+// handler, not the mock catch-all, while its other methods and neighbouring
+// paths still reach the catch-all
+func TestReservedRoutes(t *testing.T) {
+	var mu sync.Mutex
+	var upstreamPaths []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		upstreamPaths = append(upstreamPaths, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer upstream.Close()
+
+	cfg := createTestConfig()
+	cfg.Global.ProxyConfig.Target = upstream.URL
+	// Inactive mocks leave their requests to the catch-all, which proxies them
+	cfg.Mocks = map[string]config.FeatureConfig{
+		"health": {
+			Feature: "health",
+			Endpoints: []config.Endpoint{
+				{ID: "probe", Method: "GET", Path: "/healthz", DefaultResponse: "down",
+					Responses: map[string]config.Response{"down": {Status: 503}}},
+			},
+		},
+	}
+	srv := startServer(t, cfg)
+
+	// A redirect to the health route would be a routing bug, so don't follow one
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	do := func(method, path string) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, "http://"+srv.GetAddress()+path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	proxied := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		paths := upstreamPaths
+		upstreamPaths = nil
+		return paths
+	}
+
+	for _, method := range []string{"GET", "HEAD"} {
+		if status := do(method, "/healthz"); status != http.StatusOK {
+			t.Errorf("Expected %s /healthz to be answered with 200, got %d", method, status)
+		}
+	}
+	if paths := proxied(); len(paths) != 0 {
+		t.Errorf("Expected the health handler to answer, but the catch-all proxied %v", paths)
+	}
+
+	for _, request := range []string{"POST /healthz", "GET /healthz/", "GET /healthz/extra"} {
+		method, path, _ := strings.Cut(request, " ")
+		if status := do(method, path); status != http.StatusTeapot {
+			t.Errorf("Expected %s to reach the catch-all, got %d", request, status)
+		}
+	}
+	if paths := strings.Join(proxied(), ","); paths != "POST /healthz,GET /healthz/,GET /healthz/extra" {
+		t.Errorf("Expected the catch-all to proxy the other requests, got %s", paths)
+	}
+
+	// Moving the health route frees its old path for the catch-all
+	cfg.Global.HealthPath = "/_health"
+	if status := do("GET", "/healthz"); status != http.StatusTeapot {
+		t.Errorf("Expected the old health path to reach the catch-all, got %d", status)
+	}
+	if status := do("GET", "/_health"); status != http.StatusOK {
+		t.Errorf("Expected the new health path to be answered with 200, got %d", status)
+	}
+}
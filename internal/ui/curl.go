@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"swoozeki/climock/internal/config"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// curlCommand builds a curl command line that calls endpoint on the server
+// at address, a host:port or, for a Unix socket, the socket's path. Path
+// parameters such as :id are left in place for the user to fill in.
+func curlCommand(endpoint *config.Endpoint, address string) string {
+	parts := []string{"curl"}
+	baseURL := "http://" + address
+	if strings.HasPrefix(address, "/") {
+		parts = append(parts, "--unix-socket", shellQuote(address))
+		baseURL = "http://localhost"
+	}
+
+	method := strings.ToUpper(endpoint.Method)
+	parts = append(parts, "-X", method, shellQuote(baseURL+endpoint.Path))
+
+	if method == "POST" || method == "PUT" {
+		parts = append(parts,
+			"-H", shellQuote("Content-Type: application/json"),
+			"-d", shellQuote(sampleRequestBody(endpoint)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// sampleRequestBody returns a JSON body to send to endpoint: the example in
+// its request schema if it has one, otherwise an empty object
+func sampleRequestBody(endpoint *config.Endpoint) string {
+	schema, ok := endpoint.RequestSchema.(map[string]interface{})
+	if !ok {
+		return "{}"
+	}
+
+	example, ok := schema["example"]
+	if examples, isList := schema["examples"].([]interface{}); isList && len(examples) > 0 {
+		example, ok = examples[0], true
+	}
+	if !ok {
+		return "{}"
+	}
+
+	data, err := json.Marshal(example)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// copyCurl copies a curl command for the selected endpoint to the clipboard
+func (m *Model) copyCurl() tea.Cmd {
+	return func() tea.Msg {
+		item, ok := m.endpointsList.SelectedItem().(endpointItem)
+		if !ok {
+			return nil
+		}
+
+		endpoint, err := m.Config.GetEndpoint(m.selectedFeature, item.id)
+		if err != nil {
+			return err
+		}
+
+		// The display address falls back to the configured host and port
+		// while the server is stopped
+		command := curlCommand(endpoint, m.Server.GetDisplayAddress())
+		if err := clipboard.WriteAll(command); err != nil {
+			return fmt.Errorf("failed to copy to the clipboard: %v", err)
+		}
+
+		m.statusMessage = "Copied: " + command
+		return customUpdateMsg{
+			action: "curl_copied",
+			id:     item.id,
+		}
+	}
+}
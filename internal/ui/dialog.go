@@ -2,21 +2,37 @@ package ui
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 
 	"swoozeki/climock/internal/config"
 	"swoozeki/climock/internal/logger"
+	"swoozeki/climock/internal/mock"
+	"swoozeki/climock/internal/proxy"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// dialogWarning is returned by a dialog's validate function for input that
+// can still be confirmed. The function decides when to let it through, e.g.
+// on the next Enter.
+type dialogWarning string
+
+// Error implements the error interface
+func (w dialogWarning) Error() string {
+	return string(w)
+}
+
 // showNewFeatureDialog shows the new feature dialog
 func (m *Model) showNewFeatureDialog() {
 	// Clear any existing dialog state
 	m.textInputs = nil
 	m.dialogConfirmFn = nil
 	m.dialogCancelFn = nil
+	m.dialogValidateFn = nil
+	m.dialogError = ""
 	
 	// Set dialog properties
 	m.activeDialog = NewFeatureDialog
@@ -35,21 +51,27 @@ func (m *Model) showNewFeatureDialog() {
 	
 	// No need to capture the value here, we'll get it directly from m.textInputs when needed
 	
+	// Reject names that can't be created before the dialog closes
+	m.dialogValidateFn = func() error {
+		featureName := strings.TrimSpace(m.textInputs[0].Value())
+		if featureName == "" {
+			return fmt.Errorf("feature name cannot be empty")
+		}
+		if _, exists := m.Config.GetFeature(featureName); exists {
+			return fmt.Errorf("feature %s already exists", featureName)
+		}
+		return nil
+	}
+	
 	// Set the confirm function - this will be called when Enter is pressed
 	m.dialogConfirmFn = func() tea.Cmd {
 		// Capture the feature name value now, before text inputs are cleared
 		var featureName string
 		if len(m.textInputs) > 0 {
-			featureName = m.textInputs[0].Value()
+			featureName = strings.TrimSpace(m.textInputs[0].Value())
 		}
 		
 		return func() tea.Msg {
-			
-			if featureName == "" {
-				fmt.Println("Error: feature name cannot be empty")
-				return fmt.Errorf("feature name cannot be empty")
-			}
-			
 			// Create the feature config
 			feature := config.FeatureConfig{
 				Feature:   featureName,
@@ -58,8 +80,7 @@ func (m *Model) showNewFeatureDialog() {
 			
 			// Create the feature using the mock manager
 			if err := m.MockManager.CreateFeature(feature); err != nil {
-				errMsg := fmt.Sprintf("Failed to create feature: %v", err)
-				fmt.Println(errMsg)
+				logger.Error("Failed to create feature: %v", err)
 				return fmt.Errorf("Failed to create feature: %v", err)
 			}
 			
@@ -80,7 +101,7 @@ func (m *Model) showNewFeatureDialog() {
 			// Reload the server if it's running
 			if m.Server.IsRunning() {
 				if err := m.Server.Reload(); err != nil {
-					fmt.Printf("Error reloading server: %v\n", err)
+					logger.Error("Failed to reload server: %v", err)
 					return fmt.Errorf("failed to reload server: %v", err)
 				}
 			}
@@ -111,6 +132,8 @@ func (m *Model) showNewEndpointDialog() {
 	m.textInputs = nil
 	m.dialogConfirmFn = nil
 	m.dialogCancelFn = nil
+	m.dialogValidateFn = nil
+	m.dialogError = ""
 	
 	// Set dialog properties
 	m.activeDialog = NewEndpointDialog
@@ -137,6 +160,29 @@ func (m *Model) showNewEndpointDialog() {
 	// Store the text inputs in the model
 	m.textInputs = []textinput.Model{idInput, methodInput, pathInput}
 	
+	// Check the fields with the same rules used to build the endpoint, then
+	// warn once about routes that other endpoints already answer
+	acknowledged := ""
+	m.dialogValidateFn = func() error {
+		endpoint, err := mock.NewEndpoint(
+			strings.TrimSpace(m.textInputs[0].Value()),
+			strings.TrimSpace(m.textInputs[1].Value()),
+			strings.TrimSpace(m.textInputs[2].Value()),
+			200)
+		if err != nil {
+			return err
+		}
+		
+		conflicts := m.MockManager.FindConflicts(m.selectedFeature, endpoint)
+		route := endpoint.Method + " " + endpoint.Path
+		if len(conflicts) == 0 || acknowledged == route {
+			return nil
+		}
+		acknowledged = route
+		return dialogWarning(fmt.Sprintf("%s is already handled by %s; press Enter again to create it anyway, or Esc to cancel",
+			route, strings.Join(conflicts, ", ")))
+	}
+	
 	// Set the confirm function - this will be called when Enter is pressed
 	m.dialogConfirmFn = func() tea.Cmd {
 		// Capture the input values now, before text inputs are cleared
@@ -150,57 +196,16 @@ func (m *Model) showNewEndpointDialog() {
 		return func() tea.Msg {
 			// Process endpoint creation
 			
-			// Validate inputs
-			if id == "" || method == "" || path == "" {
-				fmt.Println("Error: all fields are required")
-				return fmt.Errorf("all fields are required")
-			}
-			
-			// Validate ID (alphanumeric and hyphens only)
-			for _, c := range id {
-				if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '_') {
-					fmt.Println("Error: endpoint ID can only contain letters, numbers, hyphens, and underscores")
-					return fmt.Errorf("endpoint ID can only contain letters, numbers, hyphens, and underscores")
-				}
-			}
-			
-			// Validate method
-			method = strings.ToUpper(method)
-			if method != "GET" && method != "POST" && method != "PUT" && method != "DELETE" && method != "PATCH" && method != "OPTIONS" && method != "HEAD" {
-				fmt.Printf("Error: invalid HTTP method: %s\n", method)
-				return fmt.Errorf("invalid HTTP method: %s", method)
-			}
-			
-			// Validate path (must start with /)
-			if !strings.HasPrefix(path, "/") {
-				path = "/" + path
-			}
-			
-			// Create a basic endpoint with a default response
-			endpoint := config.Endpoint{
-				ID:              id,
-				Method:          method,
-				Path:            path,
-				Active:          true,
-				DefaultResponse: "default",
-				Responses: map[string]config.Response{
-					"default": {
-						Status: 200,
-						Headers: map[string]string{
-							"Content-Type": "application/json",
-						},
-						Body: map[string]interface{}{
-							"message": "This is a default response",
-						},
-						Delay: 0,
-					},
-				},
+			// Validate inputs and build a basic endpoint with a default response
+			endpoint, err := mock.NewEndpoint(id, method, path, 200)
+			if err != nil {
+				logger.Error("Invalid endpoint: %v", err)
+				return err
 			}
 			
 			// Create the endpoint using the mock manager
 			if err := m.MockManager.CreateEndpoint(m.selectedFeature, endpoint); err != nil {
-				errMsg := fmt.Sprintf("Failed to create endpoint: %v", err)
-				fmt.Println(errMsg)
+				logger.Error("Failed to create endpoint: %v", err)
 				return fmt.Errorf("Failed to create endpoint: %v", err)
 			}
 			
@@ -218,7 +223,7 @@ func (m *Model) showNewEndpointDialog() {
 			// Reload the server if it's running
 			if m.Server.IsRunning() {
 				if err := m.Server.Reload(); err != nil {
-					fmt.Printf("Error reloading server: %v\n", err)
+					logger.Error("Failed to reload server: %v", err)
 					return fmt.Errorf("failed to reload server: %v", err)
 				}
 			}
@@ -281,6 +286,8 @@ func (m *Model) showDeleteConfirmDialog() {
 	m.textInputs = nil
 	m.dialogConfirmFn = nil
 	m.dialogCancelFn = nil
+	m.dialogValidateFn = nil
+	m.dialogError = ""
 	
 	// Set dialog properties
 	m.activeDialog = DeleteConfirmDialog
@@ -312,6 +319,8 @@ func (m *Model) showProxyConfigDialog() {
 	m.textInputs = nil
 	m.dialogConfirmFn = nil
 	m.dialogCancelFn = nil
+	m.dialogValidateFn = nil
+	m.dialogError = ""
 	
 	// Set dialog properties
 	m.activeDialog = ProxyConfigDialog
@@ -333,26 +342,39 @@ func (m *Model) showProxyConfigDialog() {
 	
 	m.textInputs = []textinput.Model{targetInput}
 	
+	// Offer recent targets with up/down, starting from the current one
+	m.dialogOptions = nil
+	m.dialogCursor = 0
+	if currentTarget != "" {
+		m.dialogOptions = append(m.dialogOptions, currentTarget)
+	}
+	for _, previous := range m.Config.Global.ProxyHistory {
+		if previous != currentTarget {
+			m.dialogOptions = append(m.dialogOptions, previous)
+		}
+	}
+	
+	// Reject targets that can't be proxied to before the dialog closes
+	m.dialogValidateFn = func() error {
+		_, err := proxy.NormalizeTarget(m.textInputs[0].Value())
+		return err
+	}
+	
 	// Store the target input for use in the confirm function
 	m.dialogConfirmFn = func() tea.Cmd {
 		// Capture the target value immediately, before the text inputs are cleared
 		var target string
 		if len(m.textInputs) > 0 {
-			target = strings.TrimSpace(m.textInputs[0].Value())
+			target = m.textInputs[0].Value()
 		}
 		
 		// Return a function that will be executed after the dialog is closed
 		return func() tea.Msg {
-			// Safety check for target value
-			if target == "" {
-				logger.Error("Proxy target cannot be empty")
-				return fmt.Errorf("proxy target cannot be empty")
-			}
-			
-			// Validate URL format
-			if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
-				logger.Error("Proxy target must start with http:// or https://")
-				return fmt.Errorf("proxy target must start with http:// or https://")
+			// Add a missing scheme and check the URL format
+			target, err := proxy.NormalizeTarget(target)
+			if err != nil {
+				logger.Error("Invalid proxy target: %v", err)
+				return err
 			}
 			
 			// Update the proxy manager - this will handle updating the config and saving it
@@ -361,7 +383,7 @@ func (m *Model) showProxyConfigDialog() {
 				return fmt.Errorf("failed to update proxy target: %v", err)
 			}
 			
-			return nil
+			return customUpdateMsg{action: "proxy_updated", name: target}
 		}
 	}
 	
@@ -374,6 +396,162 @@ func (m *Model) showProxyConfigDialog() {
 }
 
 
+// showPathRewriteDialog shows the dialog for editing the proxy's path
+// rewrite rules, one pattern=replacement rule per input
+func (m *Model) showPathRewriteDialog() {
+	// Clear any existing dialog state
+	m.textInputs = nil
+	m.dialogConfirmFn = nil
+	m.dialogCancelFn = nil
+	m.dialogValidateFn = nil
+	m.dialogError = ""
+	
+	// Set dialog properties
+	m.activeDialog = PathRewriteDialog
+	m.dialogTitle = "Path Rewrite Rules"
+	m.dialogContent = ""
+	
+	// One input per existing rule, in a stable order, plus an empty one for a new rule
+	rules := m.ProxyManager.GetPathRewrite()
+	patterns := make([]string, 0, len(rules))
+	for pattern := range rules {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	
+	for _, pattern := range append(patterns, "") {
+		ruleInput := textinput.New()
+		ruleInput.Placeholder = "pattern=replacement (e.g., ^/api=/v1); clear to remove"
+		ruleInput.CharLimit = 200
+		ruleInput.Width = 60
+		if pattern != "" {
+			ruleInput.SetValue(pattern + "=" + rules[pattern])
+		}
+		m.textInputs = append(m.textInputs, ruleInput)
+	}
+	m.textInputs[0].Focus()
+	
+	// Point out malformed rules and bad patterns before saving anything
+	m.dialogValidateFn = func() error {
+		lines := make([]string, len(m.textInputs))
+		for i, ti := range m.textInputs {
+			lines[i] = ti.Value()
+		}
+		_, err := parsePathRewrite(lines)
+		return err
+	}
+	
+	m.dialogConfirmFn = func() tea.Cmd {
+		// Capture the rules before the text inputs are cleared
+		lines := make([]string, len(m.textInputs))
+		for i, ti := range m.textInputs {
+			lines[i] = ti.Value()
+		}
+		
+		return func() tea.Msg {
+			pathRewrite, err := parsePathRewrite(lines)
+			if err != nil {
+				logger.Error("Invalid path rewrite rules: %v", err)
+				return err
+			}
+			
+			// UpdatePathRewrite checks that every pattern compiles before saving
+			if err := m.ProxyManager.UpdatePathRewrite(pathRewrite); err != nil {
+				logger.Error("Failed to update path rewrite rules: %v", err)
+				return fmt.Errorf("failed to update path rewrite rules: %v", err)
+			}
+			
+			m.statusMessage = fmt.Sprintf("Saved %d path rewrite rule(s)", len(pathRewrite))
+			return customUpdateMsg{action: "path_rewrite_updated"}
+		}
+	}
+	
+	m.dialogCancelFn = func() tea.Cmd {
+		return func() tea.Msg {
+			logger.LogDebug("Path rewrite editing cancelled")
+			return nil
+		}
+	}
+}
+
+// parsePathRewrite turns pattern=replacement lines into a path rewrite map.
+// Blank lines are skipped; the pattern ends at the first "=".
+func parsePathRewrite(lines []string) (map[string]string, error) {
+	pathRewrite := make(map[string]string)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		
+		pattern, replacement, ok := strings.Cut(line, "=")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("path rewrite rule %q must be written as pattern=replacement", line)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid path rewrite pattern %q: %v", pattern, err)
+		}
+		pathRewrite[pattern] = replacement
+	}
+	return pathRewrite, nil
+}
+
+// showScenarioDialog shows the scenario selection dialog
+func (m *Model) showScenarioDialog() {
+	// Clear any existing dialog state
+	m.textInputs = nil
+	m.dialogConfirmFn = nil
+	m.dialogCancelFn = nil
+	m.dialogValidateFn = nil
+	m.dialogError = ""
+	
+	// Set dialog properties
+	m.activeDialog = ScenarioDialog
+	m.dialogTitle = "Apply Scenario"
+	m.dialogContent = ""
+	m.dialogOptions = m.MockManager.ScenarioNames()
+	m.dialogCursor = 0
+	
+	if len(m.dialogOptions) == 0 {
+		m.dialogContent = "No scenarios defined. Add a \"scenarios\" section to config.json."
+		return
+	}
+	
+	m.dialogConfirmFn = func() tea.Cmd {
+		// Capture the selected scenario before the dialog state is cleared
+		name := m.dialogOptions[m.dialogCursor]
+		
+		return func() tea.Msg {
+			if err := m.MockManager.ApplyScenario(name); err != nil {
+				logger.Error("Failed to apply scenario %s: %v", name, err)
+				return fmt.Errorf("failed to apply scenario %s: %v", name, err)
+			}
+			
+			m.updateEndpointsList()
+			
+			// Reload the server if it's running
+			if m.Server.IsRunning() {
+				if err := m.Server.Reload(); err != nil {
+					return fmt.Errorf("failed to reload server: %v", err)
+				}
+			}
+			
+			// Return a custom message for smoother UI updates
+			return customUpdateMsg{
+				action: "scenario_applied",
+				name:   name,
+			}
+		}
+	}
+	
+	m.dialogCancelFn = func() tea.Cmd {
+		return func() tea.Msg {
+			logger.LogDebug("Scenario selection cancelled")
+			return nil
+		}
+	}
+}
+
 // deleteFeature deletes the selected feature
 func (m *Model) deleteFeature() tea.Msg {
 	item, ok := m.featuresList.SelectedItem().(featureItem)
@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"fmt"
+	"os/exec"
+
+	"swoozeki/climock/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// EndpointUpdatedMsg returns the message sent after an endpoint changes, for tests
+func EndpointUpdatedMsg(id string) tea.Msg {
+	return customUpdateMsg{action: "endpoint_updated", id: id}
+}
+
+// SelectedFeature returns the name of the selected feature for tests
+func (m *Model) SelectedFeature() string {
+	return m.selectedFeature
+}
+
+// SelectedEndpointID returns the ID of the selected endpoint for tests
+func (m *Model) SelectedEndpointID() string {
+	if item, ok := m.endpointsList.SelectedItem().(endpointItem); ok {
+		return item.id
+	}
+	return ""
+}
+
+// EndpointIDs returns the endpoint IDs in display order for tests
+func (m *Model) EndpointIDs() []string {
+	var ids []string
+	for _, item := range m.endpointsList.Items() {
+		if ei, ok := item.(endpointItem); ok {
+			ids = append(ids, ei.id)
+		}
+	}
+	return ids
+}
+
+// MethodColor exposes methodColor for tests
+func MethodColor(method string) string {
+	return string(darkPalette.methodColor(method))
+}
+
+// EndpointDescription renders the description of an endpoint item for tests
+func EndpointDescription(responses []string, defaultResponse string, width int) string {
+	return endpointItem{
+		responses:       responses,
+		defaultResponse: defaultResponse,
+		width:           width,
+	}.Description()
+}
+
+// TruncateToWidth exposes truncateToWidth for tests
+func TruncateToWidth(s string, width int) string {
+	return truncateToWidth(s, width)
+}
+
+// FindEndpointLineNumber exposes findEndpointLineNumber for tests
+func FindEndpointLineNumber(filePath, endpointID, responseName string) int {
+	return findEndpointLineNumber(filePath, endpointID, responseName)
+}
+
+// EditorCommand exposes editorCommand for tests
+func (m *Model) EditorCommand() (*exec.Cmd, error) {
+	return m.editorCommand()
+}
+
+// CurlCommand exposes curlCommand for tests
+func CurlCommand(endpoint *config.Endpoint, address string) string {
+	return curlCommand(endpoint, address)
+}
+
+// ActivePanel returns the focused panel for tests
+func (m *Model) ActivePanel() Panel {
+	return m.activePanel
+}
+
+// EndpointSortMode returns the endpoints list order for tests
+func (m *Model) EndpointSortMode() EndpointSort {
+	return m.endpointSort
+}
+
+// FeaturesWidth returns the features panel's share of the width for tests
+func (m *Model) FeaturesWidth() int {
+	return m.featuresPercent
+}
+
+// HeaderBorderColor returns the header's border color for tests
+func (m *Model) HeaderBorderColor() string {
+	return fmt.Sprint(m.styles.header.GetBorderTopForeground())
+}
+
+// FeatureTitleColor returns the features panel title's border color for tests
+func (m *Model) FeatureTitleColor() string {
+	return fmt.Sprint(m.styles.featureTitle.GetBorderTopForeground())
+}
+
+// HelpScrollOffset returns how far the help dialog is scrolled for tests
+func (m *Model) HelpScrollOffset() int {
+	return m.helpViewport.YOffset
+}
@@ -2,14 +2,20 @@ package ui
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 // featureItem represents a feature in the features list
 type featureItem struct {
-	name string
+	name     string
+	disabled bool
+	active   int // Number of active endpoints
+	total    int // Number of endpoints
+	palette  palette
 }
 
 // endpointItem represents an endpoint in the endpoints list
@@ -20,6 +26,69 @@ type endpointItem struct {
 	active          bool
 	defaultResponse string
 	responses       []string
+	description     string
+	width           int // Maximum description width, 0 for unlimited
+	palette         palette
+}
+
+// EndpointSort represents the display order of the endpoints list
+type EndpointSort int
+
+const (
+	SortByFile EndpointSort = iota
+	SortByPath
+	SortByMethod
+	SortByActive
+)
+
+// String returns a short label for the sort order
+func (s EndpointSort) String() string {
+	switch s {
+	case SortByPath:
+		return "path"
+	case SortByMethod:
+		return "method"
+	case SortByActive:
+		return "active"
+	default:
+		return "file"
+	}
+}
+
+// Next returns the sort order that follows s, wrapping around to file order
+func (s EndpointSort) Next() EndpointSort {
+	return (s + 1) % (SortByActive + 1)
+}
+
+// sortEndpointItems sorts endpoint items in place for display
+func sortEndpointItems(items []endpointItem, mode EndpointSort) {
+	byPath := func(a, b endpointItem) bool {
+		if a.path != b.path {
+			return a.path < b.path
+		}
+		return a.method < b.method
+	}
+
+	switch mode {
+	case SortByPath:
+		sort.SliceStable(items, func(i, j int) bool {
+			return byPath(items[i], items[j])
+		})
+	case SortByMethod:
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].method != items[j].method {
+				return items[i].method < items[j].method
+			}
+			return items[i].path < items[j].path
+		})
+	case SortByActive:
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].active != items[j].active {
+				return items[i].active
+			}
+			return byPath(items[i], items[j])
+		})
+	}
 }
 
 // FilterValue implements the list.Item interface
@@ -27,9 +96,16 @@ func (i featureItem) FilterValue() string {
 	return i.name
 }
 
-// Title returns the title of the feature item
+// Title returns the title of the feature item with its active endpoint
+// count, dimmed if the feature is disabled
 func (i featureItem) Title() string {
-	return i.name
+	title := fmt.Sprintf("%s (%d/%d active)", i.name, i.active, i.total)
+	if i.disabled {
+		return lipgloss.NewStyle().
+			Foreground(i.palette.muted).
+			Render(title + " (off)")
+	}
+	return title
 }
 
 // Description returns the description of the feature item
@@ -47,6 +123,9 @@ func (i endpointItem) Title() string {
 	methodStyle := lipgloss.NewStyle().
 		Width(7).
 		Align(lipgloss.Left)
+	if color := i.palette.methodColor(i.method); color != "" {
+		methodStyle = methodStyle.Foreground(color)
+	}
 
 	// Use emojis for active/inactive status
 	active := "🟢"
@@ -65,13 +144,27 @@ func (i endpointItem) Description() string {
 	var responses []string
 	for _, r := range i.responses {
 		if r == i.defaultResponse {
-			// Make default response more obvious with * symbol
-			responses = append(responses, "*"+r)
+			// Make default response more obvious with ★ symbol
+			responses = append(responses, "★"+r)
 		} else {
 			responses = append(responses, r)
 		}
 	}
 	
-	// Make the description more compact
-	return fmt.Sprintf("[%s]", strings.Join(responses, "|"))
-}
\ No newline at end of file
+	// Show how many variants exist ahead of the names
+	description := fmt.Sprintf("(%d) [%s]", len(responses), strings.Join(responses, " | "))
+	if i.description != "" {
+		description += " · " + i.description
+	}
+	return truncateToWidth(description, i.width)
+}
+
+// truncateToWidth shortens s to fit within width terminal cells, adding an
+// ellipsis when it is cut. Truncation is ANSI-aware so escape sequences are
+// never split. A width of zero or less leaves s untouched.
+func truncateToWidth(s string, width int) string {
+	if width <= 0 || ansi.StringWidth(s) <= width {
+		return s
+	}
+	return ansi.Truncate(s, width, "…")
+}
@@ -22,29 +22,45 @@ const (
 	NewEndpointDialog
 	DeleteConfirmDialog
 	ProxyConfigDialog
+	ScenarioDialog
+	PathRewriteDialog
+	SettingsDialog
+	RestartConfirmDialog
 )
 
 // KeyMap defines the keybindings for the UI
 type KeyMap struct {
-	Up           key.Binding
-	Down         key.Binding
-	Left         key.Binding
-	Right        key.Binding
-	Tab          key.Binding
-	Enter        key.Binding
-	Toggle       key.Binding
-	Response     key.Binding
-	Open         key.Binding
-	New          key.Binding
-	Delete       key.Binding
-	Proxy        key.Binding
-	Server       key.Binding
-	Quit         key.Binding
-	Help         key.Binding
-	Search       key.Binding
-	Reload       key.Binding
-	Escape       key.Binding
-	Confirm      key.Binding
+	Up             key.Binding
+	Down           key.Binding
+	Left           key.Binding
+	Right          key.Binding
+	Tab            key.Binding
+	Enter          key.Binding
+	Toggle         key.Binding
+	ToggleAll      key.Binding
+	Response       key.Binding
+	Sort           key.Binding
+	Open           key.Binding
+	New            key.Binding
+	Delete         key.Binding
+	Proxy          key.Binding
+	PathRewrite    key.Binding
+	ChangeOrigin   key.Binding
+	Trace          key.Binding
+	Server         key.Binding
+	Quit           key.Binding
+	Help           key.Binding
+	Search         key.Binding
+	Reload         key.Binding
+	Scenario       key.Binding
+	Profile        key.Binding
+	TagFilter      key.Binding
+	Curl           key.Binding
+	Settings       key.Binding
+	NarrowFeatures key.Binding
+	WidenFeatures  key.Binding
+	Escape         key.Binding
+	Confirm        key.Binding
 }
 
 // DefaultKeyMap returns the default keybindings
@@ -78,10 +94,18 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("t"),
 			key.WithHelp("t", "toggle endpoint"),
 		),
+		ToggleAll: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "toggle all endpoints"),
+		),
 		Response: key.NewBinding(
 			key.WithKeys("r"),
 			key.WithHelp("r", "cycle response"),
 		),
+		Sort: key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", "sort endpoints"),
+		),
 		Open: key.NewBinding(
 			key.WithKeys("o"),
 			key.WithHelp("o", "open in editor"),
@@ -98,6 +122,18 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("p"),
 			key.WithHelp("p", "proxy config"),
 		),
+		PathRewrite: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "path rewrite"),
+		),
+		ChangeOrigin: key.NewBinding(
+			key.WithKeys("O"),
+			key.WithHelp("O", "toggle changeOrigin"),
+		),
+		Trace: key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", "toggle request tracing"),
+		),
 		Server: key.NewBinding(
 			key.WithKeys("s"),
 			key.WithHelp("s", "start/stop server"),
@@ -118,6 +154,34 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("ctrl+r"),
 			key.WithHelp("ctrl+r", "reload"),
 		),
+		Scenario: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "apply scenario"),
+		),
+		Profile: key.NewBinding(
+			key.WithKeys("P"),
+			key.WithHelp("P", "cycle response profile"),
+		),
+		TagFilter: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "filter endpoints by tag"),
+		),
+		Curl: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "copy curl command"),
+		),
+		Settings: key.NewBinding(
+			key.WithKeys(","),
+			key.WithHelp(",", "server settings"),
+		),
+		NarrowFeatures: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", "narrow features panel"),
+		),
+		WidenFeatures: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", "widen features panel"),
+		),
 		Escape: key.NewBinding(
 			key.WithKeys("esc"),
 			key.WithHelp("esc", "cancel"),
@@ -193,7 +257,7 @@ func (pk PanelKeyMap) FullHelp() [][]key.Binding {
 func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.Left, k.Right, k.Tab, k.Enter},
-		{k.Toggle, k.Response, k.Open, k.New, k.Delete},
-		{k.Proxy, k.Server, k.Quit, k.Help, k.Search, k.Reload},
+		{k.Toggle, k.Response, k.Sort, k.TagFilter, k.Curl, k.Open, k.New, k.Delete},
+		{k.Proxy, k.PathRewrite, k.ChangeOrigin, k.Trace, k.Server, k.Settings, k.Scenario, k.Profile, k.NarrowFeatures, k.WidenFeatures, k.Quit, k.Help, k.Search, k.Reload},
 	}
 }
\ No newline at end of file
@@ -1,12 +1,14 @@
 package ui
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
+	"runtime"
 	"sort"
 	"strings"
-	"time"
 
 	"swoozeki/climock/internal/config"
 	"swoozeki/climock/internal/logger"
@@ -18,6 +20,7 @@ import (
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"golang.org/x/term"
@@ -35,10 +38,23 @@ type Model struct {
 	featuresList    list.Model
 	endpointsList   list.Model
 	selectedFeature string
+	listedFeature   string          // feature whose endpoints are shown in endpointsList
+	endpointSort    EndpointSort
+	tagFilter       string          // only endpoints with this tag are listed, if set
+	featuresPercent int             // features panel's share of the window width
+	preferences     config.UIConfig // preferences as last loaded or saved
+	theme           string          // theme name from the preferences
+	palette         palette
+	statusMessage   string          // shown in the header until the next key press
+	serverError     string          // why the server last failed to start, shown until it starts
 	width           int
 	height          int
 	keyMap          KeyMap
 	help            help.Model
+	helpViewport    viewport.Model  // scrolls the help dialog
+	// Screen rows taken up by each list item, including spacing
+	featuresRowHeight  int
+	endpointsRowHeight int
 	
 	// Dialog state
 	activeDialog    DialogType
@@ -47,10 +63,18 @@ type Model struct {
 	dialogContent   string
 	dialogConfirmFn func() tea.Cmd
 	dialogCancelFn  func() tea.Cmd
+	dialogOptions   []string
+	dialogCursor    int
+	// dialogValidateFn checks the dialog's input before it is confirmed. When
+	// it fails, the dialog stays open and shows dialogError.
+	dialogValidateFn func() error
+	dialogError      string
+	// dialogErrorIsWarning shows dialogError as a warning the user can
+	// confirm past rather than an error
+	dialogErrorIsWarning bool
 	
-	// Performance optimization
-	lastUpdate time.Time
-	styles     struct {
+	// Cached styles, rebuilt when the window size changes
+	styles struct {
 		header         lipgloss.Style
 		featureTitle   lipgloss.Style
 		endpointsTitle lipgloss.Style
@@ -69,6 +93,7 @@ type customUpdateMsg struct {
 	feature  string
 	active   bool
 	response string
+	port     int
 }
 
 // New creates a new UI model
@@ -83,6 +108,8 @@ func New(cfg *config.Config, mockManager *mock.Manager, proxyManager *proxy.Mana
 		ProxyManager: proxyManager,
 		Server:       srv,
 		activePanel:  FeaturesPanel,
+		featuresPercent: defaultFeaturesWidth,
+		palette:      darkPalette,
 		keyMap:       keyMap,
 		help:         helpModel,
 		// Set initial dimensions to reasonable defaults
@@ -95,10 +122,11 @@ func New(cfg *config.Config, mockManager *mock.Manager, proxyManager *proxy.Mana
 		dialogContent: "",
 		dialogConfirmFn: nil,
 		dialogCancelFn:  nil,
-		// Initialize performance optimization
-		lastUpdate: time.Now(),
 	}
 
+	// Restore the layout and sort order from the last session
+	m.loadPreferences()
+	
 	// Initialize cached styles
 	m.initStyles()
 	
@@ -109,8 +137,9 @@ func New(cfg *config.Config, mockManager *mock.Manager, proxyManager *proxy.Mana
 	m.initEndpointsList()
 	
 	// Set initial list dimensions
-	m.featuresList.SetSize(m.width/4, m.height-6)
-	m.endpointsList.SetSize(3*m.width/4, m.height-6)
+	featureWidth, endpointWidth := m.panelWidths(m.width)
+	m.featuresList.SetSize(featureWidth, m.height-6)
+	m.endpointsList.SetSize(endpointWidth, m.height-6)
 	m.help.Width = m.width
 
 	return m
@@ -118,43 +147,45 @@ func New(cfg *config.Config, mockManager *mock.Manager, proxyManager *proxy.Mana
 
 // initStyles initializes cached styles for better performance
 func (m *Model) initStyles() {
+	featureWidth, endpointWidth := m.panelWidths(m.width)
+	
 	// Header style - removed bottom border
 	m.styles.header = lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("63")).
+		BorderForeground(m.palette.accent).
 		BorderBottom(false). // No bottom border
 		Padding(1, 2)
 	
 	// Panel title styles
 	m.styles.featureTitle = lipgloss.NewStyle().
-		Width(m.width/4).
+		Width(featureWidth).
 		Align(lipgloss.Left).
 		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("63")).
+		BorderForeground(m.palette.accent).
 		BorderBottom(true).
 		Padding(0, 1)
 	
 	m.styles.endpointsTitle = lipgloss.NewStyle().
-		Width(3*m.width/4).
+		Width(endpointWidth).
 		Align(lipgloss.Left).
 		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("63")).
+		BorderForeground(m.palette.accent).
 		BorderBottom(true).
 		Padding(0, 1)
 	
 	// List styles
 	m.styles.features = lipgloss.NewStyle().
-		Width(m.width/4).
+		Width(featureWidth).
 		Padding(0, 1)
 	
 	m.styles.endpoints = lipgloss.NewStyle().
-		Width(3*m.width/4).
+		Width(endpointWidth).
 		Padding(0, 1)
 	
 	// Footer style - removed top border
 	m.styles.footer = lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("63")).
+		BorderForeground(m.palette.accent).
 		BorderTop(false). // No top border
 		Padding(0, 2)
 }
@@ -185,8 +216,9 @@ func (m *Model) Init() tea.Cmd {
 				listHeight := height - topHeight - bottomHeight
 				
 				// Adjust widths to account for borders
-				featureWidth := width/4 - 2
-				endpointWidth := 3*width/4 - 2
+				featureWidth, endpointWidth := m.panelWidths(width)
+				featureWidth -= 2
+				endpointWidth -= 2
 				
 				m.featuresList.SetSize(featureWidth, listHeight)
 				m.endpointsList.SetSize(endpointWidth, listHeight)
@@ -232,12 +264,12 @@ func (m *Model) initFeaturesList() {
 	// Create a compact delegate with no description
 	compactDelegate := m.createCompactDelegate(false)
 	
-	items := []list.Item{}
+	// Remember the current selection so it survives the rebuild
+	previousFeature := m.selectedFeature
 	
-	// Add features from config
-	for feature := range m.Config.Mocks {
-		items = append(items, featureItem{name: feature})
-	}
+	// Feature names are sorted so the list order is stable across rebuilds
+	names := m.Config.FeatureNames()
+	items := m.createFeatureItems(names)
 	
 	// Create the list with proper dimensions
 	listHeight := m.height - 6 // Account for header and footer
@@ -246,7 +278,8 @@ func (m *Model) initFeaturesList() {
 	}
 	
 	// Adjust width to account for borders
-	featureWidth := m.width/4 - 2
+	featureWidth, _ := m.panelWidths(m.width)
+	featureWidth -= 2
 	
 	m.featuresList = list.New(items, compactDelegate, featureWidth, listHeight)
 	m.featuresList.Title = "Features"
@@ -257,10 +290,17 @@ func (m *Model) initFeaturesList() {
 	// Update delegates based on active panel
 	m.updateListDelegatesForActivePanel()
 	
-	// Select the first feature if available
+	// Reselect the previous feature, falling back to the first one
 	if len(items) > 0 {
-		m.featuresList.Select(0) // Explicitly select the first item
-		if fi, ok := items[0].(featureItem); ok {
+		selectedIndex := 0
+		for i, name := range names {
+			if name == previousFeature {
+				selectedIndex = i
+				break
+			}
+		}
+		m.featuresList.Select(selectedIndex)
+		if fi, ok := items[selectedIndex].(featureItem); ok {
 			m.selectedFeature = fi.name
 		}
 	} else {
@@ -269,38 +309,95 @@ func (m *Model) initFeaturesList() {
 	}
 }
 
+// createFeatureItems creates feature items for the list, in the order of names
+func (m *Model) createFeatureItems(names []string) []list.Item {
+	items := []list.Item{}
+	for _, feature := range names {
+		featureConfig, _ := m.Config.GetFeature(feature)
+		item := featureItem{
+			name:     feature,
+			disabled: !featureConfig.IsEnabled(),
+			total:    len(featureConfig.Endpoints),
+			palette:  m.palette,
+		}
+		for _, endpoint := range featureConfig.Endpoints {
+			if endpoint.Active {
+				item.active++
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// updateFeatureCounts refreshes the endpoint counts shown in the features
+// list without rebuilding it
+func (m *Model) updateFeatureCounts() {
+	m.featuresList.SetItems(m.createFeatureItems(m.Config.FeatureNames()))
+}
+
 // createEndpointItems creates endpoint items for the list
 func (m *Model) createEndpointItems() []list.Item {
-	items := []list.Item{}
+	endpoints := []endpointItem{}
 	
 	// Add endpoints from selected feature
 	if m.selectedFeature != "" {
-		if featureConfig, ok := m.Config.Mocks[m.selectedFeature]; ok {
+		if featureConfig, ok := m.Config.GetFeature(m.selectedFeature); ok {
 			for _, endpoint := range featureConfig.Endpoints {
-				// Get all response names and sort them alphabetically for consistent order
-				var allResponses []string
-				for name := range endpoint.Responses {
-					allResponses = append(allResponses, name)
+				if m.tagFilter != "" && !endpoint.HasTag(m.tagFilter) {
+					continue
 				}
-				
-				// Sort responses alphabetically using Go's built-in sort package
-				sort.Strings(allResponses)
-				
-				items = append(items, endpointItem{
-					id:              endpoint.ID,
-					method:          endpoint.Method,
-					path:            endpoint.Path,
-					active:          endpoint.Active,
-					defaultResponse: endpoint.DefaultResponse,
-					responses:       allResponses,
-				})
+				endpoints = append(endpoints, m.newEndpointItem(endpoint))
 			}
 		}
 	}
 	
+	// Sort for display only; the on-disk order is left untouched
+	sortEndpointItems(endpoints, m.endpointSort)
+	
+	items := make([]list.Item, len(endpoints))
+	for i, endpoint := range endpoints {
+		items[i] = endpoint
+	}
+	
 	return items
 }
 
+// newEndpointItem creates a list item for an endpoint, sized to the endpoints panel
+func (m *Model) newEndpointItem(endpoint config.Endpoint) endpointItem {
+	// Get all response names and sort them alphabetically for consistent order
+	var allResponses []string
+	for name := range endpoint.Responses {
+		allResponses = append(allResponses, name)
+	}
+	sort.Strings(allResponses)
+	
+	return endpointItem{
+		id:              endpoint.ID,
+		method:          endpoint.Method,
+		path:            endpoint.Path,
+		active:          endpoint.Active,
+		defaultResponse: endpoint.DefaultResponse,
+		responses:       allResponses,
+		description:     endpoint.Description,
+		// Leave room for the delegate's left padding
+		width:           m.endpointsList.Width() - 2,
+		palette:         m.palette,
+	}
+}
+
+// endpointsTitle returns the endpoints panel title for the selected feature
+func (m *Model) endpointsTitle() string {
+	title := fmt.Sprintf("Endpoints (%s)", m.selectedFeature)
+	if m.endpointSort != SortByFile {
+		title += " by " + m.endpointSort.String()
+	}
+	if m.tagFilter != "" {
+		title += " tagged " + m.tagFilter
+	}
+	return title
+}
+
 // initEndpointsList initializes the endpoints list
 func (m *Model) initEndpointsList() {
 	// Create a compact delegate with description
@@ -316,10 +413,12 @@ func (m *Model) initEndpointsList() {
 	}
 	
 	// Adjust width to account for borders
-	endpointWidth := 3*m.width/4 - 2
+	_, endpointWidth := m.panelWidths(m.width)
+	endpointWidth -= 2
 	
 	m.endpointsList = list.New(items, compactDelegate, endpointWidth, listHeight)
-	m.endpointsList.Title = fmt.Sprintf("Endpoints (%s)", m.selectedFeature)
+	m.listedFeature = m.selectedFeature
+	m.endpointsList.Title = m.endpointsTitle()
 	m.endpointsList.SetShowStatusBar(false)
 	m.endpointsList.SetFilteringEnabled(false)
 	m.endpointsList.SetShowHelp(false)
@@ -327,20 +426,31 @@ func (m *Model) initEndpointsList() {
 
 // updateEndpointsList updates the endpoints list based on the selected feature
 func (m *Model) updateEndpointsList() {
-	// Save current selection index
-	currentIndex := m.endpointsList.Index()
+	// Remember the selected endpoint if the list still shows the same feature
+	var previousID string
+	if m.listedFeature == m.selectedFeature {
+		if item, ok := m.endpointsList.SelectedItem().(endpointItem); ok {
+			previousID = item.id
+		}
+	}
 	
 	// Get endpoint items using the shared function
 	items := m.createEndpointItems()
 	
 	// Update just the items, not the entire list
 	m.endpointsList.SetItems(items)
-	m.endpointsList.Title = fmt.Sprintf("Endpoints (%s)", m.selectedFeature)
+	m.listedFeature = m.selectedFeature
+	m.endpointsList.Title = m.endpointsTitle()
 	
-	// Restore selection if possible
-	if currentIndex < len(m.endpointsList.Items()) {
-		m.endpointsList.Select(currentIndex)
+	// Reselect the previous endpoint by ID, falling back to the first one
+	selectedIndex := 0
+	for i, item := range items {
+		if ei, ok := item.(endpointItem); ok && ei.id == previousID {
+			selectedIndex = i
+			break
+		}
 	}
+	m.endpointsList.Select(selectedIndex)
 	
 	// Update delegates based on active panel
 	m.updateListDelegatesForActivePanel()
@@ -388,28 +498,16 @@ func (m *Model) updateListDelegatesForActivePanel() {
 	// Update the lists with the new delegates
 	m.featuresList.SetDelegate(featuresDelegate)
 	m.endpointsList.SetDelegate(endpointsDelegate)
+	
+	// Remember the item heights so mouse clicks can be mapped to items
+	m.featuresRowHeight = featuresDelegate.Height() + featuresDelegate.Spacing()
+	m.endpointsRowHeight = endpointsDelegate.Height() + endpointsDelegate.Spacing()
 }
 
 // Update updates the UI model
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	
-	// Throttle updates to max 30fps (about 33ms between updates)
-	now := time.Now()
-	if now.Sub(m.lastUpdate) < 33*time.Millisecond {
-		// Skip non-essential updates if they come too quickly
-		switch msg.(type) {
-		case tea.WindowSizeMsg, tea.KeyMsg:
-			// Always process these immediately
-		default:
-			// Delay other updates
-			return m, tea.Tick(33*time.Millisecond-now.Sub(m.lastUpdate), func(t time.Time) tea.Msg {
-				return msg
-			})
-		}
-	}
-	m.lastUpdate = now
-
 	switch msg := msg.(type) {
 	case customUpdateMsg:
 		// Handle custom update messages for smoother UI updates
@@ -430,11 +528,66 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Endpoint was deleted, no need to force a full redraw
 			// The lists have already been updated in the dialog confirm function
 			
+		case "scenario_applied":
+			// Scenario was applied, no need to force a full redraw
+			// The endpoints list has already been updated in the dialog confirm function
+			m.updateFeatureCounts()
+			
+		case "feature_toggled":
+			// All endpoints of a feature were toggled, refresh the endpoints list
+			m.updateEndpointsList()
+			m.updateFeatureCounts()
+			
+		case "path_rewrite_updated":
+			// Path rewrite rules were saved, the summary is already in the status message
+			
+		case "proxy_updated":
+			// Proxy target was changed, the header reads it on render
+			
+		case "editor_closed":
+			// A blocking editor exited and the UI has the terminal back, pick up any edits
+			return m, m.reloadConfig
+			
+		case "change_origin_toggled":
+			// changeOrigin was flipped, the header and proxy dialog read it on render
+			
+		case "trace_toggled":
+			// Request tracing was flipped, the summary is already in the status message
+			
+		case "profile_changed":
+			// A different response profile is active, the summary is already in the status message
+			
+		case "tag_filter_changed":
+			// List only the endpoints with the new tag, or all of them
+			m.tagFilter = msg.name
+			m.updateEndpointsList()
+			
+		case "curl_copied":
+			// A curl command was copied, it's already shown in the status message
+			
+		case "config_reloaded":
+			// Configuration was reloaded, the summary is already in the status message
+			
 		case "server_toggled":
 			// Server was started or stopped, force a UI update
 			// No additional action needed as the message itself triggers the update
 			
+		case "server_restart_required":
+			// New server settings need a restart of the running server, ask first
+			m.showRestartConfirmDialog(msg.name, msg.port)
+			
+		case "server_settings_updated":
+			// Server settings were applied or left alone, the summary is already in the status message
+			
 		case "endpoint_updated":
+			// The endpoint may have been toggled, changing its feature's count
+			m.updateFeatureCounts()
+			
+			// A change may move the endpoint when the list is sorted, so rebuild it
+			if m.endpointSort != SortByFile {
+				m.updateEndpointsList()
+				break
+			}
 			// Update just the specific endpoint in the list
 			if msg.id != "" {
 				for i, item := range m.endpointsList.Items() {
@@ -443,23 +596,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						items := m.endpointsList.Items()
 						endpoint, _ := m.Config.GetEndpoint(m.selectedFeature, msg.id)
 						if endpoint != nil {
-							// Get all response names
-							var allResponses []string
-							for name := range endpoint.Responses {
-								allResponses = append(allResponses, name)
-							}
-							
-							// Sort responses alphabetically using Go's built-in sort package
-							sort.Strings(allResponses)
-							
-							items[i] = endpointItem{
-								id:              endpoint.ID,
-								method:          endpoint.Method,
-								path:            endpoint.Path,
-								active:          endpoint.Active,
-								defaultResponse: endpoint.DefaultResponse,
-								responses:       allResponses,
-							}
+							items[i] = m.newEndpointItem(*endpoint)
 							m.endpointsList.SetItems(items)
 						}
 						break
@@ -479,18 +616,41 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		listHeight := m.height - topHeight - bottomHeight
 		
 		// Adjust widths to account for borders (subtract 2 for borders)
-		featureWidth := m.width/4 - 2
-		endpointWidth := 3*m.width/4 - 2
+		featureWidth, endpointWidth := m.panelWidths(m.width)
+		featureWidth -= 2
+		endpointWidth -= 2
 		
 		m.featuresList.SetSize(featureWidth, listHeight)
 		m.endpointsList.SetSize(endpointWidth, listHeight)
 		
 		m.help.Width = m.width
 		
+		// Rebuild endpoint items so descriptions fit the new width
+		m.updateEndpointsList()
+		
 		// Update cached styles with new dimensions
 		m.initStyles()
 		
+		// Refit open help to the new size
+		if m.activeDialog == HelpDialog {
+			m.sizeHelpViewport()
+		}
+		
+	case error:
+		// Surface errors returned by commands in the status message
+		m.statusMessage = fmt.Sprintf("Error: %v", msg)
+		
+	case tea.MouseMsg:
+		// Dialogs are keyboard only
+		if m.activeDialog != NoDialog {
+			return m, nil
+		}
+		return m.handleMouse(msg)
+		
 	case tea.KeyMsg:
+		// Any key press dismisses the previous status message
+		m.statusMessage = ""
+		
 		// Handle dialog-specific key presses
 		if m.activeDialog != NoDialog {
 			return m.updateDialog(msg)
@@ -499,7 +659,12 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Handle global key presses
 		switch {
 		case key.Matches(msg, m.keyMap.Quit):
+			m.savePreferences()
 			return m, tea.Quit
+		case key.Matches(msg, m.keyMap.NarrowFeatures):
+			return m.resizeFeatures(-featuresWidthStep)
+		case key.Matches(msg, m.keyMap.WidenFeatures):
+			return m.resizeFeatures(featuresWidthStep)
 		case key.Matches(msg, m.keyMap.Left):
 			if m.activePanel == EndpointsPanel {
 				m.activePanel = FeaturesPanel
@@ -515,6 +680,8 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Initialize dialog content
 			m.dialogTitle = "Climock Help"
 			m.dialogContent = ""
+			m.helpViewport = viewport.New(0, 0)
+			m.sizeHelpViewport()
 			return m, nil
 		case key.Matches(msg, m.keyMap.New):
 			if m.activePanel == FeaturesPanel {
@@ -541,15 +708,46 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keyMap.Proxy):
 			m.showProxyConfigDialog()
 			return m, nil
+		case key.Matches(msg, m.keyMap.Settings):
+			m.showSettingsDialog()
+			return m, nil
+		case key.Matches(msg, m.keyMap.ChangeOrigin):
+			return m, m.toggleChangeOrigin()
+		case key.Matches(msg, m.keyMap.Trace):
+			return m, m.toggleTrace()
+		case key.Matches(msg, m.keyMap.Profile):
+			return m, m.cycleProfile()
+		case key.Matches(msg, m.keyMap.TagFilter):
+			return m, m.cycleTagFilter()
+		case key.Matches(msg, m.keyMap.PathRewrite):
+			m.showPathRewriteDialog()
+			return m, nil
+		case key.Matches(msg, m.keyMap.Scenario):
+			m.showScenarioDialog()
+			return m, nil
 		case key.Matches(msg, m.keyMap.Server):
 			return m, m.toggleServer()
 		case key.Matches(msg, m.keyMap.Reload):
 			return m, m.reloadConfig
 		case key.Matches(msg, m.keyMap.Toggle):
+			// In the features panel, toggle every endpoint of the selected feature
+			if m.activePanel == FeaturesPanel && len(m.featuresList.Items()) > 0 {
+				return m, m.toggleFeature()
+			}
 			// Only toggle if we're in the endpoints panel and there are endpoints
 			if m.activePanel == EndpointsPanel && m.selectedFeature != "" && len(m.endpointsList.Items()) > 0 {
 				return m, m.toggleEndpoint()
 			}
+		case key.Matches(msg, m.keyMap.Sort):
+			// Cycle the display order of the endpoints list
+			m.endpointSort = m.endpointSort.Next()
+			m.updateEndpointsList()
+			return m, nil
+		case key.Matches(msg, m.keyMap.Curl):
+			// Only copy a command if we're in the endpoints panel and there are endpoints
+			if m.activePanel == EndpointsPanel && m.selectedFeature != "" && len(m.endpointsList.Items()) > 0 {
+				return m, m.copyCurl()
+			}
 		case key.Matches(msg, m.keyMap.Response):
 			// Only cycle response if we're in the endpoints panel and there are endpoints
 			if m.activePanel == EndpointsPanel && m.selectedFeature != "" && len(m.endpointsList.Items()) > 0 {
@@ -565,7 +763,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			
 			if hasSelection {
-				return m, m.openInEditor
+				return m, m.openInEditor()
 			}
 			return m, nil
 		}
@@ -608,8 +806,10 @@ func (m *Model) toggleServer() tea.Cmd {
 			logger.Info("User requested to start server")
 			if err := m.Server.Start(); err != nil {
 				logger.Error("Failed to start server: %v", err)
+				m.serverError = err.Error()
 				return fmt.Errorf("failed to start server: %v", err)
 			}
+			m.serverError = ""
 			// Return a custom update message to trigger UI refresh
 			return customUpdateMsg{action: "server_toggled", active: true}
 		}
@@ -618,20 +818,48 @@ func (m *Model) toggleServer() tea.Cmd {
 
 // reloadConfig reloads the configuration
 func (m *Model) reloadConfig() tea.Msg {
-	if err := m.Config.Load(); err != nil {
-		return err
-	}
+	err := m.Config.Load()
 	
+	// Rebuild the lists even when the load failed, since it may have got as
+	// far as replacing the features. The selected feature falls back to the
+	// first one if it's gone, so the endpoints panel never lists a feature
+	// that no longer exists.
 	m.initFeaturesList()
 	m.updateEndpointsList()
 	
+	if err != nil {
+		m.statusMessage = fmt.Sprintf("Reload failed: %v", err)
+		return customUpdateMsg{action: "config_reloaded"}
+	}
+	
 	if m.Server.IsRunning() {
 		if err := m.Server.Reload(); err != nil {
-			return err
+			m.statusMessage = fmt.Sprintf("Reload failed: %v", err)
+			return customUpdateMsg{action: "config_reloaded"}
 		}
 	}
 	
-	return nil
+	// Start template counters over with the fresh configuration
+	m.MockManager.ResetCounters()
+	
+	m.statusMessage = reloadSummary(len(m.Config.FeatureNames()), m.Config.LoadErrors())
+	
+	// Return a custom update message to show the reload summary
+	return customUpdateMsg{action: "config_reloaded"}
+}
+
+// reloadSummary describes how many features loaded and which files failed
+func reloadSummary(loaded int, loadErrors []config.LoadError) string {
+	summary := fmt.Sprintf("Reloaded %d feature(s)", loaded)
+	if len(loadErrors) == 0 {
+		return summary
+	}
+	
+	files := make([]string, len(loadErrors))
+	for i, loadErr := range loadErrors {
+		files[i] = loadErr.File
+	}
+	return fmt.Sprintf("%s, %d failed: %s", summary, len(loadErrors), strings.Join(files, ", "))
 }
 
 // toggleEndpoint toggles the selected endpoint
@@ -666,6 +894,163 @@ func (m *Model) toggleEndpoint() tea.Cmd {
 	}
 }
 
+// toggleFeature activates every endpoint of the selected feature, or
+// deactivates them all if they are already active
+func (m *Model) toggleFeature() tea.Cmd {
+	return func() tea.Msg {
+		item, ok := m.featuresList.SelectedItem().(featureItem)
+		if !ok {
+			return nil
+		}
+		
+		featureConfig, ok := m.Config.GetFeature(item.name)
+		if !ok || len(featureConfig.Endpoints) == 0 {
+			return nil
+		}
+		
+		// Activate all unless every endpoint is already active
+		active := false
+		for _, endpoint := range featureConfig.Endpoints {
+			if !endpoint.Active {
+				active = true
+				break
+			}
+		}
+		
+		if err := m.MockManager.SetFeatureActive(item.name, active); err != nil {
+			return err
+		}
+		
+		if m.Server.IsRunning() {
+			if err := m.Server.Reload(); err != nil {
+				return err
+			}
+		}
+		
+		// Return a custom update message instead of forcing a full redraw
+		return customUpdateMsg{
+			action:  "feature_toggled",
+			feature: item.name,
+			active:  active,
+		}
+	}
+}
+
+// toggleChangeOrigin flips whether proxied requests get their Host header
+// rewritten to the target's
+func (m *Model) toggleChangeOrigin() tea.Cmd {
+	return func() tea.Msg {
+		changeOrigin := !m.ProxyManager.IsChangeOrigin()
+		if err := m.ProxyManager.SetChangeOrigin(changeOrigin); err != nil {
+			logger.Error("Failed to update changeOrigin: %v", err)
+			return fmt.Errorf("failed to update changeOrigin: %v", err)
+		}
+		
+		m.statusMessage = fmt.Sprintf("changeOrigin %s", onOff(changeOrigin))
+		return customUpdateMsg{
+			action: "change_origin_toggled",
+			active: changeOrigin,
+		}
+	}
+}
+
+// toggleTrace turns request tracing on or off. It applies to the running
+// server and to the next start, and isn't saved.
+func (m *Model) toggleTrace() tea.Cmd {
+	return func() tea.Msg {
+		trace := !m.Server.IsTracing()
+		m.Server.SetTrace(trace)
+		
+		m.statusMessage = fmt.Sprintf("Request tracing %s", onOff(trace))
+		return customUpdateMsg{
+			action: "trace_toggled",
+			active: trace,
+		}
+	}
+}
+
+// cycleProfile switches to the next response profile, going back to no
+// profile after the last one. Like tracing, the choice isn't saved.
+func (m *Model) cycleProfile() tea.Cmd {
+	return func() tea.Msg {
+		profiles := m.MockManager.ProfileNames()
+		if len(profiles) == 0 {
+			return fmt.Errorf("no response profiles defined; tag responses with \"profiles\"")
+		}
+		
+		// Options are no profile, then each profile in order
+		next := profiles[0]
+		current := m.MockManager.Profile()
+		for i, profile := range profiles {
+			if profile == current {
+				next = ""
+				if i+1 < len(profiles) {
+					next = profiles[i+1]
+				}
+				break
+			}
+		}
+		
+		if err := m.MockManager.SetProfile(next); err != nil {
+			return err
+		}
+		
+		if next == "" {
+			m.statusMessage = "Response profile off"
+		} else {
+			m.statusMessage = fmt.Sprintf("Response profile: %s", next)
+		}
+		return customUpdateMsg{
+			action: "profile_changed",
+			name:   next,
+		}
+	}
+}
+
+// cycleTagFilter limits the endpoints list to the next tag, going back to
+// all endpoints after the last one. Like sorting, it only changes the display.
+func (m *Model) cycleTagFilter() tea.Cmd {
+	current := m.tagFilter
+	return func() tea.Msg {
+		tags := m.MockManager.TagNames()
+		if len(tags) == 0 && current == "" {
+			return fmt.Errorf("no tags defined; add \"tags\" to endpoints")
+		}
+		
+		// Options are no filter, then each tag in order
+		next := ""
+		if current == "" {
+			next = tags[0]
+		}
+		for i, tag := range tags {
+			if tag == current {
+				if i+1 < len(tags) {
+					next = tags[i+1]
+				}
+				break
+			}
+		}
+		
+		if next == "" {
+			m.statusMessage = "Tag filter off"
+		} else {
+			m.statusMessage = fmt.Sprintf("Showing endpoints tagged %s", next)
+		}
+		return customUpdateMsg{
+			action: "tag_filter_changed",
+			name:   next,
+		}
+	}
+}
+
+// onOff describes a boolean setting for display
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
 // cycleResponse cycles through the available responses for the selected endpoint
 func (m *Model) cycleResponse() tea.Cmd {
 	return func() tea.Msg {
@@ -684,14 +1069,15 @@ func (m *Model) cycleResponse() tea.Cmd {
 			return err
 		}
 		
-		// Get all response names
+		// Get all response names, sorted to match the order they're listed in
 		var responses []string
 		for name := range endpoint.Responses {
 			responses = append(responses, name)
 		}
+		sort.Strings(responses)
 		
 		if len(responses) == 0 {
-			return nil
+			return fmt.Errorf("endpoint %s has no responses to cycle through", item.id)
 		}
 		
 		// Find the current default response
@@ -715,6 +1101,11 @@ func (m *Model) cycleResponse() tea.Cmd {
 			return err
 		}
 		
+		// Describe the newly selected response if it documents itself
+		if description := endpoint.Responses[nextResponse].Description; description != "" {
+			m.statusMessage = fmt.Sprintf("%s: %s", nextResponse, description)
+		}
+		
 		if m.Server.IsRunning() {
 			if err := m.Server.Reload(); err != nil {
 				return err
@@ -731,79 +1122,124 @@ func (m *Model) cycleResponse() tea.Cmd {
 	}
 }
 
-// openInEditor opens the selected feature or endpoint in the editor
-func (m *Model) openInEditor() tea.Msg {
+// openInEditor opens the selected feature or endpoint in the editor. Blocking
+// editors are run with tea.ExecProcess so they get the terminal until they exit;
+// other editors are started in the background.
+func (m *Model) openInEditor() tea.Cmd {
+	cmd, err := m.editorCommand()
+	if err != nil {
+		return func() tea.Msg { return err }
+	}
+	if cmd == nil {
+		return nil
+	}
+	
+	if m.editorConfig().IsBlocking() {
+		return tea.ExecProcess(cmd, func(err error) tea.Msg {
+			if err != nil {
+				return fmt.Errorf("editor exited with an error: %v", err)
+			}
+			return customUpdateMsg{action: "editor_closed"}
+		})
+	}
+	
+	return func() tea.Msg {
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("failed to start editor: %v", err)
+		}
+		
+		// Don't wait for the editor to close
+		return nil
+	}
+}
+
+// editorCommand builds the editor command for the selected feature or endpoint.
+// It returns a nil command if there is nothing to open.
+func (m *Model) editorCommand() (*exec.Cmd, error) {
 	var filePath string
 	var line int
 	
 	// Check if there are items to select from
 	if m.activePanel == FeaturesPanel {
 		if len(m.featuresList.Items()) == 0 {
-			return nil // No features available, silently do nothing
+			return nil, nil // No features available, silently do nothing
 		}
 		
 		item, ok := m.featuresList.SelectedItem().(featureItem)
 		if !ok {
-			return fmt.Errorf("no feature selected")
+			return nil, fmt.Errorf("no feature selected")
 		}
 		
-		filePath = fmt.Sprintf("%s/%s.json", m.Config.BaseDir, item.name)
+		filePath = m.Config.FeaturePath(item.name)
 		line = 1
 	} else {
 		if m.selectedFeature == "" || len(m.endpointsList.Items()) == 0 {
-			return nil // No endpoints available, silently do nothing
+			return nil, nil // No endpoints available, silently do nothing
 		}
 		
 		endpoint, ok := m.endpointsList.SelectedItem().(endpointItem)
 		if !ok {
-			return fmt.Errorf("no endpoint selected")
+			return nil, fmt.Errorf("no endpoint selected")
 		}
 		
-		filePath = fmt.Sprintf("%s/%s.json", m.Config.BaseDir, m.selectedFeature)
+		filePath = m.Config.FeaturePath(m.selectedFeature)
 		
-		// Find the actual line number of the endpoint in the file
-		line = findEndpointLineNumber(filePath, endpoint.id)
+		// Find the actual line number of the endpoint in the file, at its current response if it has one
+		line = findEndpointLineNumber(filePath, endpoint.id, endpoint.defaultResponse)
 	}
 	
 	// Check if file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return fmt.Errorf("file not found: %s", filePath)
+		return nil, fmt.Errorf("file not found: %s", filePath)
 	}
 	
 	// Get editor command and args
-	command := m.Config.Global.Editor.Command
+	editor := m.editorConfig()
+	command := editor.Command
 	if command == "" {
-		return fmt.Errorf("editor command not configured")
+		return nil, fmt.Errorf("no editor configured; set editor.command in config.json")
 	}
 	
 	// Create a new slice for args to avoid modifying the original
-	args := make([]string, 0, len(m.Config.Global.Editor.Args))
+	args := make([]string, 0, len(editor.Args))
 	
 	// Replace placeholders in args
-	for _, arg := range m.Config.Global.Editor.Args {
+	for _, arg := range editor.Args {
 		newArg := strings.ReplaceAll(arg, "{file}", filePath)
 		newArg = strings.ReplaceAll(newArg, "{line}", fmt.Sprintf("%d", line))
 		args = append(args, newArg)
 	}
 	
-	// Execute the editor command
-	
-	// Execute the editor command
-	cmd := exec.Command(command, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start editor: %v", err)
+	return exec.Command(command, args...), nil
+}
+
+// editorConfig returns the configured editor. On unix, if no command is
+// configured, it falls back to $EDITOR, which may include its own arguments,
+// and opens the file without a line number.
+func (m *Model) editorConfig() config.EditorConfig {
+	editor := m.Config.Global.Editor
+	if editor.Command != "" || runtime.GOOS == "windows" {
+		return editor
 	}
 	
-	// Don't wait for the editor to close
-	return nil
+	fields := strings.Fields(os.Getenv("EDITOR"))
+	if len(fields) == 0 {
+		return editor
+	}
+	editor.Command = fields[0]
+	editor.Args = append(fields[1:], "{file}")
+	return editor
 }
 
-// findEndpointLineNumber finds the line number of an endpoint in a JSON file
-func findEndpointLineNumber(filePath, endpointID string) int {
+// findEndpointLineNumber finds the line number of an endpoint in a JSON file.
+// If responseName is set, it returns the line of that response's key within
+// the endpoint's responses instead, falling back to the endpoint's path line.
+// The file is walked with a JSON tokenizer, so it works however the file is formatted.
+func findEndpointLineNumber(filePath, endpointID, responseName string) int {
 	// Default line number if we can't find the exact position
 	defaultLine := 1
 	
@@ -813,79 +1249,133 @@ func findEndpointLineNumber(filePath, endpointID string) int {
 		return defaultLine
 	}
 	
-	// Convert to string for line-by-line processing
-	content := string(data)
-	lines := strings.Split(content, "\n")
-	
-	// First, find the endpoints array
-	endpointsStartLine := -1
-	for i, line := range lines {
-		if strings.Contains(line, `"endpoints":`) {
-			endpointsStartLine = i
-			break
-		}
+	// lineAt converts the decoder's current byte offset into a 1-based line number
+	// Comments are blanked out in place, so offsets still match the file
+	dec := json.NewDecoder(bytes.NewReader(config.StripComments(data)))
+	lineAt := func() int {
+		return bytes.Count(data[:dec.InputOffset()], []byte("\n")) + 1
 	}
 	
-	if endpointsStartLine == -1 {
+	// The feature file is an object; find its endpoints array
+	if !expectDelim(dec, '{') {
 		return defaultLine
 	}
-	
-	// Now search for the endpoint with the matching ID
-	inEndpoint := false
-	endpointStartLine := -1
-	idLine := -1
-	pathLine := -1
-	
-	for i := endpointsStartLine; i < len(lines); i++ {
-		line := strings.TrimSpace(lines[i])
-		
-		// Start of an endpoint object
-		if line == "{" && !inEndpoint {
-			inEndpoint = true
-			endpointStartLine = i
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return defaultLine
+		}
+		if key != "endpoints" {
+			if skipJSONValue(dec) != nil {
+				return defaultLine
+			}
 			continue
 		}
 		
-		// End of an endpoint object
-		if line == "}" && inEndpoint {
-			// If we found the ID but not the path, reset and continue
-			if idLine > 0 && pathLine == -1 {
-				inEndpoint = false
-				endpointStartLine = -1
-				idLine = -1
-				continue
+		if !expectDelim(dec, '[') {
+			return defaultLine
+		}
+		for dec.More() {
+			if !expectDelim(dec, '{') {
+				return defaultLine
+			}
+			startLine := lineAt()
+			
+			// Collect where the interesting fields of this endpoint are
+			var id string
+			pathLine := -1
+			responseLines := make(map[string]int)
+			for dec.More() {
+				field, err := dec.Token()
+				if err != nil {
+					return defaultLine
+				}
+				fieldLine := lineAt()
+				
+				switch field {
+				case "id":
+					value, err := dec.Token()
+					if err != nil {
+						return defaultLine
+					}
+					id, _ = value.(string)
+				case "path":
+					pathLine = fieldLine
+					if skipJSONValue(dec) != nil {
+						return defaultLine
+					}
+				case "responses":
+					if !expectDelim(dec, '{') {
+						return defaultLine
+					}
+					for dec.More() {
+						name, err := dec.Token()
+						if err != nil {
+							return defaultLine
+						}
+						if s, ok := name.(string); ok {
+							responseLines[s] = lineAt()
+						}
+						if skipJSONValue(dec) != nil {
+							return defaultLine
+						}
+					}
+					if !expectDelim(dec, '}') {
+						return defaultLine
+					}
+				default:
+					if skipJSONValue(dec) != nil {
+						return defaultLine
+					}
+				}
+			}
+			if !expectDelim(dec, '}') {
+				return defaultLine
 			}
 			
-			// If we found both ID and path, we're done
-			if idLine > 0 && pathLine > 0 {
-				return pathLine + 1 // Return the path line (1-based)
+			if id != endpointID {
+				continue
 			}
+			if line, ok := responseLines[responseName]; ok && responseName != "" {
+				return line
+			}
+			if pathLine > 0 {
+				return pathLine
+			}
+			return startLine
 		}
-		
-		// Look for the ID field
-		if inEndpoint && strings.Contains(line, `"id":`) && strings.Contains(line, `"`+endpointID+`"`) {
-			idLine = i
-		}
-		
-		// Look for the path field if we've already found the ID
-		if inEndpoint && idLine > 0 && strings.Contains(line, `"path":`) {
-			pathLine = i
-		}
-	}
-	
-	// If we found the ID but not the path, return the ID line
-	if idLine > 0 {
-		return idLine + 1
-	}
-	
-	// If we found the endpoint start but not the ID or path, return the endpoint start line
-	if endpointStartLine > 0 {
-		return endpointStartLine + 1
+		return defaultLine
 	}
 	
 	return defaultLine
 }
 
+// expectDelim reads the next token and reports whether it is the given delimiter
+func expectDelim(dec *json.Decoder, delim json.Delim) bool {
+	token, err := dec.Token()
+	return err == nil && token == delim
+}
+
+// skipJSONValue reads past the next value, including any nested objects or arrays
+func skipJSONValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
 // updateDialog updates the active dialog
 func (m *Model) updateDialog(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Safety check - if somehow we get here with NoDialog, return to normal UI
@@ -893,7 +1383,23 @@ func (m *Model) updateDialog(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	// Scroll help that doesn't fit; other keys still dismiss it below
+	if m.activeDialog == HelpDialog {
+		switch msg.Type {
+		case tea.KeyUp, tea.KeyDown, tea.KeyPgUp, tea.KeyPgDown:
+			var cmd tea.Cmd
+			m.helpViewport, cmd = m.helpViewport.Update(msg)
+			return m, cmd
+		}
+	}
+
 	switch msg.Type {
+	case tea.KeyCtrlO:
+		// Flip changeOrigin from within the proxy dialog, where O is typed into the input
+		if m.activeDialog == ProxyConfigDialog {
+			return m, m.toggleChangeOrigin()
+		}
+		
 	case tea.KeyEsc:
 		// Cancel the dialog
 		m.activeDialog = NoDialog
@@ -910,6 +1416,10 @@ func (m *Model) updateDialog(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.dialogContent = ""
 		m.dialogCancelFn = nil
 		m.dialogConfirmFn = nil
+		m.dialogOptions = nil
+		m.dialogCursor = 0
+		m.dialogValidateFn = nil
+		m.dialogError = ""
 		
 		// Execute cancel function if available
 		if cancelFn != nil {
@@ -927,6 +1437,15 @@ func (m *Model) updateDialog(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		
+		// Keep the dialog open and explain what's wrong if the input is invalid
+		if m.dialogValidateFn != nil {
+			if err := m.dialogValidateFn(); err != nil {
+				m.dialogError = err.Error()
+				_, m.dialogErrorIsWarning = err.(dialogWarning)
+				return m, nil
+			}
+		}
+		
 		// Execute the confirm function if available
 		if m.dialogConfirmFn != nil {
 			// Store the confirm function before clearing dialog state
@@ -943,6 +1462,10 @@ func (m *Model) updateDialog(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.dialogConfirmFn = nil
 			m.dialogCancelFn = nil
 			m.textInputs = nil
+			m.dialogOptions = nil
+			m.dialogCursor = 0
+			m.dialogValidateFn = nil
+			m.dialogError = ""
 			
 			return m, cmd
 		}
@@ -954,6 +1477,10 @@ func (m *Model) updateDialog(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.dialogContent = ""
 		m.dialogConfirmFn = nil
 		m.dialogCancelFn = nil
+		m.dialogOptions = nil
+		m.dialogCursor = 0
+		m.dialogValidateFn = nil
+		m.dialogError = ""
 		return m, nil
 		
 	case tea.KeyTab:
@@ -1017,6 +1544,22 @@ func (m *Model) updateDialog(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		
+	case tea.KeyUp, tea.KeyDown:
+		// Move the selection in option dialogs
+		if len(m.dialogOptions) > 0 {
+			if msg.Type == tea.KeyUp {
+				m.dialogCursor = (m.dialogCursor - 1 + len(m.dialogOptions)) % len(m.dialogOptions)
+			} else {
+				m.dialogCursor = (m.dialogCursor + 1) % len(m.dialogOptions)
+			}
+			// In the proxy dialog the options are recent targets to prefill
+			if m.activeDialog == ProxyConfigDialog && len(m.textInputs) > 0 {
+				m.textInputs[0].SetValue(m.dialogOptions[m.dialogCursor])
+				m.textInputs[0].CursorEnd()
+			}
+			return m, nil
+		}
+		
 	default:
 		// Update text inputs if any
 		if len(m.textInputs) > 0 {
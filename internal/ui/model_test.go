@@ -1,6 +1,15 @@
 package ui_test
 
 import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
 
 	"swoozeki/climock/internal/config"
@@ -11,6 +20,7 @@ import (
 	"swoozeki/climock/internal/ui"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
 )
 
 func init() {
@@ -24,6 +34,7 @@ func createTestConfig() *config.Config {
 
 	// Set up global config
 	cfg.Global = config.GlobalConfig{
+		Version: config.CurrentVersion,
 		ServerConfig: config.ServerConfig{
 			Port: 3000,
 			Host: "localhost",
@@ -303,4 +314,1602 @@ func TestServerInteraction(t *testing.T) {
 	
 	// We can't reliably test the server state in a unit test
 	// as it depends on network resources
-}
\ No newline at end of file
+}
+// newTestModel creates a UI model with managers and a server for the given config
+func newTestModel(t *testing.T, cfg *config.Config) *ui.Model {
+	t.Helper()
+
+	mockManager := mock.New(cfg)
+	proxyManager, err := proxy.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create proxy manager: %v", err)
+	}
+	srv := server.New(cfg, mockManager, proxyManager)
+
+	return ui.New(cfg, mockManager, proxyManager, srv)
+}
+
+// writeTestConfigDir writes the global config and the given feature files to a temporary directory
+func writeTestConfigDir(t *testing.T, features ...config.FeatureConfig) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	global := createTestConfig().Global
+	data, err := json.MarshalIndent(global, "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal global config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write global config: %v", err)
+	}
+
+	for _, feature := range features {
+		data, err := json.MarshalIndent(feature, "", "  ")
+		if err != nil {
+			t.Fatalf("Failed to marshal feature config: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, feature.Feature+".json"), data, 0644); err != nil {
+			t.Fatalf("Failed to write feature config: %v", err)
+		}
+	}
+
+	return dir
+}
+
+// TestReloadPreservesSelection tests that reloading keeps the selected feature and endpoint
+func TestReloadPreservesSelection(t *testing.T) {
+	makeFeature := func(name string) config.FeatureConfig {
+		return config.FeatureConfig{
+			Feature: name,
+			Endpoints: []config.Endpoint{
+				{ID: name + "-1", Method: "GET", Path: "/api/" + name + "/1", DefaultResponse: "standard",
+					Responses: map[string]config.Response{"standard": {Status: 200}}},
+				{ID: name + "-2", Method: "GET", Path: "/api/" + name + "/2", DefaultResponse: "standard",
+					Responses: map[string]config.Response{"standard": {Status: 200}}},
+			},
+		}
+	}
+	dir := writeTestConfigDir(t, makeFeature("alpha"), makeFeature("beta"), makeFeature("gamma"))
+
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+
+	// Select the second feature and its second endpoint
+	model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	model.Update(tea.KeyMsg{Type: tea.KeyRight})
+	model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if model.SelectedFeature() != "beta" || model.SelectedEndpointID() != "beta-2" {
+		t.Fatalf("Expected beta/beta-2 to be selected, got %s/%s", model.SelectedFeature(), model.SelectedEndpointID())
+	}
+
+	// Reload the configuration
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if cmd == nil {
+		t.Fatal("Expected reload command")
+	}
+	cmd()
+
+	if model.SelectedFeature() != "beta" {
+		t.Errorf("Expected feature 'beta' to remain selected, got %q", model.SelectedFeature())
+	}
+	if model.SelectedEndpointID() != "beta-2" {
+		t.Errorf("Expected endpoint 'beta-2' to remain selected, got %q", model.SelectedEndpointID())
+	}
+
+	// Remove the selected endpoint on disk and reload: selection falls back to the first endpoint
+	feature := makeFeature("beta")
+	feature.Endpoints = feature.Endpoints[:1]
+	data, _ := json.Marshal(feature)
+	if err := os.WriteFile(filepath.Join(dir, "beta.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to rewrite feature config: %v", err)
+	}
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	cmd()
+
+	if model.SelectedFeature() != "beta" {
+		t.Errorf("Expected feature 'beta' to remain selected, got %q", model.SelectedFeature())
+	}
+	if model.SelectedEndpointID() != "beta-1" {
+		t.Errorf("Expected selection to fall back to 'beta-1', got %q", model.SelectedEndpointID())
+	}
+}
+
+// TestEndpointSort tests that the sort key reorders the endpoints list for display only
+func TestEndpointSort(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mocks["test"] = config.FeatureConfig{
+		Feature: "test",
+		Endpoints: []config.Endpoint{
+			{ID: "put-a", Method: "PUT", Path: "/api/a", Active: false},
+			{ID: "delete-c", Method: "DELETE", Path: "/api/c", Active: true},
+			{ID: "get-b", Method: "GET", Path: "/api/b", Active: true},
+		},
+	}
+	model := newTestModel(t, cfg)
+
+	sortKey := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'S'}}
+	tests := []struct {
+		mode     string
+		expected []string
+	}{
+		{"path", []string{"put-a", "get-b", "delete-c"}},
+		{"method", []string{"delete-c", "get-b", "put-a"}},
+		{"active", []string{"get-b", "delete-c", "put-a"}},
+		{"file", []string{"put-a", "delete-c", "get-b"}},
+	}
+
+	for _, tt := range tests {
+		model.Update(sortKey)
+		ids := model.EndpointIDs()
+		if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("Sorted by %s: expected %v, got %v", tt.mode, tt.expected, ids)
+		}
+	}
+
+	// Sorting must not change the underlying config order
+	if cfg.Mocks["test"].Endpoints[0].ID != "put-a" {
+		t.Errorf("Expected config order to be unchanged, got first endpoint %q", cfg.Mocks["test"].Endpoints[0].ID)
+	}
+}
+
+// TestMethodColors tests that each common HTTP method has a distinct color
+func TestMethodColors(t *testing.T) {
+	tests := []struct {
+		method string
+		color  string
+	}{
+		{"GET", "42"},
+		{"POST", "33"},
+		{"PUT", "208"},
+		{"DELETE", "196"},
+		{"PATCH", "135"},
+		{"delete", "196"},
+		{"OPTIONS", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ui.MethodColor(tt.method); got != tt.color {
+			t.Errorf("Expected color %q for %s, got %q", tt.color, tt.method, got)
+		}
+	}
+}
+
+// TestEndpointDescription tests the response count prefix and truncation
+func TestEndpointDescription(t *testing.T) {
+	responses := []string{"error", "slow", "standard"}
+
+	desc := ui.EndpointDescription(responses, "standard", 0)
+	if desc != "(3) [error | slow | ★standard]" {
+		t.Errorf("Unexpected description: %q", desc)
+	}
+
+	desc = ui.EndpointDescription(responses, "standard", 12)
+	if desc != "(3) [error …" {
+		t.Errorf("Unexpected truncated description: %q", desc)
+	}
+
+	// Truncation must not split ANSI escape sequences
+	styled := "\x1b[31m(3) [error | slow]\x1b[0m"
+	truncated := ui.TruncateToWidth(styled, 8)
+	if !strings.HasPrefix(truncated, "\x1b[31m") {
+		t.Errorf("Expected leading escape sequence to be kept, got %q", truncated)
+	}
+	if plain := ansi.Strip(truncated); plain != "(3) [er…" {
+		t.Errorf("Expected plain text %q, got %q", "(3) [er…", plain)
+	}
+}
+
+// TestMockOnlyHeader tests that the header indicates when proxying is disabled
+func TestMockOnlyHeader(t *testing.T) {
+	cfg := createTestConfig()
+	proxyEnabled := false
+	cfg.Global.ProxyEnabled = &proxyEnabled
+	model := newTestModel(t, cfg)
+
+	if view := model.View(); !strings.Contains(view, "Proxy: Mock-only") {
+		t.Error("Expected header to show Mock-only mode")
+	}
+}
+
+// TestReloadStatusMessage tests that reloading reports loaded and failed features
+func TestReloadStatusMessage(t *testing.T) {
+	dir := writeTestConfigDir(t, config.FeatureConfig{Feature: "users"})
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"feature": `), 0644); err != nil {
+		t.Fatalf("Failed to write broken feature file: %v", err)
+	}
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if cmd == nil {
+		t.Fatal("Expected reload command")
+	}
+	cmd()
+
+	view := model.View()
+	if !strings.Contains(view, "Reloaded 1 feature(s), 1 failed: broken.json") {
+		t.Errorf("Expected reload summary in view, got:\n%s", view)
+	}
+
+	// The message is dismissed by the next key press
+	model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if strings.Contains(model.View(), "Reloaded") {
+		t.Error("Expected status message to be cleared after a key press")
+	}
+}
+
+// TestDisabledFeatureMarked tests that disabled features are marked in the features list
+func TestDisabledFeatureMarked(t *testing.T) {
+	cfg := createTestConfig()
+	for name, feature := range cfg.Mocks {
+		enabled := false
+		feature.Enabled = &enabled
+		cfg.Mocks[name] = feature
+	}
+	model := newTestModel(t, cfg)
+
+	if !strings.Contains(model.View(), "(off)") {
+		t.Error("Expected disabled feature to be marked in the features list")
+	}
+}
+
+// TestEndpointDescriptionShown tests that an endpoint's description is shown
+// in its description line
+func TestEndpointDescriptionShown(t *testing.T) {
+	dir := writeTestConfigDir(t, config.FeatureConfig{
+		Feature: "users",
+		Endpoints: []config.Endpoint{
+			{
+				ID:              "get-users",
+				Method:          "GET",
+				Path:            "/api/users",
+				Description:     "Lists all users",
+				DefaultResponse: "standard",
+				Responses:       map[string]config.Response{"standard": {Status: 200}},
+			},
+		},
+	})
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	if !strings.Contains(model.View(), "(1) [★standard] · Lists all users") {
+		t.Error("Expected endpoint description in the endpoints list")
+	}
+}
+
+// TestCycleResponseWithoutResponses tests that cycling an endpoint with no
+// responses reports an error instead of misbehaving
+func TestCycleResponseWithoutResponses(t *testing.T) {
+	dir := writeTestConfigDir(t, config.FeatureConfig{
+		Feature: "users",
+		Endpoints: []config.Endpoint{
+			{ID: "get-users", Method: "GET", Path: "/api/users", Active: true},
+		},
+	})
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRight})
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	if cmd == nil {
+		t.Fatal("Expected cycle response command")
+	}
+
+	err, ok := cmd().(error)
+	if !ok || !strings.Contains(err.Error(), "no responses") {
+		t.Fatalf("Expected a no responses error, got %v", err)
+	}
+
+	// The error is shown in the header once the command result is delivered
+	model.Update(err)
+	if !strings.Contains(model.View(), "Error: endpoint get-users has no responses") {
+		t.Errorf("Expected error in view, got:\n%s", model.View())
+	}
+}
+
+// TestCycleResponseOrder tests that r steps through responses in the sorted
+// order they're listed in and wraps around after the last
+func TestCycleResponseOrder(t *testing.T) {
+	dir := writeTestConfigDir(t, config.FeatureConfig{
+		Feature: "users",
+		Endpoints: []config.Endpoint{
+			{ID: "get-users", Method: "GET", Path: "/api/users", Active: true, DefaultResponse: "alpha",
+				Responses: map[string]config.Response{"gamma": {Status: 500}, "alpha": {Status: 200}, "beta": {Status: 404}}},
+		},
+	})
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+	model.Update(tea.KeyMsg{Type: tea.KeyRight})
+
+	for _, want := range []string{"beta", "gamma", "alpha", "beta"} {
+		_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+		if cmd == nil {
+			t.Fatal("Expected cycle response command")
+		}
+		model.Update(cmd())
+
+		endpoint, err := cfg.GetEndpoint("users", "get-users")
+		if err != nil {
+			t.Fatalf("Failed to get endpoint: %v", err)
+		}
+		if endpoint.DefaultResponse != want {
+			t.Fatalf("Expected the next response to be %q, got %q", want, endpoint.DefaultResponse)
+		}
+	}
+}
+
+// TestRapidUpdatesApplied tests that update messages arriving in quick
+// succession are all applied immediately rather than delayed
+func TestRapidUpdatesApplied(t *testing.T) {
+	cfg := createTestConfig()
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	for _, id := range []string{"endpoint1", "endpoint2"} {
+		endpoint, err := cfg.GetEndpoint("test", id)
+		if err != nil {
+			t.Fatalf("Failed to get endpoint %s: %v", id, err)
+		}
+		endpoint.Active = !endpoint.Active
+		if err := cfg.UpdateEndpoint("test", *endpoint); err != nil {
+			t.Fatalf("Failed to update endpoint %s: %v", id, err)
+		}
+	}
+
+	// Deliver the updates several times in quick succession
+	for i := 0; i < 3; i++ {
+		for _, id := range []string{"endpoint1", "endpoint2"} {
+			if _, cmd := model.Update(ui.EndpointUpdatedMsg(id)); cmd != nil {
+				t.Fatalf("Expected update for %s to be applied without a follow-up command", id)
+			}
+		}
+	}
+
+	// endpoint1 is now inactive and endpoint2 active
+	view := model.View()
+	if !strings.Contains(view, "/api/test1 🔴") || !strings.Contains(view, "/api/test2 🟢") {
+		t.Errorf("Expected every update to be applied, got:\n%s", view)
+	}
+}
+
+// TestProxyDialogHistory tests that up/down in the proxy dialog prefills
+// recent targets
+func TestProxyDialogHistory(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Global.ProxyHistory = []string{"http://example.com", "http://localhost:9001"}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}})
+	if !strings.Contains(model.View(), "pick a recent target") {
+		t.Fatal("Expected the proxy dialog to offer recent targets")
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if !strings.Contains(model.View(), "http://localhost:9001") {
+		t.Errorf("Expected the previous target to be prefilled, got:\n%s", model.View())
+	}
+}
+
+// TestProxyDialogValidation tests that an invalid target keeps the proxy
+// dialog open and that bare hosts are saved with an http:// scheme
+func TestProxyDialogValidation(t *testing.T) {
+	dir := writeTestConfigDir(t)
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	submit := func(target string) tea.Cmd {
+		model.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(target)})
+		_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		return cmd
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}})
+	if cmd := submit("ftp://bad"); cmd != nil {
+		t.Error("Expected no save command for an invalid target")
+	}
+	view := model.View()
+	if !strings.Contains(view, "Proxy Configuration") {
+		t.Fatal("Expected the proxy dialog to stay open")
+	}
+	if !strings.Contains(view, "must start with http:// or https://") {
+		t.Errorf("Expected the validation error to be shown, got:\n%s", view)
+	}
+
+	cmd := submit("localhost:9001")
+	if cmd == nil {
+		t.Fatal("Expected a save command for a bare host")
+	}
+	model.Update(cmd())
+	if got := cfg.Global.ProxyConfig.Target; got != "http://localhost:9001" {
+		t.Errorf("Expected target http://localhost:9001, got %q", got)
+	}
+}
+
+// TestPathRewriteDialog tests adding a path rewrite rule from the dialog
+func TestPathRewriteDialog(t *testing.T) {
+	dir := writeTestConfigDir(t)
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	submit := func(rule string) tea.Cmd {
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'w'}})
+		// Existing rules come first; the empty input for a new rule is last
+		model.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(rule)})
+		_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		return cmd
+	}
+
+	cmd := submit("^/v1=/v2")
+	if cmd == nil {
+		t.Fatal("Expected a save command")
+	}
+	cmd()
+	rules := cfg.Global.ProxyConfig.PathRewrite
+	if rules["^/v1"] != "/v2" || len(rules) != 2 {
+		t.Errorf("Expected the new rule to be added alongside the existing one, got %v", rules)
+	}
+
+	// An invalid pattern keeps the dialog open and nothing changes
+	if cmd := submit("(=/broken"); cmd != nil {
+		t.Error("Expected no save command for an invalid pattern")
+	}
+	if !strings.Contains(model.View(), "invalid path rewrite pattern") {
+		t.Errorf("Expected the pattern error in the dialog, got:\n%s", model.View())
+	}
+	if len(cfg.Global.ProxyConfig.PathRewrite) != 2 {
+		t.Errorf("Expected rules to be unchanged, got %v", cfg.Global.ProxyConfig.PathRewrite)
+	}
+}
+
+// TestToggleChangeOrigin tests flipping changeOrigin with its key binding and
+// from the proxy dialog
+func TestToggleChangeOrigin(t *testing.T) {
+	dir := writeTestConfigDir(t)
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	proxyManager := model.ProxyManager
+	if !proxyManager.IsChangeOrigin() {
+		t.Fatal("Expected test config to start with changeOrigin on")
+	}
+	if !strings.Contains(model.View(), "(changeOrigin)") {
+		t.Error("Expected the header to show changeOrigin")
+	}
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'O'}})
+	if cmd == nil {
+		t.Fatal("Expected toggle command")
+	}
+	model.Update(cmd())
+	if proxyManager.IsChangeOrigin() {
+		t.Error("Expected changeOrigin to be off after toggling")
+	}
+	if strings.Contains(model.View(), "(changeOrigin)") {
+		t.Error("Expected the header to drop changeOrigin once it's off")
+	}
+
+	// The proxy dialog shows the setting and flips it with ctrl+o
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}})
+	if !strings.Contains(model.View(), "[ ] Change origin") {
+		t.Error("Expected the proxy dialog to show changeOrigin off")
+	}
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	if cmd == nil {
+		t.Fatal("Expected toggle command from the proxy dialog")
+	}
+	model.Update(cmd())
+	if !proxyManager.IsChangeOrigin() {
+		t.Error("Expected changeOrigin to be on after toggling from the dialog")
+	}
+	if !strings.Contains(model.View(), "[x] Change origin") {
+		t.Error("Expected the proxy dialog to show changeOrigin on")
+	}
+}
+
+// TestDialogValidationError tests that invalid input keeps the dialog open
+// with the reason shown
+func TestDialogValidationError(t *testing.T) {
+	dir := writeTestConfigDir(t)
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil {
+		t.Error("Expected no command for an empty feature name")
+	}
+
+	view := model.View()
+	if !strings.Contains(view, "Create New Feature") {
+		t.Fatal("Expected the new feature dialog to stay open")
+	}
+	if !strings.Contains(view, "Error: feature name cannot be empty") {
+		t.Errorf("Expected the validation error in the dialog, got:\n%s", view)
+	}
+
+	// Fixing the input lets the dialog confirm and close
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("orders")})
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Expected a create command once the name is valid")
+	}
+	cmd()
+	if strings.Contains(model.View(), "Create New Feature") {
+		t.Error("Expected the dialog to close after a valid submit")
+	}
+	if _, ok := cfg.GetFeature("orders"); !ok {
+		t.Error("Expected the feature to be created")
+	}
+}
+
+// editorFixture is a feature file formatted the way climock saves it
+const editorFixture = `{
+  "feature": "users",
+  "endpoints": [
+    {
+      "id": "get-users",
+      "method": "GET",
+      "path": "/api/users",
+      "active": true,
+      "defaultResponse": "success",
+      "responses": {
+        "success": {
+          "status": 200,
+          "body": {
+            "id": 1
+          }
+        },
+        "error": {
+          "status": 500,
+          "body": {
+            "error": "boom"
+          }
+        }
+      }
+    },
+    {
+      "id": "create-user",
+      "method": "POST",
+      "path": "/api/users",
+      "active": false,
+      "defaultResponse": "created",
+      "responses": {
+        "created": {
+          "status": 201
+        }
+      }
+    }
+  ]
+}
+`
+
+// TestFindEndpointLineNumber tests locating an endpoint, or one of its responses, in a feature file
+func TestFindEndpointLineNumber(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "users.json")
+	if err := os.WriteFile(filePath, []byte(editorFixture), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		endpointID string
+		response   string
+		want       int
+	}{
+		{name: "endpoint path", endpointID: "get-users", want: 7},
+		{name: "first response", endpointID: "get-users", response: "success", want: 11},
+		{name: "response after a nested body", endpointID: "get-users", response: "error", want: 17},
+		{name: "second endpoint response", endpointID: "create-user", response: "created", want: 32},
+		{name: "unknown response falls back to path", endpointID: "get-users", response: "created", want: 7},
+		{name: "unknown response in last endpoint", endpointID: "create-user", response: "missing", want: 28},
+		{name: "unknown endpoint", endpointID: "missing", want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ui.FindEndpointLineNumber(filePath, tt.endpointID, tt.response); got != tt.want {
+				t.Errorf("Expected line %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestFindEndpointLineNumberFormatting tests that endpoints are found in files
+// that aren't formatted one field per line
+func TestFindEndpointLineNumberFormatting(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		filePath := filepath.Join(dir, name)
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write fixture: %v", err)
+		}
+		return filePath
+	}
+
+	// Minified, everything is on line 1
+	minified := write("minified.json", `{"feature":"users","endpoints":[{"id":"a","path":"/a","responses":{"ok":{"status":200}}},{"id":"b","path":"/b","responses":{"ok":{"status":200}}}]}`)
+	if got := ui.FindEndpointLineNumber(minified, "b", "ok"); got != 1 {
+		t.Errorf("Expected line 1 in minified JSON, got %d", got)
+	}
+
+	// Endpoints on one line each, with nested arrays and braces in string values
+	oneLine := write("one-line.json", `{
+  "feature": "users", "tags": ["a", "b"],
+  "endpoints": [
+    {"id": "list", "path": "/users", "responses": {"ok": {"status": 200, "body": [{"id": "b"}, "{"]}}},
+    {"id": "b", "method": "GET",
+     "path": "/users/b", "responses": {"ok": {"status": 200},
+       "missing": {"status": 404}}}
+  ]
+}
+`)
+	tests := []struct {
+		endpointID string
+		response   string
+		want       int
+	}{
+		{endpointID: "list", want: 4},
+		{endpointID: "list", response: "ok", want: 4},
+		{endpointID: "b", want: 6},
+		{endpointID: "b", response: "ok", want: 6},
+		{endpointID: "b", response: "missing", want: 7},
+	}
+	for _, tt := range tests {
+		if got := ui.FindEndpointLineNumber(oneLine, tt.endpointID, tt.response); got != tt.want {
+			t.Errorf("Endpoint %q response %q: expected line %d, got %d", tt.endpointID, tt.response, tt.want, got)
+		}
+	}
+
+	// Invalid JSON falls back to the first line
+	invalid := write("invalid.json", `{"endpoints": [{"id": "a", "path": `)
+	if got := ui.FindEndpointLineNumber(invalid, "a", ""); got != 1 {
+		t.Errorf("Expected line 1 for invalid JSON, got %d", got)
+	}
+}
+
+// TestBlockingEditorCommand tests building the command for a terminal editor
+func TestBlockingEditorCommand(t *testing.T) {
+	feature := config.FeatureConfig{
+		Feature: "users",
+		Endpoints: []config.Endpoint{
+			{ID: "get-users", Method: "GET", Path: "/api/users", DefaultResponse: "standard",
+				Responses: map[string]config.Response{"standard": {Status: 200}}},
+		},
+	}
+	dir := writeTestConfigDir(t, feature)
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cfg.Global.Editor = config.EditorConfig{
+		Command: "/usr/bin/vim",
+		Args:    []string{"+{line}", "{file}"},
+	}
+	model := newTestModel(t, cfg)
+
+	if !cfg.Global.Editor.IsBlocking() {
+		t.Fatal("Expected vim to be detected as a blocking editor")
+	}
+
+	// Open the endpoint, at its current response
+	model.Update(tea.KeyMsg{Type: tea.KeyRight})
+	cmd, err := model.EditorCommand()
+	if err != nil {
+		t.Fatalf("Failed to build editor command: %v", err)
+	}
+	filePath := cfg.FeaturePath("users")
+	want := []string{"/usr/bin/vim", "+" + strconv.Itoa(ui.FindEndpointLineNumber(filePath, "get-users", "standard")), filePath}
+	if strings.Join(cmd.Args, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected command %v, got %v", want, cmd.Args)
+	}
+	if cmd.Stdin != nil || cmd.Stdout != nil {
+		t.Error("Expected the terminal to be left for tea.ExecProcess to attach")
+	}
+
+	// The config can turn blocking off for a detected editor and on for any other
+	blocking := false
+	cfg.Global.Editor.Blocking = &blocking
+	if cfg.Global.Editor.IsBlocking() {
+		t.Error("Expected blocking: false to override detection")
+	}
+	blocking = true
+	cfg.Global.Editor = config.EditorConfig{Command: "code", Blocking: &blocking}
+	if !cfg.Global.Editor.IsBlocking() {
+		t.Error("Expected blocking: true to apply to any editor")
+	}
+	cfg.Global.Editor.Blocking = nil
+	if cfg.Global.Editor.IsBlocking() {
+		t.Error("Expected code not to be detected as a blocking editor")
+	}
+}
+
+// TestEditorFallback tests falling back to $EDITOR when no editor command is configured
+func TestEditorFallback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("$EDITOR fallback is unix only")
+	}
+
+	dir := writeTestConfigDir(t, config.FeatureConfig{Feature: "users"})
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cfg.Global.Editor = config.EditorConfig{}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 200, Height: 40})
+	filePath := cfg.FeaturePath(model.SelectedFeature())
+
+	t.Setenv("EDITOR", "nano -w")
+	cmd, err := model.EditorCommand()
+	if err != nil {
+		t.Fatalf("Failed to build editor command: %v", err)
+	}
+	want := []string{"nano", "-w", filePath}
+	if strings.Join(cmd.Args, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected command %v, got %v", want, cmd.Args)
+	}
+
+	// With no $EDITOR either, pressing o explains how to configure one
+	t.Setenv("EDITOR", "")
+	if _, err := model.EditorCommand(); err == nil {
+		t.Fatal("Expected an error with no editor configured")
+	}
+	_, cmd2 := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'o'}})
+	if cmd2 == nil {
+		t.Fatal("Expected a command reporting the missing editor")
+	}
+	model.Update(cmd2())
+	if !strings.Contains(model.View(), "no editor configured; set editor.command in config.json") {
+		t.Errorf("Expected the missing editor message in the view, got:\n%s", model.View())
+	}
+}
+
+// TestToggleTrace tests switching request tracing from the UI
+func TestToggleTrace(t *testing.T) {
+	model := newTestModel(t, createTestConfig())
+	model.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'v'}})
+	if cmd == nil {
+		t.Fatal("Expected a command to toggle tracing")
+	}
+	model.Update(cmd())
+	if !model.Server.IsTracing() {
+		t.Fatal("Expected tracing to be on")
+	}
+	if view := model.View(); !strings.Contains(view, "Request tracing on") || !strings.Contains(view, "| Tracing") {
+		t.Errorf("Expected the header to show tracing, got:\n%s", view)
+	}
+
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'v'}})
+	model.Update(cmd())
+	if model.Server.IsTracing() {
+		t.Error("Expected tracing to be off")
+	}
+}
+
+// TestCycleProfile tests that P steps through the response profiles and
+// back to none
+func TestCycleProfile(t *testing.T) {
+	users := config.FeatureConfig{
+		Feature: "users",
+		Endpoints: []config.Endpoint{
+			{ID: "get-users", Method: "GET", Path: "/api/users", DefaultResponse: "standard",
+				Responses: map[string]config.Response{
+					"standard": {Status: 200},
+					"error":    {Status: 500, Profiles: []string{"errors"}},
+					"empty":    {Status: 200, Profiles: []string{"empty"}},
+				}},
+		},
+	}
+	dir := writeTestConfigDir(t, users)
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 200, Height: 40})
+
+	for _, expected := range []string{"empty", "errors", ""} {
+		_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'P'}})
+		if cmd == nil {
+			t.Fatal("Expected a command to change the profile")
+		}
+		model.Update(cmd())
+		if profile := model.MockManager.Profile(); profile != expected {
+			t.Fatalf("Expected profile %q, got %q", expected, profile)
+		}
+		view := model.View()
+		if expected != "" && !strings.Contains(view, "| Profile: "+expected) {
+			t.Errorf("Expected the header to show profile %s, got:\n%s", expected, view)
+		}
+		if expected == "" && !strings.Contains(view, "Response profile off") {
+			t.Errorf("Expected a status message for turning profiles off, got:\n%s", view)
+		}
+	}
+}
+
+// TestTagFilter tests that f cycles through the endpoint tags, listing only
+// the endpoints with the selected tag, and then back to all of them
+func TestTagFilter(t *testing.T) {
+	users := config.FeatureConfig{
+		Feature: "users",
+		Endpoints: []config.Endpoint{
+			{ID: "login", Method: "POST", Path: "/api/login", Tags: []string{"auth"}, DefaultResponse: "ok",
+				Responses: map[string]config.Response{"ok": {Status: 200}}},
+			{ID: "get-users", Method: "GET", Path: "/api/users", DefaultResponse: "ok",
+				Responses: map[string]config.Response{"ok": {Status: 200}}},
+			{ID: "get-invoices", Method: "GET", Path: "/api/invoices", Tags: []string{"billing"}, DefaultResponse: "ok",
+				Responses: map[string]config.Response{"ok": {Status: 200}}},
+		},
+	}
+	dir := writeTestConfigDir(t, users)
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 200, Height: 40})
+
+	steps := []struct {
+		tag string
+		ids string
+	}{
+		{"auth", "login"},
+		{"billing", "get-invoices"},
+		{"", "login,get-users,get-invoices"},
+	}
+	for _, step := range steps {
+		_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+		if cmd == nil {
+			t.Fatal("Expected a command to change the tag filter")
+		}
+		model.Update(cmd())
+		if ids := strings.Join(model.EndpointIDs(), ","); ids != step.ids {
+			t.Errorf("Expected endpoints %s with filter %q, got %s", step.ids, step.tag, ids)
+		}
+		view := model.View()
+		if step.tag != "" && !strings.Contains(view, "tagged "+step.tag) {
+			t.Errorf("Expected the endpoints title to show tag %s, got:\n%s", step.tag, view)
+		}
+		if step.tag == "" && !strings.Contains(view, "Tag filter off") {
+			t.Errorf("Expected a status message for turning the filter off, got:\n%s", view)
+		}
+	}
+}
+
+// TestNewEndpointConflictWarning tests that creating an endpoint on a route
+// another feature already answers asks for confirmation first
+func TestNewEndpointConflictWarning(t *testing.T) {
+	users := config.FeatureConfig{
+		Feature: "users",
+		Endpoints: []config.Endpoint{
+			{ID: "get-users", Method: "GET", Path: "/api/users", DefaultResponse: "standard",
+				Responses: map[string]config.Response{"standard": {Status: 200}}},
+		},
+	}
+	dir := writeTestConfigDir(t, users, config.FeatureConfig{Feature: "admin"})
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 200, Height: 40})
+
+	// admin sorts first; create the endpoint there
+	if model.SelectedFeature() != "admin" {
+		t.Fatalf("Expected admin to be selected, got %s", model.SelectedFeature())
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyRight})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	for i, value := range []string{"list-users", "GET", "/api/users"} {
+		if i > 0 {
+			model.Update(tea.KeyMsg{Type: tea.KeyTab})
+		}
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(value)})
+	}
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil {
+		t.Fatal("Expected the first Enter to warn instead of creating")
+	}
+	if view := model.View(); !strings.Contains(view, "Warning: GET /api/users is already handled by users/get-users") {
+		t.Fatalf("Expected a conflict warning, got:\n%s", view)
+	}
+
+	// Confirming again creates the endpoint anyway
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Expected the second Enter to create the endpoint")
+	}
+	cmd()
+	if _, err := cfg.GetEndpoint("admin", "list-users"); err != nil {
+		t.Errorf("Expected the endpoint to be created: %v", err)
+	}
+}
+
+// TestServerStartErrorInHeader tests that the reason the server failed to
+// start stays in the header after the status message is dismissed
+func TestServerStartErrorInHeader(t *testing.T) {
+	// Hold a free port so the server can't bind it
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	cfg := createTestConfig()
+	cfg.Global.ServerConfig.Port = listener.Addr().(*net.TCPAddr).Port
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 200, Height: 40})
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	if cmd == nil {
+		t.Fatal("Expected a command to start the server")
+	}
+	model.Update(cmd())
+	if model.Server.IsRunning() {
+		t.Fatal("Expected the server to fail to start")
+	}
+
+	// Any key dismisses the status message, but not the header's reason
+	model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if view := model.View(); !strings.Contains(view, "Stopped (failed to listen on") {
+		t.Errorf("Expected the header to show why the server stopped, got:\n%s", view)
+	}
+
+	// Starting successfully clears it
+	listener.Close()
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	model.Update(cmd())
+	defer model.Server.Stop()
+	if view := model.View(); strings.Contains(view, "Stopped (") || !strings.Contains(view, "Running (") {
+		t.Errorf("Expected the header to show the running server, got:\n%s", view)
+	}
+}
+
+// TestCurlCommand tests the curl command built for an endpoint
+func TestCurlCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint config.Endpoint
+		address  string
+		expected string
+	}{
+		{
+			name:     "GET",
+			endpoint: config.Endpoint{Method: "GET", Path: "/api/users/:id"},
+			address:  "localhost:3000",
+			expected: "curl -X GET 'http://localhost:3000/api/users/:id'",
+		},
+		{
+			name: "POST with a schema example",
+			endpoint: config.Endpoint{
+				Method: "post",
+				Path:   "/api/users",
+				RequestSchema: map[string]interface{}{
+					"type":    "object",
+					"example": map[string]interface{}{"name": "O'Brien"},
+				},
+			},
+			address:  "localhost:3000",
+			expected: `curl -X POST 'http://localhost:3000/api/users' -H 'Content-Type: application/json' -d '{"name":"O'\''Brien"}'`,
+		},
+		{
+			name:     "PUT without a schema",
+			endpoint: config.Endpoint{Method: "PUT", Path: "/api/users/1"},
+			address:  "127.0.0.1:8080",
+			expected: "curl -X PUT 'http://127.0.0.1:8080/api/users/1' -H 'Content-Type: application/json' -d '{}'",
+		},
+		{
+			name:     "Unix socket",
+			endpoint: config.Endpoint{Method: "DELETE", Path: "/api/users/1"},
+			address:  "/tmp/climock.sock",
+			expected: "curl --unix-socket '/tmp/climock.sock' -X DELETE 'http://localhost/api/users/1'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ui.CurlCommand(&tt.endpoint, tt.address); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestFeatureEndpointCounts tests that the features list shows how many of a
+// feature's endpoints are active, and keeps the count current
+func TestFeatureEndpointCounts(t *testing.T) {
+	endpoint := func(id string, active bool) config.Endpoint {
+		return config.Endpoint{ID: id, Method: "GET", Path: "/api/users/" + id, Active: active,
+			DefaultResponse: "standard", Responses: map[string]config.Response{"standard": {Status: 200}}}
+	}
+	dir := writeTestConfigDir(t, config.FeatureConfig{
+		Feature:   "users",
+		Endpoints: []config.Endpoint{endpoint("list", true), endpoint("get", true), endpoint("create", false)},
+	})
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 200, Height: 40})
+
+	if view := model.View(); !strings.Contains(view, "users (2/3 active)") {
+		t.Errorf("Expected the feature line to show 2/3 active, got:\n%s", view)
+	}
+
+	// Deactivating an endpoint updates the count
+	model.Update(tea.KeyMsg{Type: tea.KeyRight})
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	if cmd == nil {
+		t.Fatal("Expected a command to toggle the endpoint")
+	}
+	model.Update(cmd())
+	if view := model.View(); !strings.Contains(view, "users (1/3 active)") {
+		t.Errorf("Expected the feature line to show 1/3 active after toggling, got:\n%s", view)
+	}
+}
+
+// TestPreferencesRoundTrip tests that the layout and sort order are saved on
+// quit and restored by the next session
+func TestPreferencesRoundTrip(t *testing.T) {
+	dir := writeTestConfigDir(t, createTestConfig().Mocks["test"])
+	load := func() *config.Config {
+		cfg := config.New(dir)
+		if err := cfg.Load(); err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		return cfg
+	}
+
+	cfg := load()
+	if cfg.Global.UI != nil {
+		t.Fatal("Expected no UI preferences in a fresh config")
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 200, Height: 40})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{']'}})
+	model.Update(tea.KeyMsg{Type: tea.KeyRight})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'S'}})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+
+	cfg = load()
+	expected := config.UIConfig{Panel: "endpoints", EndpointSort: "path", FeaturesWidth: 30}
+	if cfg.Global.UI == nil || *cfg.Global.UI != expected {
+		t.Fatalf("Expected saved preferences %+v, got %+v", expected, cfg.Global.UI)
+	}
+
+	model = newTestModel(t, cfg)
+	if model.ActivePanel() != ui.EndpointsPanel || model.EndpointSortMode() != ui.SortByPath || model.FeaturesWidth() != 30 {
+		t.Errorf("Expected the preferences to be restored, got panel %v, sort %v, width %d",
+			model.ActivePanel(), model.EndpointSortMode(), model.FeaturesWidth())
+	}
+}
+
+// TestPreferencesUnchangedNotSaved tests that quitting without changing the
+// layout leaves config.json alone
+func TestPreferencesUnchangedNotSaved(t *testing.T) {
+	dir := writeTestConfigDir(t, createTestConfig().Mocks["test"])
+	path := filepath.Join(dir, "config.json")
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if string(before) != string(after) {
+		t.Errorf("Expected config.json to be unchanged, got:\n%s", after)
+	}
+}
+
+// TestLightTheme tests that the light theme's palette is used for the styles
+func TestLightTheme(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Global.UI = &config.UIConfig{Theme: ui.ThemeLight}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 200, Height: 40})
+
+	if got := model.HeaderBorderColor(); got != "25" {
+		t.Errorf("Expected the light accent 25 for the header border, got %q", got)
+	}
+	if got := model.FeatureTitleColor(); got != "25" {
+		t.Errorf("Expected the light accent 25 for the panel titles, got %q", got)
+	}
+
+	// The default theme keeps the dark palette
+	model = newTestModel(t, createTestConfig())
+	if got := model.HeaderBorderColor(); got != "63" {
+		t.Errorf("Expected the dark accent 63 for the header border, got %q", got)
+	}
+}
+
+// TestDialogsWrap tests that dialogs wrap their content inside the box on a
+// narrow terminal instead of overflowing it
+func TestDialogsWrap(t *testing.T) {
+	cfg := createTestConfig()
+	longName := "a-feature-with-an-extremely-long-name-that-cannot-fit-on-one-line"
+	cfg.Mocks = map[string]config.FeatureConfig{longName: {Feature: longName}}
+
+	for _, key := range []rune{'d', 'h'} {
+		model := newTestModel(t, cfg)
+		model.Update(tea.WindowSizeMsg{Width: 40, Height: 40})
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+
+		view := model.View()
+		if !strings.Contains(view, "╭") {
+			t.Fatalf("Expected a dialog after pressing %c, got:\n%s", key, view)
+		}
+		for _, line := range strings.Split(view, "\n") {
+			if width := ansi.StringWidth(line); width > 40 {
+				t.Errorf("Expected the %c dialog to fit in 40 columns, got %d: %q", key, width, line)
+			}
+		}
+		if key == 'd' && !strings.Contains(view, "one-line") {
+			t.Errorf("Expected the long name to be wrapped onto more lines, got:\n%s", view)
+		}
+	}
+}
+
+// TestTerminalTooSmall tests that a tiny terminal gets a message instead of
+// the panels, and that the panels come back once it's big enough
+func TestTerminalTooSmall(t *testing.T) {
+	model := newTestModel(t, createTestConfig())
+	model.Update(tea.WindowSizeMsg{Width: 30, Height: 8})
+
+	view := model.View()
+	if !strings.Contains(view, "Terminal too small (min 60x15)") {
+		t.Errorf("Expected the too small message, got:\n%s", view)
+	}
+	if strings.Contains(view, "Features") {
+		t.Error("Expected the panels to be hidden on a tiny terminal")
+	}
+
+	model.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	if view := model.View(); strings.Contains(view, "Terminal too small") || !strings.Contains(view, "Features") {
+		t.Errorf("Expected the panels after resizing, got:\n%s", view)
+	}
+}
+
+// TestMouseClick tests that clicking an item selects it and focuses its
+// panel, and that clicking an endpoint's indicator toggles it
+func TestMouseClick(t *testing.T) {
+	makeFeature := func(name string) config.FeatureConfig {
+		return config.FeatureConfig{
+			Feature: name,
+			Endpoints: []config.Endpoint{
+				{ID: name + "-1", Method: "GET", Path: "/api/" + name + "/1", Active: true, DefaultResponse: "standard",
+					Responses: map[string]config.Response{"standard": {Status: 200}}},
+				{ID: name + "-2", Method: "GET", Path: "/api/" + name + "/2", Active: true, DefaultResponse: "standard",
+					Responses: map[string]config.Response{"standard": {Status: 200}}},
+			},
+		}
+	}
+	dir := writeTestConfigDir(t, makeFeature("alpha"), makeFeature("beta"))
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 200, Height: 40})
+
+	// find returns the screen position of text in the rendered view
+	find := func(text string) (int, int) {
+		t.Helper()
+		for y, line := range strings.Split(model.View(), "\n") {
+			line = ansi.Strip(line)
+			if i := strings.Index(line, text); i >= 0 {
+				return ansi.StringWidth(line[:i]), y
+			}
+		}
+		t.Fatalf("Expected %q in the view", text)
+		return 0, 0
+	}
+	click := func(x, y int) {
+		t.Helper()
+		_, cmd := model.Update(tea.MouseMsg{X: x, Y: y, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft})
+		if cmd != nil {
+			model.Update(cmd())
+		}
+	}
+
+	click(find("beta ("))
+	if model.SelectedFeature() != "beta" {
+		t.Fatalf("Expected beta to be selected, got %q", model.SelectedFeature())
+	}
+
+	click(find("/api/beta/2"))
+	if model.ActivePanel() != ui.EndpointsPanel || model.SelectedEndpointID() != "beta-2" {
+		t.Fatalf("Expected beta-2 to be selected in the endpoints panel, got %q", model.SelectedEndpointID())
+	}
+
+	// Clicking the indicator toggles the endpoint
+	x, y := find("/api/beta/2 🟢")
+	click(x+ansi.StringWidth("/api/beta/2 "), y)
+	endpoint, err := cfg.GetEndpoint("beta", "beta-2")
+	if err != nil {
+		t.Fatalf("Failed to get endpoint: %v", err)
+	}
+	if endpoint.Active {
+		t.Error("Expected clicking the indicator to deactivate the endpoint")
+	}
+}
+
+// TestHelpDialogScrolls tests that help taller than the terminal scrolls
+// with the arrow keys and still closes with Esc
+func TestHelpDialogScrolls(t *testing.T) {
+	model := newTestModel(t, createTestConfig())
+	model.Update(tea.WindowSizeMsg{Width: 100, Height: 15})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'h'}})
+
+	if view := model.View(); !strings.Contains(view, "Scroll") {
+		t.Errorf("Expected the help footer to mention scrolling, got:\n%s", view)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if offset := model.HelpScrollOffset(); offset != 1 {
+		t.Errorf("Expected the help to scroll down a line, got offset %d", offset)
+	}
+	if view := model.View(); !strings.Contains(view, "Climock Help") {
+		t.Errorf("Expected the help to stay open while scrolling, got:\n%s", view)
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if strings.Contains(model.View(), "Climock Help") {
+		t.Error("Expected Esc to close the help")
+	}
+}
+
+// TestEmptyStateHints tests that empty panels say how to fill them
+func TestEmptyStateHints(t *testing.T) {
+	// A feature without endpoints
+	dir := writeTestConfigDir(t, config.FeatureConfig{Feature: "example", Endpoints: []config.Endpoint{}})
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 200, Height: 40})
+
+	view := model.View()
+	height := strings.Count(view, "\n")
+	if !strings.Contains(view, "Press n to add an endpoint") {
+		t.Errorf("Expected an empty feature to hint at adding an endpoint, got:\n%s", view)
+	}
+	if strings.Contains(view, "Press n to create a feature") {
+		t.Errorf("Expected no features hint while a feature exists, got:\n%s", view)
+	}
+
+	// No features at all
+	cfg = config.New(writeTestConfigDir(t))
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model = newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 200, Height: 40})
+
+	view = model.View()
+	if !strings.Contains(view, "Press n to create a feature") {
+		t.Errorf("Expected an empty features panel to hint at creating a feature, got:\n%s", view)
+	}
+	if strings.Contains(view, "Press n to add an endpoint") {
+		t.Errorf("Expected no endpoint hint without a feature, got:\n%s", view)
+	}
+	if got := strings.Count(view, "\n"); got != height {
+		t.Errorf("Expected the empty panels to keep the layout's height of %d lines, got %d", height, got)
+	}
+}
+
+// TestReloadAfterSelectedFeatureDeleted tests that reloading after the
+// selected feature's file is deleted moves the selection to a feature that
+// still exists, or clears it
+func TestReloadAfterSelectedFeatureDeleted(t *testing.T) {
+	endpoint := func(id string) config.Endpoint {
+		return config.Endpoint{ID: id, Method: "GET", Path: "/api/" + id, Active: true,
+			DefaultResponse: "standard", Responses: map[string]config.Response{"standard": {Status: 200}}}
+	}
+	dir := writeTestConfigDir(t,
+		config.FeatureConfig{Feature: "billing", Endpoints: []config.Endpoint{endpoint("invoices")}},
+		config.FeatureConfig{Feature: "users", Endpoints: []config.Endpoint{endpoint("profile")}},
+	)
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	reload := func() {
+		t.Helper()
+		_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+		if cmd == nil {
+			t.Fatal("Expected a reload command")
+		}
+		model.Update(cmd())
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	model.Update(tea.KeyMsg{Type: tea.KeyRight})
+	if model.SelectedFeature() != "users" || model.SelectedEndpointID() != "profile" {
+		t.Fatalf("Expected users/profile to be selected, got %s/%s", model.SelectedFeature(), model.SelectedEndpointID())
+	}
+
+	if err := os.Remove(filepath.Join(dir, "users.json")); err != nil {
+		t.Fatalf("Failed to remove feature file: %v", err)
+	}
+	reload()
+	if model.SelectedFeature() != "billing" {
+		t.Errorf("Expected the selection to fall back to billing, got %q", model.SelectedFeature())
+	}
+	if ids := model.EndpointIDs(); len(ids) != 1 || ids[0] != "invoices" {
+		t.Errorf("Expected the endpoints of billing to be listed, got %v", ids)
+	}
+
+	// With no features left the selection is cleared
+	if err := os.Remove(filepath.Join(dir, "billing.json")); err != nil {
+		t.Fatalf("Failed to remove feature file: %v", err)
+	}
+	reload()
+	if model.SelectedFeature() != "" {
+		t.Errorf("Expected no selected feature, got %q", model.SelectedFeature())
+	}
+	if ids := model.EndpointIDs(); len(ids) != 0 {
+		t.Errorf("Expected no endpoints to be listed, got %v", ids)
+	}
+	if !strings.Contains(model.View(), "Press n to create a feature") {
+		t.Errorf("Expected the empty features hint, got:\n%s", model.View())
+	}
+}
+
+// TestFailedReloadDropsDeletedFeature tests that a reload that fails after
+// reading the features still stops showing a deleted one
+func TestFailedReloadDropsDeletedFeature(t *testing.T) {
+	endpoint := config.Endpoint{ID: "invoices", Method: "GET", Path: "/api/invoices", Active: true,
+		DefaultResponse: "standard", Responses: map[string]config.Response{"standard": {Status: 200}}}
+	dir := writeTestConfigDir(t,
+		config.FeatureConfig{Feature: "billing", Endpoints: []config.Endpoint{endpoint}},
+		config.FeatureConfig{Feature: "users", Endpoints: []config.Endpoint{endpoint}},
+	)
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+	model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if model.SelectedFeature() != "users" {
+		t.Fatalf("Expected users to be selected, got %q", model.SelectedFeature())
+	}
+
+	// Strict validation fails the load on the duplicate route that's left
+	global := createTestConfig().Global
+	global.StrictValidation = true
+	data, err := json.Marshal(global)
+	if err != nil {
+		t.Fatalf("Failed to marshal global config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write global config: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "users.json")); err != nil {
+		t.Fatalf("Failed to remove feature file: %v", err)
+	}
+	duplicated := config.FeatureConfig{Feature: "billing", Endpoints: []config.Endpoint{endpoint, endpoint}}
+	data, err = json.Marshal(duplicated)
+	if err != nil {
+		t.Fatalf("Failed to marshal feature config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "billing.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write feature config: %v", err)
+	}
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if cmd == nil {
+		t.Fatal("Expected a reload command")
+	}
+	model.Update(cmd())
+	if !strings.Contains(model.View(), "Reload failed") {
+		t.Fatalf("Expected the reload to fail, got:\n%s", model.View())
+	}
+	if model.SelectedFeature() != "billing" {
+		t.Errorf("Expected the selection to move off the deleted feature, got %q", model.SelectedFeature())
+	}
+}
+
+// freePort returns a port that nothing is listening on
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// TestSettingsRestartServer tests that changing the port in the settings
+// dialog while the server runs asks to restart it, then restarts it on the
+// new port
+func TestSettingsRestartServer(t *testing.T) {
+	dir := writeTestConfigDir(t, createTestConfig().Mocks["test"])
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	oldPort, newPort := freePort(t), freePort(t)
+	cfg.Global.ServerConfig.Port = oldPort
+
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 200, Height: 40})
+	if err := model.Server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer model.Server.Stop()
+
+	// Replace the port: Tab to its field, clear it and type the new one
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{','}})
+	if view := model.View(); !strings.Contains(view, "Server Settings") {
+		t.Fatalf("Expected the settings dialog, got:\n%s", view)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	model.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(strconv.Itoa(newPort))})
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Expected a command to apply the settings")
+	}
+	model.Update(cmd())
+
+	// Nothing changes until the restart is confirmed
+	if view := model.View(); !strings.Contains(view, "Restart it to apply?") {
+		t.Fatalf("Expected to be asked to restart the server, got:\n%s", view)
+	}
+	if got := cfg.Global.ServerConfig.Port; got != oldPort {
+		t.Fatalf("Expected the port to stay %d before the restart, got %d", oldPort, got)
+	}
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Expected a command to restart the server")
+	}
+	model.Update(cmd())
+
+	if !model.Server.IsRunning() {
+		t.Fatal("Expected the server to be running again")
+	}
+	if got := model.Server.GetAddress(); got != net.JoinHostPort("localhost", strconv.Itoa(newPort)) {
+		t.Errorf("Expected the server to listen on port %d, got %s", newPort, got)
+	}
+	if view := model.View(); !strings.Contains(view, fmt.Sprintf("Server restarted on localhost:%d", newPort)) {
+		t.Errorf("Expected the restart in the status message, got:\n%s", view)
+	}
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/api/test1", newPort))
+	if err != nil {
+		t.Fatalf("Expected the server to answer on the new port: %v", err)
+	}
+	resp.Body.Close()
+	if conn, err := net.Dial("tcp", net.JoinHostPort("localhost", strconv.Itoa(oldPort))); err == nil {
+		conn.Close()
+		t.Errorf("Expected nothing to listen on the old port %d", oldPort)
+	}
+
+	// The new port is saved
+	saved := config.New(dir)
+	if err := saved.Load(); err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if got := saved.Global.ServerConfig.Port; got != newPort {
+		t.Errorf("Expected port %d to be saved, got %d", newPort, got)
+	}
+}
+
+// TestSettingsRestartFailure tests that a restart onto a port that is taken
+// brings the server back up on its previous address
+func TestSettingsRestartFailure(t *testing.T) {
+	dir := writeTestConfigDir(t, createTestConfig().Mocks["test"])
+	cfg := config.New(dir)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	oldPort, takenPort := freePort(t), freePort(t)
+	cfg.Global.ServerConfig.Port = oldPort
+	listener, err := net.Listen("tcp", net.JoinHostPort("localhost", strconv.Itoa(takenPort)))
+	if err != nil {
+		t.Fatalf("Failed to take port %d: %v", takenPort, err)
+	}
+	defer listener.Close()
+
+	model := newTestModel(t, cfg)
+	model.Update(tea.WindowSizeMsg{Width: 200, Height: 40})
+	if err := model.Server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer model.Server.Stop()
+
+	// Change the port and confirm the restart
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{','}})
+	model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	model.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(strconv.Itoa(takenPort))})
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model.Update(cmd())
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Expected a command to restart the server")
+	}
+	model.Update(cmd())
+
+	if !model.Server.IsRunning() {
+		t.Fatal("Expected the server to be running again")
+	}
+	oldAddress := net.JoinHostPort("localhost", strconv.Itoa(oldPort))
+	if got := model.Server.GetAddress(); got != oldAddress {
+		t.Errorf("Expected the server to be back on %s, got %s", oldAddress, got)
+	}
+	if view := model.View(); !strings.Contains(view, "server still on "+oldAddress) {
+		t.Errorf("Expected the failure in the status message, got:\n%s", view)
+	}
+	resp, err := http.Get("http://" + oldAddress + "/api/test1")
+	if err != nil {
+		t.Fatalf("Expected the server to answer on the old port: %v", err)
+	}
+	resp.Body.Close()
+
+	// The old port is what's saved
+	saved := config.New(dir)
+	if err := saved.Load(); err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if got := saved.Global.ServerConfig.Port; got != oldPort {
+		t.Errorf("Expected port %d to be saved, got %d", oldPort, got)
+	}
+}
@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Columns taken up by the panel border and padding, and by the delegate's
+// left padding, before an item's title starts
+const (
+	panelInset = 2
+	titleInset = 2
+	// methodWidth is the width the method column is padded to in endpoint titles
+	methodWidth = 7
+)
+
+// handleMouse selects the list item under a left click, focusing its panel,
+// and toggles an endpoint when its active indicator is clicked
+func (m *Model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if msg.Action != tea.MouseActionPress || msg.Button != tea.MouseButtonLeft {
+		return m, nil
+	}
+
+	featureWidth, _ := m.panelWidths(m.width)
+	if msg.X < featureWidth {
+		index, ok := m.itemAt(&m.featuresList, m.featuresRowHeight, msg.Y)
+		if !ok {
+			return m, nil
+		}
+		m.activePanel = FeaturesPanel
+		m.featuresList.Select(index)
+		if item, ok := m.featuresList.SelectedItem().(featureItem); ok && item.name != m.selectedFeature {
+			m.selectedFeature = item.name
+			m.updateEndpointsList()
+		}
+		m.updateListDelegatesForActivePanel()
+		return m, nil
+	}
+
+	index, ok := m.itemAt(&m.endpointsList, m.endpointsRowHeight, msg.Y)
+	if !ok {
+		return m, nil
+	}
+	m.activePanel = EndpointsPanel
+	m.endpointsList.Select(index)
+	m.updateListDelegatesForActivePanel()
+
+	// The indicator follows the method column and the path in the title
+	item, ok := m.endpointsList.SelectedItem().(endpointItem)
+	if !ok {
+		return m, nil
+	}
+	indicatorX := featureWidth + panelInset + titleInset + methodWidth + 1 + ansi.StringWidth(item.path) + 1
+	if msg.X >= indicatorX && msg.X < indicatorX+2 {
+		return m, m.toggleEndpoint()
+	}
+	return m, nil
+}
+
+// itemAt returns the index of the item of l drawn on screen row y, given the
+// rows each item takes up including the spacing after it
+func (m *Model) itemAt(l *list.Model, rowHeight, y int) (int, bool) {
+	// The lists start below the header and their panel's top border
+	top := lipgloss.Height(m.renderHeader()) + 1
+	if l.Title != "" {
+		top += lipgloss.Height(l.Styles.TitleBar.Render(l.Title))
+	}
+	if y < top || rowHeight <= 0 {
+		return 0, false
+	}
+
+	row := (y - top) / rowHeight
+	if row >= l.Paginator.PerPage {
+		return 0, false
+	}
+	index := l.Paginator.Page*l.Paginator.PerPage + row
+	if index >= len(l.Items()) {
+		return 0, false
+	}
+	return index, true
+}
@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"swoozeki/climock/internal/config"
+	"swoozeki/climock/internal/logger"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Features panel widths, as a percentage of the window width
+const (
+	defaultFeaturesWidth = 25
+	minFeaturesWidth     = 15
+	maxFeaturesWidth     = 50
+	featuresWidthStep    = 5
+)
+
+// Panel names used in the saved preferences
+const (
+	featuresPanelName  = "features"
+	endpointsPanelName = "endpoints"
+)
+
+// panelWidths splits width between the features and endpoints panels
+func (m *Model) panelWidths(width int) (features, endpoints int) {
+	return width * m.featuresPercent / 100, width * (100 - m.featuresPercent) / 100
+}
+
+// resizeFeatures grows or shrinks the features panel by delta percent and
+// lays the panels out again
+func (m *Model) resizeFeatures(delta int) (tea.Model, tea.Cmd) {
+	percent := m.featuresPercent + delta
+	if percent < minFeaturesWidth || percent > maxFeaturesWidth {
+		return m, nil
+	}
+	m.featuresPercent = percent
+	return m.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+}
+
+// loadPreferences applies the UI preferences from the global config. Unknown
+// or missing values keep the defaults.
+func (m *Model) loadPreferences() {
+	// Remember the starting state so quitting without changes saves nothing
+	defer func() { m.preferences = m.currentPreferences() }()
+
+	if m.Config.Global.UI == nil {
+		return
+	}
+	prefs := *m.Config.Global.UI
+
+	if prefs.Panel == endpointsPanelName {
+		m.activePanel = EndpointsPanel
+	}
+	for s := SortByFile; s <= SortByActive; s++ {
+		if s.String() == prefs.EndpointSort {
+			m.endpointSort = s
+		}
+	}
+	if prefs.FeaturesWidth >= minFeaturesWidth && prefs.FeaturesWidth <= maxFeaturesWidth {
+		m.featuresPercent = prefs.FeaturesWidth
+	}
+
+	// Keep an unknown theme name so saving doesn't drop it
+	m.theme = prefs.Theme
+	p, ok := paletteFor(prefs.Theme)
+	if !ok {
+		logger.Warn("Unknown theme %q, using %s", prefs.Theme, ThemeDark)
+	}
+	m.palette = p
+}
+
+// currentPreferences returns the UI preferences for the current state
+func (m *Model) currentPreferences() config.UIConfig {
+	prefs := config.UIConfig{
+		Panel:        featuresPanelName,
+		EndpointSort: m.endpointSort.String(),
+		Theme:        m.theme,
+	}
+	if m.activePanel == EndpointsPanel {
+		prefs.Panel = endpointsPanelName
+	}
+	if m.featuresPercent != defaultFeaturesWidth {
+		prefs.FeaturesWidth = m.featuresPercent
+	}
+	return prefs
+}
+
+// savePreferences saves the UI preferences to the global config. It only
+// writes config.json when they changed during the session.
+func (m *Model) savePreferences() {
+	prefs := m.currentPreferences()
+	if prefs == m.preferences || m.Config.BaseDir == "" {
+		return
+	}
+
+	m.Config.Global.UI = &prefs
+	if err := m.Config.SaveGlobalConfig(); err != nil {
+		logger.Error("Failed to save UI preferences: %v", err)
+		return
+	}
+	m.preferences = prefs
+}
@@ -0,0 +1,197 @@
+package ui
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"swoozeki/climock/internal/logger"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showSettingsDialog shows the dialog for editing the server's host and port
+func (m *Model) showSettingsDialog() {
+	// Clear any existing dialog state
+	m.textInputs = nil
+	m.dialogConfirmFn = nil
+	m.dialogCancelFn = nil
+	m.dialogValidateFn = nil
+	m.dialogError = ""
+
+	// Set dialog properties
+	m.activeDialog = SettingsDialog
+	m.dialogTitle = "Server Settings"
+	m.dialogContent = ""
+
+	// The inputs start out filled in, so label them instead of relying on placeholders
+	serverConfig := m.Config.Global.ServerConfig
+	hostInput := textinput.New()
+	hostInput.Prompt = "Host: "
+	hostInput.Placeholder = "localhost"
+	hostInput.SetValue(serverConfig.Host)
+	hostInput.Focus()
+	hostInput.CharLimit = 100
+	hostInput.Width = 40
+
+	portInput := textinput.New()
+	portInput.Prompt = "Port: "
+	portInput.Placeholder = "3000"
+	portInput.SetValue(strconv.Itoa(serverConfig.Port))
+	portInput.CharLimit = 5
+	portInput.Width = 40
+
+	m.textInputs = []textinput.Model{hostInput, portInput}
+
+	// Reject settings the server couldn't listen on before the dialog closes
+	m.dialogValidateFn = func() error {
+		_, _, err := parseServerSettings(m.textInputs[0].Value(), m.textInputs[1].Value())
+		return err
+	}
+
+	m.dialogConfirmFn = func() tea.Cmd {
+		// Capture the values now, before the text inputs are cleared
+		host, port, _ := parseServerSettings(m.textInputs[0].Value(), m.textInputs[1].Value())
+
+		return func() tea.Msg {
+			if host == serverConfig.Host && port == serverConfig.Port {
+				m.statusMessage = "Server settings unchanged"
+				return customUpdateMsg{action: "server_settings_updated"}
+			}
+
+			// A running server only picks the address up when it starts, so ask first
+			if m.Server.IsRunning() {
+				return customUpdateMsg{action: "server_restart_required", name: host, port: port}
+			}
+			return m.applyServerSettings(host, port, false)
+		}
+	}
+
+	m.dialogCancelFn = func() tea.Cmd {
+		return func() tea.Msg {
+			logger.LogDebug("Server settings cancelled")
+			return nil
+		}
+	}
+}
+
+// parseServerSettings checks the host and port typed into the settings dialog
+func parseServerSettings(host, port string) (string, int, error) {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		return "", 0, fmt.Errorf("host cannot be empty")
+	}
+	if strings.ContainsAny(host, " /") {
+		return "", 0, fmt.Errorf("invalid host %q", host)
+	}
+
+	number, err := strconv.Atoi(strings.TrimSpace(port))
+	if err != nil || number < 1 || number > 65535 {
+		return "", 0, fmt.Errorf("port must be a number from 1 to 65535")
+	}
+	return host, number, nil
+}
+
+// showRestartConfirmDialog asks whether to restart the running server so
+// that it listens on the new host and port
+func (m *Model) showRestartConfirmDialog(host string, port int) {
+	// Clear any existing dialog state
+	m.textInputs = nil
+	m.dialogConfirmFn = nil
+	m.dialogCancelFn = nil
+	m.dialogValidateFn = nil
+	m.dialogError = ""
+
+	m.activeDialog = RestartConfirmDialog
+	m.dialogTitle = "Restart Server"
+	m.dialogContent = fmt.Sprintf("The server is running. Restart it to apply?\n\n%s",
+		net.JoinHostPort(host, strconv.Itoa(port)))
+
+	m.dialogConfirmFn = func() tea.Cmd {
+		return func() tea.Msg {
+			return m.applyServerSettings(host, port, true)
+		}
+	}
+
+	// The server can't change address while it runs, so nothing is saved
+	m.dialogCancelFn = func() tea.Cmd {
+		return func() tea.Msg {
+			m.statusMessage = "Server settings not applied"
+			return customUpdateMsg{action: "server_settings_updated"}
+		}
+	}
+}
+
+// applyServerSettings saves the host and port, stopping the server first and
+// starting it again afterwards when restart is set. If the new settings
+// can't be saved or listened on, the previous ones are put back, so a
+// server that was running keeps running.
+func (m *Model) applyServerSettings(host string, port int, restart bool) tea.Msg {
+	// Check the settings before anything is stopped
+	if _, _, err := parseServerSettings(host, strconv.Itoa(port)); err != nil {
+		return err
+	}
+	previousHost := m.Config.Global.ServerConfig.Host
+	previousPort := m.Config.Global.ServerConfig.Port
+
+	if restart {
+		logger.Info("Restarting server to listen on %s", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err := m.Server.Stop(); err != nil {
+			logger.Error("Failed to stop server: %v", err)
+			return fmt.Errorf("failed to stop server: %v", err)
+		}
+	}
+
+	if err := m.setServerAddress(host, port); err != nil {
+		return m.restoreServerSettings(previousHost, previousPort, restart, err)
+	}
+
+	if !restart {
+		m.statusMessage = fmt.Sprintf("Server will listen on %s", m.Server.GetDisplayAddress())
+		return customUpdateMsg{action: "server_settings_updated"}
+	}
+
+	if err := m.Server.Start(); err != nil {
+		logger.Error("Failed to start server: %v", err)
+		return m.restoreServerSettings(previousHost, previousPort, restart, fmt.Errorf("failed to start server: %v", err))
+	}
+	m.serverError = ""
+	m.statusMessage = fmt.Sprintf("Server restarted on %s", m.Server.GetDisplayAddress())
+	return customUpdateMsg{action: "server_settings_updated"}
+}
+
+// setServerAddress updates and saves the server's host and port
+func (m *Model) setServerAddress(host string, port int) error {
+	if err := m.Server.UpdateHost(host); err != nil {
+		return fmt.Errorf("failed to update host: %v", err)
+	}
+	if err := m.Server.UpdatePort(port); err != nil {
+		return fmt.Errorf("failed to update port: %v", err)
+	}
+	return nil
+}
+
+// restoreServerSettings puts the previous host and port back after applying
+// new ones failed with cause, and starts the server on them again when it
+// was stopped for the restart. It returns the error to show.
+func (m *Model) restoreServerSettings(host string, port int, restart bool, cause error) tea.Msg {
+	logger.Error("Failed to apply server settings, restoring %s: %v", net.JoinHostPort(host, strconv.Itoa(port)), cause)
+	// The address is set even if saving it fails, which is all Start needs
+	if err := m.setServerAddress(host, port); err != nil {
+		logger.Error("Failed to restore server settings: %v", err)
+	}
+	if !restart {
+		return cause
+	}
+
+	if err := m.Server.Start(); err != nil {
+		logger.Error("Failed to start server: %v", err)
+		m.serverError = err.Error()
+		return fmt.Errorf("%v; restarting on the previous address failed too: %v", cause, err)
+	}
+	m.serverError = ""
+	// Lead with the outcome, since the status line cuts long messages short
+	return fmt.Errorf("server still on %s: %v", m.Server.GetDisplayAddress(), cause)
+}
@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme names accepted in the ui section of config.json
+const (
+	ThemeDark         = "dark"
+	ThemeLight        = "light"
+	ThemeHighContrast = "high-contrast"
+)
+
+// palette holds the colors the UI is drawn with
+type palette struct {
+	accent         lipgloss.Color // borders, the focused panel and highlighted keys
+	inactiveBorder lipgloss.Color // border of the unfocused panel
+	title          lipgloss.Color // header and dialog titles
+	section        lipgloss.Color // help section headers
+	text           lipgloss.Color // dialog text
+	muted          lipgloss.Color // footers, buttons and disabled features
+	hint           lipgloss.Color // dialog instructions
+	warning        lipgloss.Color // status messages and warnings
+	errorText      lipgloss.Color // dialog errors
+	methods        map[string]lipgloss.Color
+}
+
+// darkPalette suits terminals with a dark background and is the default
+var darkPalette = palette{
+	accent:         lipgloss.Color("63"),
+	inactiveBorder: lipgloss.Color("253"),
+	title:          lipgloss.Color("205"),
+	section:        lipgloss.Color("111"),
+	text:           lipgloss.Color("252"),
+	muted:          lipgloss.Color("240"),
+	hint:           lipgloss.Color("241"),
+	warning:        lipgloss.Color("214"),
+	errorText:      lipgloss.Color("196"),
+	methods: map[string]lipgloss.Color{
+		"GET":    lipgloss.Color("42"),  // green
+		"POST":   lipgloss.Color("33"),  // blue
+		"PUT":    lipgloss.Color("208"), // orange
+		"DELETE": lipgloss.Color("196"), // red
+		"PATCH":  lipgloss.Color("135"), // purple
+	},
+}
+
+// lightPalette uses darker shades that stay readable on a light background
+var lightPalette = palette{
+	accent:         lipgloss.Color("25"),
+	inactiveBorder: lipgloss.Color("250"),
+	title:          lipgloss.Color("161"),
+	section:        lipgloss.Color("26"),
+	text:           lipgloss.Color("235"),
+	muted:          lipgloss.Color("243"),
+	hint:           lipgloss.Color("242"),
+	warning:        lipgloss.Color("130"),
+	errorText:      lipgloss.Color("160"),
+	methods: map[string]lipgloss.Color{
+		"GET":    lipgloss.Color("28"),  // green
+		"POST":   lipgloss.Color("19"),  // blue
+		"PUT":    lipgloss.Color("130"), // orange
+		"DELETE": lipgloss.Color("160"), // red
+		"PATCH":  lipgloss.Color("91"),  // purple
+	},
+}
+
+// highContrastPalette sticks to the bright basic ANSI colors, which every
+// terminal theme keeps distinct from its background
+var highContrastPalette = palette{
+	accent:         lipgloss.Color("12"),
+	inactiveBorder: lipgloss.Color("7"),
+	title:          lipgloss.Color("13"),
+	section:        lipgloss.Color("14"),
+	text:           lipgloss.Color("15"),
+	muted:          lipgloss.Color("7"),
+	hint:           lipgloss.Color("7"),
+	warning:        lipgloss.Color("11"),
+	errorText:      lipgloss.Color("9"),
+	methods: map[string]lipgloss.Color{
+		"GET":    lipgloss.Color("10"), // green
+		"POST":   lipgloss.Color("12"), // blue
+		"PUT":    lipgloss.Color("11"), // yellow
+		"DELETE": lipgloss.Color("9"),  // red
+		"PATCH":  lipgloss.Color("13"), // magenta
+	},
+}
+
+// paletteFor returns the palette for a theme name, with an empty name
+// meaning the dark theme. ok is false for unknown names.
+func paletteFor(theme string) (p palette, ok bool) {
+	switch theme {
+	case "", ThemeDark:
+		return darkPalette, true
+	case ThemeLight:
+		return lightPalette, true
+	case ThemeHighContrast:
+		return highContrastPalette, true
+	}
+	return darkPalette, false
+}
+
+// methodColor returns the display color for an HTTP method, or an empty
+// color (terminal default) for methods without a dedicated color
+func (p palette) methodColor(method string) lipgloss.Color {
+	return p.methods[strings.ToUpper(method)]
+}
@@ -5,9 +5,16 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// The smallest terminal the panels can be laid out in
+const (
+	minTerminalWidth  = 60
+	minTerminalHeight = 15
+)
+
 // View renders the UI
 func (m *Model) View() string {
 	// If a dialog is active, render it
@@ -15,6 +22,12 @@ func (m *Model) View() string {
 		return m.renderDialog()
 	}
 
+	// Below the minimum size the list widths and heights go negative, so ask
+	// for a bigger terminal rather than drawing a broken layout
+	if m.width < minTerminalWidth || m.height < minTerminalHeight {
+		return m.renderTooSmall()
+	}
+
 	// Render the main UI
 	var sb strings.Builder
 
@@ -32,6 +45,14 @@ func (m *Model) View() string {
 	return sb.String()
 }
 
+// renderTooSmall renders the message shown when the terminal is too small
+func (m *Model) renderTooSmall() string {
+	message := lipgloss.NewStyle().
+		Foreground(m.palette.warning).
+		Render(fmt.Sprintf("Terminal too small (min %dx%d)", minTerminalWidth, minTerminalHeight))
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, message)
+}
+
 // renderHeader renders the header
 func (m *Model) renderHeader() string {
 	// Use cached style with updated width but without bottom border
@@ -40,25 +61,53 @@ func (m *Model) renderHeader() string {
 	// Title style similar to dialog titles
 	titleStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("205"))
+		Foreground(m.palette.title)
 
 	serverStatus := "Stopped"
+	if m.serverError != "" {
+		// Keep the reason visible after the status message is dismissed
+		serverStatus = fmt.Sprintf("Stopped (%s)", m.serverError)
+	}
 	if m.Server.IsRunning() {
-		serverStatus = fmt.Sprintf("Running (%s)", m.Server.GetAddress())
+		serverStatus = fmt.Sprintf("Running (%s)", m.Server.GetDisplayAddress())
 	}
 
 	proxyTarget := m.ProxyManager.GetTargetURL()
+	if !m.Config.Global.IsProxyEnabled() {
+		proxyTarget = "Mock-only"
+	} else if !m.ProxyManager.HasTarget() {
+		proxyTarget = "none"
+	} else if m.ProxyManager.IsChangeOrigin() {
+		proxyTarget += " (changeOrigin)"
+	}
 	header := fmt.Sprintf("Server: %s | Proxy: %s", serverStatus, proxyTarget)
+	if m.Server.IsTracing() {
+		header += " | Tracing"
+	}
+	if profile := m.MockManager.Profile(); profile != "" {
+		header += " | Profile: " + profile
+	}
+	
+	// Append the latest status message, if any
+	if m.statusMessage != "" {
+		statusStyle := lipgloss.NewStyle().Foreground(m.palette.warning)
+		header += " | " + statusStyle.Render(m.statusMessage)
+	}
 
-	return headerStyle.Render(titleStyle.Render("Climock") + " - " + header)
+	// Keep the header on one line, however long the status message is
+	content := titleStyle.Render("Climock") + " - " + header
+	content = truncateToWidth(content, m.width-headerStyle.GetHorizontalFrameSize())
+	
+	return headerStyle.Render(content)
 }
 
 // renderLists renders the feature and endpoint lists
 func (m *Model) renderLists() string {
 	// Calculate widths accounting for borders (subtract border width)
 	// Border takes 2 characters (1 on each side)
-	featureWidth := m.width/4 - 2
-	endpointWidth := 3*m.width/4 - 2
+	featureWidth, endpointWidth := m.panelWidths(m.width)
+	featureWidth -= 2
+	endpointWidth -= 2
 	
 	// Use cached styles with adjusted widths
 	featuresStyle := m.styles.features.Width(featureWidth)
@@ -73,25 +122,51 @@ func (m *Model) renderLists() string {
 		BorderStyle(lipgloss.RoundedBorder())
 	
 	// Highlight the active panel with a different border color
-	// Use a much lighter color (253) for inactive borders
+	// Use a much lighter color for inactive borders
 	if m.activePanel == FeaturesPanel {
 		featuresStyle = featuresStyle.
-			BorderForeground(lipgloss.Color("63"))
+			BorderForeground(m.palette.accent)
 		endpointsStyle = endpointsStyle.
-			BorderForeground(lipgloss.Color("253"))
+			BorderForeground(m.palette.inactiveBorder)
 	} else {
 		featuresStyle = featuresStyle.
-			BorderForeground(lipgloss.Color("253"))
+			BorderForeground(m.palette.inactiveBorder)
 		endpointsStyle = endpointsStyle.
-			BorderForeground(lipgloss.Color("63"))
+			BorderForeground(m.palette.accent)
 	}
 
-	featuresView := featuresStyle.Render(m.featuresList.View())
-	endpointsView := endpointsStyle.Render(m.endpointsList.View())
+	endpointsHint := ""
+	if m.tagFilter != "" {
+		endpointsHint = fmt.Sprintf("No endpoints tagged %s; press f to change the filter", m.tagFilter)
+	} else if m.selectedFeature != "" {
+		endpointsHint = "Press n to add an endpoint"
+	}
+	featuresView := featuresStyle.Render(m.listView(m.featuresList, "Press n to create a feature"))
+	endpointsView := endpointsStyle.Render(m.listView(m.endpointsList, endpointsHint))
 
 	return lipgloss.JoinHorizontal(lipgloss.Top, featuresView, endpointsView)
 }
 
+// listView renders l, or when it's empty, its title and a hint on what to do
+// next in place of the list's own "No items." message
+func (m *Model) listView(l list.Model, hint string) string {
+	if len(l.Items()) > 0 || hint == "" {
+		return l.View()
+	}
+
+	title := l.Styles.TitleBar.Render(l.Styles.Title.Render(l.Title))
+	hintView := lipgloss.NewStyle().
+		Foreground(m.palette.hint).
+		Padding(0, 2).
+		Width(l.Width()).
+		Render(hint)
+	
+	return lipgloss.NewStyle().
+		Height(l.Height()).
+		MaxHeight(l.Height()).
+		Render(lipgloss.JoinVertical(lipgloss.Left, title, hintView))
+}
+
 // renderFooter renders the footer
 func (m *Model) renderFooter() string {
 	// Use cached style with updated width but without top border
@@ -99,7 +174,7 @@ func (m *Model) renderFooter() string {
 
 	// Style for the footer content
 	footerContentStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("240"))
+		Foreground(m.palette.muted)
 
 	// Create a panel-specific keymap that only shows relevant shortcuts
 	panelKeyMap := NewPanelKeyMap(m.keyMap, m.activePanel)
@@ -125,9 +200,12 @@ func (m *Model) renderFooter() string {
 	}
 	
 	// Add panel-specific actions
+	if m.activePanel == FeaturesPanel && hasFeatures {
+		row1 = append(row1, m.keyMap.ToggleAll)
+	}
 	if m.activePanel == EndpointsPanel && hasEndpoints {
 		// Only show toggle and response options if endpoints are available
-		row1 = append(row1, m.keyMap.Toggle, m.keyMap.Response)
+		row1 = append(row1, m.keyMap.Toggle, m.keyMap.Response, m.keyMap.Sort)
 	}
 	
 	// Add Open and Delete options based on selection state
@@ -172,10 +250,12 @@ func (m *Model) renderDialog() string {
 		return m.renderHelpDialog()
 	case NewFeatureDialog, NewEndpointDialog:
 		return m.renderInputDialog()
-	case DeleteConfirmDialog:
+	case DeleteConfirmDialog, RestartConfirmDialog:
 		return m.renderConfirmDialog()
-	case ProxyConfigDialog:
+	case ProxyConfigDialog, PathRewriteDialog, SettingsDialog:
 		return m.renderInputDialog() // Reuse input dialog renderer
+	case ScenarioDialog:
+		return m.renderSelectDialog()
 	default:
 		// If we somehow get here with NoDialog, render the main UI
 		return m.View()
@@ -183,28 +263,38 @@ func (m *Model) renderDialog() string {
 }
 
 
-// renderHelpDialog renders the help dialog
-func (m *Model) renderHelpDialog() string {
-	// Create a box for the dialog - make it even narrower
-	box := lipgloss.NewStyle().
-		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("63")).
-		Padding(1, 1).
-		Width(m.width - 60)
+// minDialogWidth is the narrowest a dialog box gets on a small terminal
+const minDialogWidth = 30
 
-	// Style for the title
-	titleStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("205"))
+// dialogWidth returns the width of a dialog box that leaves margin columns
+// beside it, clamped so that it neither collapses on a narrow terminal nor
+// overflows it. The box wraps its content to this width.
+func (m *Model) dialogWidth(margin int) int {
+	width := m.width - margin
+	if width < minDialogWidth {
+		width = minDialogWidth
+	}
+	// Leave room for the border
+	if width > m.width-2 {
+		width = m.width - 2
+	}
+	return width
+}
 
+// Rows of the help dialog around its scrolling content: the border and
+// padding, the title, and the blank line and footer
+const helpDialogChrome = 7
+
+// helpContent returns the scrollable body of the help dialog
+func (m *Model) helpContent() string {
 	// Style for section headers
 	sectionStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("111"))
+		Foreground(m.palette.section)
 
 	// Key style
 	keyStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("63"))
+		Foreground(m.palette.accent)
 
 	// Create a grid layout for maximum compactness
 	navSection := sectionStyle.Render("Navigation:")
@@ -234,24 +324,83 @@ func (m *Model) renderHelpDialog() string {
 	
 	// Fourth row of actions - removed search (/) since it doesn't work
 	actionsRow4 := fmt.Sprintf(
-		"%s Reload configs",
-		keyStyle.Render("Ctrl+r"))
-
-	// Footer text
-	footerStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("240")).
-		Align(lipgloss.Center)
-	footer := footerStyle.Render("Press Esc to return")
+		"%s Reload configs  %s Apply scenario  %s Sort endpoints",
+		keyStyle.Render("Ctrl+r"), keyStyle.Render("a"), keyStyle.Render("S"))
+	
+	// Fifth row of actions
+	actionsRow5 := fmt.Sprintf(
+		"%s Path rewrite    %s changeOrigin    %s Trace",
+		keyStyle.Render("w"), keyStyle.Render("O"), keyStyle.Render("v"))
+	
+	// Sixth row of actions
+	actionsRow6 := fmt.Sprintf(
+		"%s Profile    %s Copy curl    %s/%s Panel width",
+		keyStyle.Render("P"), keyStyle.Render("c"), keyStyle.Render("["), keyStyle.Render("]"))
+	
+	// Seventh row of actions
+	actionsRow7 := fmt.Sprintf(
+		"%s Server settings    %s Filter by tag",
+		keyStyle.Render(","), keyStyle.Render("f"))
 
-	// Combine title and content with minimal spacing
-	content := titleStyle.Render("Climock Help") + "\n" +
-		navSection + "\n" +
+	return navSection + "\n" +
 		navKeys + "\n\n" +
 		actionsSection + "\n" +
 		actionsRow1 + "\n" +
 		actionsRow2 + "\n" +
 		actionsRow3 + "\n" +
-		actionsRow4 + "\n\n" +
+		actionsRow4 + "\n" +
+		actionsRow5 + "\n" +
+		actionsRow6 + "\n" +
+		actionsRow7
+}
+
+// sizeHelpViewport fits the help viewport to the terminal, wrapping the help
+// content to its width. Rows that don't fit are reached by scrolling.
+func (m *Model) sizeHelpViewport() {
+	// The box has one column of padding on each side
+	width := m.dialogWidth(60) - 2
+	content := lipgloss.NewStyle().Width(width).Render(m.helpContent())
+
+	height := lipgloss.Height(content)
+	if maxHeight := m.height - helpDialogChrome; height > maxHeight {
+		height = maxHeight
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	m.helpViewport.Width = width
+	m.helpViewport.Height = height
+	m.helpViewport.SetContent(content)
+}
+
+// renderHelpDialog renders the help dialog
+func (m *Model) renderHelpDialog() string {
+	// Create a box for the dialog - make it even narrower
+	box := lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(m.palette.accent).
+		Padding(1, 1).
+		Width(m.dialogWidth(60))
+
+	// Style for the title
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(m.palette.title)
+
+	// Footer text, mentioning scrolling when the content doesn't fit
+	footerStyle := lipgloss.NewStyle().
+		Foreground(m.palette.muted).
+		Align(lipgloss.Center)
+	footerText := "Press Esc to return"
+	if m.helpViewport.TotalLineCount() > m.helpViewport.Height {
+		footerText = "↑/↓ Scroll  Esc Return"
+	}
+	footer := footerStyle.Render(footerText)
+
+	// Combine title and content with minimal spacing
+	content := titleStyle.Render("Climock Help") + "\n" +
+		m.helpViewport.View() + "\n\n" +
 		footer
 
 	// Create the dialog box
@@ -266,25 +415,25 @@ func (m *Model) renderInputDialog() string {
 	// Create a box for the dialog
 	box := lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("63")).
+		BorderForeground(m.palette.accent).
 		Padding(1, 2).
-		Width(m.width - 20)
+		Width(m.dialogWidth(20))
 
 	// Style for the title
 	titleStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("205")).
+		Foreground(m.palette.title).
 		MarginBottom(1)
 
 	// Style for the instructions
 	instructionStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("241")).
+		Foreground(m.palette.hint).
 		Italic(true).
 		MarginBottom(1)
 
 	// Style for the buttons
 	buttonStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("240")).
+		Foreground(m.palette.muted).
 		MarginTop(1)
 
 	// Build the dialog content
@@ -296,6 +445,9 @@ func (m *Model) renderInputDialog() string {
 	if len(m.textInputs) > 1 {
 		sb.WriteString(instructionStyle.Render("Use [Tab] to navigate between fields"))
 		sb.WriteString("\n\n")
+	} else if m.activeDialog == ProxyConfigDialog && len(m.dialogOptions) > 1 {
+		sb.WriteString(instructionStyle.Render("Use [↑/↓] to pick a recent target"))
+		sb.WriteString("\n\n")
 	} else {
 		sb.WriteString("\n")
 	}
@@ -312,6 +464,28 @@ func (m *Model) renderInputDialog() string {
 		sb.WriteString("Loading inputs...")
 	}
 	
+	// Explain why the last confirm was refused
+	if m.dialogError != "" {
+		sb.WriteString("\n\n")
+		if m.dialogErrorIsWarning {
+			warningStyle := lipgloss.NewStyle().Foreground(m.palette.warning)
+			sb.WriteString(warningStyle.Render("Warning: " + m.dialogError))
+		} else {
+			errorStyle := lipgloss.NewStyle().Foreground(m.palette.errorText)
+			sb.WriteString(errorStyle.Render("Error: " + m.dialogError))
+		}
+	}
+	
+	// The proxy dialog also shows the changeOrigin setting, flipped with ctrl+o
+	if m.activeDialog == ProxyConfigDialog {
+		checkbox := "[ ]"
+		if m.ProxyManager.IsChangeOrigin() {
+			checkbox = "[x]"
+		}
+		sb.WriteString("\n\n")
+		sb.WriteString(fmt.Sprintf("%s Change origin (Host header)  [Ctrl+o] Toggle", checkbox))
+	}
+	
 	sb.WriteString("\n\n")
 	sb.WriteString(buttonStyle.Render("[Enter] Confirm  [Esc] Cancel"))
 
@@ -327,26 +501,26 @@ func (m *Model) renderConfirmDialog() string {
 	// Create a box for the dialog
 	box := lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("63")).
+		BorderForeground(m.palette.accent).
 		Padding(1, 2).
-		Width(m.width - 20).
+		Width(m.dialogWidth(20)).
 		Align(lipgloss.Center)
 
 	// Style for the title
 	titleStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("205")).
+		Foreground(m.palette.title).
 		MarginBottom(1)
 
 	// Style for the content
 	contentStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("252")).
+		Foreground(m.palette.text).
 		MarginTop(1).
 		MarginBottom(1)
 
 	// Style for the buttons
 	buttonStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("240")).
+		Foreground(m.palette.muted).
 		MarginTop(1)
 
 	// Build the dialog content
@@ -363,3 +537,59 @@ func (m *Model) renderConfirmDialog() string {
 	// Position the dialog in the center of the screen
 	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, dialog)
 }
+
+// renderSelectDialog renders a dialog for choosing one of several options
+func (m *Model) renderSelectDialog() string {
+	// Create a box for the dialog
+	box := lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(m.palette.accent).
+		Padding(1, 2).
+		Width(m.dialogWidth(20))
+
+	// Style for the title
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(m.palette.title).
+		MarginBottom(1)
+
+	// Styles for the options
+	optionStyle := lipgloss.NewStyle().
+		Foreground(m.palette.text)
+	selectedStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(m.palette.accent)
+
+	// Style for the buttons
+	buttonStyle := lipgloss.NewStyle().
+		Foreground(m.palette.muted).
+		MarginTop(1)
+
+	// Build the dialog content
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render(m.dialogTitle))
+	sb.WriteString("\n\n")
+	
+	if len(m.dialogOptions) == 0 {
+		sb.WriteString(optionStyle.Render(m.dialogContent))
+		sb.WriteString("\n\n")
+		sb.WriteString(buttonStyle.Render("[Esc] Close"))
+	} else {
+		for i, option := range m.dialogOptions {
+			if i == m.dialogCursor {
+				sb.WriteString(selectedStyle.Render("> " + option))
+			} else {
+				sb.WriteString(optionStyle.Render("  " + option))
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+		sb.WriteString(buttonStyle.Render("[↑/↓] Select  [Enter] Apply  [Esc] Cancel"))
+	}
+
+	// Create the dialog box
+	dialog := box.Render(sb.String())
+
+	// Position the dialog in the center of the screen
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, dialog)
+}
@@ -0,0 +1,114 @@
+// Package climock runs the Climock mock server inside a Go program, such as
+// a test suite, without starting the climock binary.
+//
+// A Server is loaded from a mocks directory laid out like the one the
+// climock command uses. Changes made through it, such as toggling an
+// endpoint, are saved to that directory just as they are from the UI, so
+// tests usually point it at a copy in a temporary directory.
+package climock
+
+import (
+	"fmt"
+
+	"swoozeki/climock/internal/config"
+	"swoozeki/climock/internal/mock"
+	"swoozeki/climock/internal/proxy"
+	"swoozeki/climock/internal/server"
+)
+
+// Server is an embedded mock server
+type Server struct {
+	config *config.Config
+	mocks  *mock.Manager
+	server *server.Server
+}
+
+// New loads the mocks in configDir and returns a server for them. The
+// server isn't started.
+func New(configDir string) (*Server, error) {
+	cfg := config.New(configDir)
+	if err := cfg.Load(); err != nil {
+		return nil, err
+	}
+
+	proxyManager, err := proxy.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create proxy: %w", err)
+	}
+	mockManager := mock.New(cfg)
+
+	return &Server{
+		config: cfg,
+		mocks:  mockManager,
+		server: server.New(cfg, mockManager, proxyManager),
+	}, nil
+}
+
+// Listen sets the host and port the server binds when it starts, instead of
+// those in config.json. Port 0 picks a free port, which avoids clashes when
+// tests run in parallel. The change isn't saved.
+func (s *Server) Listen(host string, port int) error {
+	if s.server.IsRunning() {
+		return fmt.Errorf("cannot change the address while the server is running")
+	}
+
+	s.config.Global.ServerConfig.Host = host
+	s.config.Global.ServerConfig.Port = port
+	s.config.Global.ServerConfig.SocketPath = ""
+	return nil
+}
+
+// Start starts serving. It returns once the server is listening.
+func (s *Server) Start() error {
+	return s.server.Start()
+}
+
+// Stop stops the server, waiting for in-flight requests to finish
+func (s *Server) Stop() error {
+	return s.server.Stop()
+}
+
+// URL returns the base URL of the running server, such as
+// http://localhost:3000
+func (s *Server) URL() string {
+	return "http://" + s.server.GetDisplayAddress()
+}
+
+// SetEndpointActive activates or deactivates an endpoint. Inactive endpoints
+// are proxied, or get the fallback response when there is no proxy target.
+func (s *Server) SetEndpointActive(feature, id string, active bool) error {
+	endpoint, err := s.config.GetEndpoint(feature, id)
+	if err != nil {
+		return err
+	}
+	if endpoint.Active == active {
+		return nil
+	}
+	return s.mocks.ToggleEndpoint(feature, id)
+}
+
+// SetResponse makes an endpoint serve the named response
+func (s *Server) SetResponse(feature, id, response string) error {
+	return s.mocks.SetDefaultResponse(feature, id, response)
+}
+
+// ApplyScenario applies a scenario from config.json
+func (s *Server) ApplyScenario(name string) error {
+	return s.mocks.ApplyScenario(name)
+}
+
+// SetProfile switches to a response profile, or turns profiles off when
+// name is empty
+func (s *Server) SetProfile(name string) error {
+	return s.mocks.SetProfile(name)
+}
+
+// Reload rereads the mocks directory, picking up files changed on disk.
+// Template counters start over.
+func (s *Server) Reload() error {
+	if err := s.server.Reload(); err != nil {
+		return err
+	}
+	s.mocks.ResetCounters()
+	return nil
+}
@@ -0,0 +1,70 @@
+package climock_test
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"swoozeki/climock/pkg/climock"
+)
+
+// Example starts an embedded server, requests a mocked endpoint, then
+// switches the endpoint to another response
+func Example() {
+	dir, err := os.MkdirTemp("", "climock-example")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	users := `{"feature": "users", "endpoints": [
+		{"id": "get-user", "method": "GET", "path": "/api/users/:id", "active": true,
+		 "defaultResponse": "success",
+		 "responses": {
+		   "success": {"status": 200, "body": {"id": "{{.params.id}}", "name": "Ada"}},
+		   "missing": {"status": 404, "body": {"error": "User not found"}}
+		 }}
+	]}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{}`), 0644); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "users.json"), []byte(users), 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	srv, err := climock.New(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Let the system pick a free port
+	if err := srv.Listen("localhost", 0); err != nil {
+		log.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		log.Fatal(err)
+	}
+	defer srv.Stop()
+
+	get := func() {
+		resp, err := http.Get(srv.URL() + "/api/users/42")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		fmt.Println(resp.StatusCode, string(body))
+	}
+
+	get()
+	if err := srv.SetResponse("users", "get-user", "missing"); err != nil {
+		log.Fatal(err)
+	}
+	get()
+
+	// Output:
+	// 200 {"id":"42","name":"Ada"}
+	// 404 {"error":"User not found"}
+}